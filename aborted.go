@@ -0,0 +1,65 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// aborted reports if the error appears to be the connection being lost before the response headers were received,
+// ex: a HTTP/2 GOAWAY or a connection reset. GitHub may or may not have counted such a request against the rate limit.
+func aborted(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	// the HTTP/2 errors are not exported by net/http
+	msg := err.Error()
+	return strings.Contains(msg, "GOAWAY") || strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "client connection lost") || strings.Contains(msg, "server closed idle connection")
+}
+
+// idempotent reports if the request can safely be sent again: its method is idempotent or it has an idempotency key,
+// either as a header or via ContextWithIdempotencyKey.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != "" || IdempotencyKeyFromContext(req.Context()) != ""
+}
+
+// unsent reports if the error means the request was rejected before it was sent to GitHub,
+// so it can be sent again even if it is not idempotent.
+func unsent(err error) bool {
+	return errors.Is(err, ErrLatencyBudgetExceeded) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrHardCapReached) ||
+		errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrSaturated)
+}
+
+// abort records that a request of the resource type was aborted, so the next rate limit observed for it is trusted even if
+// it appears stale (ex: more remaining than the optimistic decrement assumed), reconciling the local counters with GitHub's.
+func (l *Limits) abort(resource Resource) {
+	l.aborted.Store(resource, true)
+}
+
+// roundTripAborted executes the request via roundTrip, retrying idempotent requests that were aborted, see RetryAborted.
+func (t *Transport) roundTripAborted(req *http.Request, resource Resource, cost uint64) (*http.Response, error) {
+	for retries := 0; ; retries++ {
+		resp, err := t.roundTrip(req, resource, cost)
+		if retries >= t.RetryAborted || !aborted(err) || !idempotent(req) || req.Context().Err() != nil {
+			return resp, err
+		}
+		retry, rerr := rewind(req)
+		if rerr != nil {
+			return resp, err
+		}
+		debugf("retrying %s %s after the connection was lost: %v", req.Method, req.URL, err)
+		t.Limits.emit(retried(req, resource, "connection lost: "+err.Error()))
+		req = retry
+	}
+}
@@ -0,0 +1,57 @@
+package ghratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_aborted(t *testing.T) {
+	assert.True(t, aborted(errors.New("http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR")))
+	assert.True(t, aborted(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.False(t, aborted(nil))
+	assert.False(t, aborted(errors.New("x509: certificate signed by unknown authority")))
+}
+
+func TestTransport_RetryAborted(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	var attempts int
+	var abort bool
+	transport := &Transport{Optimistic: true, RetryAborted: 1, Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if abort {
+			abort = false
+			return nil, errors.New("http2: server sent GOAWAY and closed the connection")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{
+			"X-Ratelimit-Limit":     []string{"5000"},
+			"X-Ratelimit-Used":      []string{"10"},
+			"X-Ratelimit-Remaining": []string{"4990"},
+			"X-Ratelimit-Reset":     []string{fmt.Sprint(reset)},
+			"X-Ratelimit-Resource":  []string{"core"},
+		}, Body: http.NoBody, Request: req}, nil
+	})}
+	transport.Limits.Store(&http.Response{}, ResourceCore, &Rate{Limit: 5000, Used: 10, Remaining: 4990, Reset: uint64(reset)})
+
+	abort = true
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err, "idempotent requests should be retried") {
+		discard(resp)
+	}
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, uint64(4990), transport.Limits.Load(ResourceCore).Remaining, "the aborted request should not drift the local counters")
+	assert.Equal(t, Accounting{Requests: 2, Points: 1, Aborted: 1}, transport.Accounting()[UsageKey{Resource: ResourceCore}])
+
+	abort = true
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	_, err = transport.RoundTrip(req)
+	assert.ErrorContains(t, err, "GOAWAY", "non-idempotent requests should not be retried")
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,76 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// UsageKey identifies the requests attributed together by Accounting.
+type UsageKey struct {
+	// Resource is the rate-limit resource of the requests.
+	Resource Resource
+	// Caller is the label set via ContextWithCaller, empty for requests without one.
+	Caller string
+}
+
+// account counts the requests sent for a UsageKey.
+type account struct {
+	requests, points, notModified, aborted atomic.Uint64
+}
+
+// Accounting is the quota consumed by the requests of a resource type and caller.
+type Accounting struct {
+	// Requests is the number of requests sent, including retries.
+	Requests uint64 `json:"requests"`
+	// Points is the estimated rate-limit points consumed, see CostEstimator. 304 Not Modified responses consume none.
+	Points uint64 `json:"points"`
+	// NotModified is the number of 304 Not Modified responses, each of which saved the points of a full response.
+	NotModified uint64 `json:"not_modified"`
+	// Aborted is the number of requests whose connection was lost before a response (ex: a HTTP/2 GOAWAY or a connection reset).
+	// GitHub may or may not have counted them, so their points are not included in Points.
+	Aborted uint64 `json:"aborted,omitempty"`
+}
+
+// account attributes the outcome of a request to its resource type and caller, see Accounting.
+func (t *Transport) account(req *http.Request, resource Resource, cost uint64, resp *http.Response, err error) {
+	key := UsageKey{Resource: resource, Caller: CallerFromContext(req.Context())}
+	val, _ := t.accounts.LoadOrStore(key, new(account))
+	a := val.(*account)
+	a.requests.Add(1)
+	switch {
+	case aborted(err):
+		a.aborted.Add(1)
+	case resp != nil && resp.StatusCode == http.StatusNotModified:
+		a.notModified.Add(1)
+	default:
+		a.points.Add(cost)
+	}
+}
+
+// Accounting returns the quota consumed by the transport for each resource type and caller,
+// ex: to attribute which internal service is spending a shared token's budget.
+func (t *Transport) Accounting() map[UsageKey]Accounting {
+	totals := make(map[UsageKey]Accounting)
+	t.accounts.Range(func(key, value any) bool {
+		a := value.(*account)
+		totals[key.(UsageKey)] = Accounting{Requests: a.requests.Load(), Points: a.points.Load(), NotModified: a.notModified.Load(), Aborted: a.aborted.Load()}
+		return true
+	})
+	return totals
+}
+
+// Accounting returns the quota consumed by every transport in the pool for each resource type and caller.
+func (bt *Balancer) Accounting() map[UsageKey]Accounting {
+	totals := make(map[UsageKey]Accounting)
+	for _, transport := range bt.transports() {
+		for key, a := range transport.Accounting() {
+			total := totals[key]
+			total.Requests += a.Requests
+			total.Points += a.Points
+			total.NotModified += a.NotModified
+			total.Aborted += a.Aborted
+			totals[key] = total
+		}
+	}
+	return totals
+}
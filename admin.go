@@ -0,0 +1,268 @@
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Pause stops the transport from being selected by a Balancer, in-flight requests are unaffected.
+// Requests sent directly to the transport are still executed.
+func (t *Transport) Pause() {
+	t.paused.Store(true)
+}
+
+// Resume allows a paused transport to be selected by a Balancer again.
+func (t *Transport) Resume() {
+	t.paused.Store(false)
+}
+
+// Paused reports if the transport has been paused (or is draining).
+func (t *Transport) Paused() bool {
+	return t.paused.Load()
+}
+
+// idleChan returns a channel that is closed the next time the transport has no in-flight requests.
+func (t *Transport) idleChan() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	return t.idle
+}
+
+// release decrements the in-flight requests, waking any Drain if it was the last.
+func (t *Transport) release() {
+	if t.inflight.Add(-1) != 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Drain pauses the transport, then blocks until all in-flight requests have finished.
+// If ctx is done first, a *WaitError is returned and the transport remains paused.
+func (t *Transport) Drain(ctx context.Context) error {
+	t.Pause()
+	for {
+		idle := t.idleChan()
+		if t.InFlight() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return &WaitError{Op: "in-flight requests to drain", Err: context.Cause(ctx)}
+		case <-idle:
+		}
+	}
+}
+
+// Pause stops the transport from being selected for new requests, reporting if it is in the pool, see (*Transport).Pause.
+func (bt *Balancer) Pause(transport *Transport) bool {
+	if !slices.Contains(bt.transports(), transport) {
+		return false
+	}
+	transport.Pause()
+	return true
+}
+
+// Resume allows a paused transport to be selected again, reporting if it is in the pool, see (*Transport).Resume.
+func (bt *Balancer) Resume(transport *Transport) bool {
+	if !slices.Contains(bt.transports(), transport) {
+		return false
+	}
+	transport.Resume()
+	return true
+}
+
+// Drain pauses every transport in the pool, then blocks until all of their in-flight requests have finished,
+// ex: before shutting down. New requests fail with ErrNoTransports until a transport is resumed (or added).
+// If ctx is done first, a *WaitError is returned and the transports remain paused.
+func (bt *Balancer) Drain(ctx context.Context) error {
+	transports := bt.transports()
+	for _, transport := range transports {
+		transport.Pause()
+	}
+	for _, transport := range transports {
+		if err := transport.Drain(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrUnauthorized is returned by the default AdminHandler.Authorize, rejecting every request.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// AdminHandler is a http.Handler exposing administrative controls for a Balancer,
+// for environments where redeploys are expensive. Transports are identified by their Name or, if unnamed, their fingerprint
+// (see Balancer.Fingerprint), never by their index as it shifts when transports are added or removed:
+//
+//	GET    /                   status of every transport
+//	POST   /                   add a transport (requires NewTransport)
+//	DELETE /{id}               remove a transport
+//	POST   /{id}/pause         pause a transport
+//	POST   /{id}/resume        resume a transport
+//	POST   /{id}/drain         drain a transport, blocking until its in-flight requests finish
+//	PUT    /standby-threshold  set the StandbyThreshold, ex: {"threshold": 0.2}
+//	PUT    /debug              enable or disable decision logging (see SetDebug), ex: {"enabled": true}
+//
+// Paths are relative, use http.StripPrefix to mount it under a prefix.
+type AdminHandler struct {
+	// Pool is the Balancer being administered.
+	Pool *Balancer
+	// Authorize is called for every request, if it returns an error the request is rejected with 403 Forbidden.
+	// It is required, if nil every request is rejected.
+	Authorize func(*http.Request) error
+	// NewTransport creates a transport to add to the pool from a request, ex: using a token in the request body.
+	// If nil, transports cannot be added.
+	NewTransport func(*http.Request) (*Transport, error)
+
+	once sync.Once
+	mux  *http.ServeMux
+}
+
+// ServeHTTP implements http.Handler
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authorize := h.Authorize
+	if authorize == nil {
+		authorize = func(*http.Request) error { return ErrUnauthorized }
+	}
+	if err := authorize(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	h.once.Do(func() {
+		h.mux = http.NewServeMux()
+		h.mux.HandleFunc("GET /{$}", h.serveStatus)
+		h.mux.HandleFunc("POST /{$}", h.serveAdd)
+		h.mux.HandleFunc("DELETE /{id}", h.serveRemove)
+		h.mux.HandleFunc("POST /{id}/{action}", h.serveAction)
+		h.mux.HandleFunc("PUT /standby-threshold", h.serveStandbyThreshold)
+		h.mux.HandleFunc("PUT /debug", h.serveDebug)
+	})
+	h.mux.ServeHTTP(w, r)
+}
+
+// writeJSON writes the fields as a JSON response, embedding the SchemaVersion.
+func writeJSON(w http.ResponseWriter, fields map[string]any) {
+	fields["schema_version"] = SchemaVersion
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(fields)
+}
+
+// lookup returns the transport identified by the id path value, either its Name or its fingerprint.
+func (h *AdminHandler) lookup(w http.ResponseWriter, r *http.Request) (int, *Transport, bool) {
+	transports := h.Pool.transports()
+	id := r.PathValue("id")
+	for idx, transport := range transports {
+		if transport.Name != "" && transport.Name == id {
+			return idx, transport, true
+		}
+	}
+	for idx, transport := range transports {
+		if fingerprint := h.Pool.fingerprint(transport); fingerprint != "" && fingerprint == id {
+			return idx, transport, true
+		}
+	}
+	http.Error(w, "unknown transport", http.StatusNotFound)
+	return 0, nil, false
+}
+
+// serveStatus handles GET /
+func (h *AdminHandler) serveStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"standby_threshold": h.Pool.getStandbyThreshold(),
+		"debug":             Debug(),
+		"transports":        h.Pool.Status(),
+	})
+}
+
+// serveAdd handles POST /
+func (h *AdminHandler) serveAdd(w http.ResponseWriter, r *http.Request) {
+	if h.NewTransport == nil {
+		http.Error(w, "adding transports is not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	transport, err := h.NewTransport(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if other := h.Pool.duplicateOf(transport); other != nil {
+		http.Error(w, "transport has the same credential as transport "+h.Pool.identify(other), http.StatusConflict)
+		return
+	}
+	h.Pool.Add(transport)
+	writeJSON(w, map[string]any{"transport": h.Pool.status(transport, h.Pool.index(transport))})
+}
+
+// serveRemove handles DELETE /{id}
+func (h *AdminHandler) serveRemove(w http.ResponseWriter, r *http.Request) {
+	idx, transport, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	if !h.Pool.Remove(transport) {
+		http.Error(w, "unknown transport", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"transport": h.Pool.status(transport, idx)})
+}
+
+// serveAction handles POST /{id}/{action}
+func (h *AdminHandler) serveAction(w http.ResponseWriter, r *http.Request) {
+	idx, transport, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	switch r.PathValue("action") {
+	case "pause":
+		transport.Pause()
+	case "resume":
+		transport.Resume()
+	case "drain":
+		if err := transport.Drain(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusGatewayTimeout)
+			return
+		}
+	default:
+		http.Error(w, "unknown action", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]any{"transport": h.Pool.status(transport, idx)})
+}
+
+// serveStandbyThreshold handles PUT /standby-threshold
+func (h *AdminHandler) serveStandbyThreshold(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Threshold *float64 `json:"threshold"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Threshold == nil || *body.Threshold < 0 || *body.Threshold > 1 {
+		http.Error(w, "expected {\"threshold\": 0.0-1.0}", http.StatusBadRequest)
+		return
+	}
+	h.Pool.SetStandbyThreshold(*body.Threshold)
+	writeJSON(w, map[string]any{"standby_threshold": *body.Threshold})
+}
+
+// serveDebug handles PUT /debug
+func (h *AdminHandler) serveDebug(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+		http.Error(w, "expected {\"enabled\": true|false}", http.StatusBadRequest)
+		return
+	}
+	SetDebug(*body.Enabled)
+	writeJSON(w, map[string]any{"debug": *body.Enabled})
+}
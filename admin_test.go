@@ -0,0 +1,132 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Drain(t *testing.T) {
+	a, b := withRemaining(5000), withRemaining(100)
+	bt := &Balancer{Transports: []*Transport{a, b}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, 1, a.InFlight())
+
+	drained := make(chan error, 1)
+	go func() { drained <- a.Drain(context.Background()) }()
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, a.Paused(), "draining transport should be paused")
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, b, selected, "paused transport should not be selected")
+	select {
+	case <-drained:
+		t.Fatal("drain should wait for in-flight requests")
+	default:
+	}
+	discard(resp)
+	select {
+	case err := <-drained:
+		assert.NoError(t, err, "(*Transport).Drain failed")
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish once in-flight requests finished")
+	}
+
+	b.Pause()
+	_, err = bt.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoTransports, "all transports are paused")
+}
+
+func TestBalancer_Drain(t *testing.T) {
+	a, b := withRemaining(5000), withRemaining(100)
+	bt := &Balancer{Transports: []*Transport{a, b}}
+	assert.False(t, bt.Pause(withRemaining(100)), "transports outside the pool should not be paused")
+	assert.True(t, bt.Pause(a))
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, 1, b.InFlight(), "paused transport should not be selected")
+	assert.True(t, bt.Resume(a))
+	assert.False(t, a.Paused())
+
+	drained := make(chan error, 1)
+	go func() { drained <- bt.Drain(context.Background()) }()
+	assert.Eventually(t, func() bool { return a.Paused() && b.Paused() }, time.Second, time.Millisecond, "every transport should be paused")
+	_, err = bt.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoTransports, "new requests should be rejected while draining")
+	select {
+	case <-drained:
+		t.Fatal("drain should wait for in-flight requests")
+	default:
+	}
+	discard(resp)
+	select {
+	case err := <-drained:
+		assert.NoError(t, err, "(*Balancer).Drain failed")
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish once in-flight requests finished")
+	}
+
+	bt.Resume(b)
+	resp, err = bt.RoundTrip(req)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var waitErr *WaitError
+	assert.ErrorAs(t, bt.Drain(ctx), &waitErr, "drain should stop once ctx is done")
+	discard(resp)
+}
+
+func TestAdminHandler(t *testing.T) {
+	transport := withRemaining(5000)
+	bt := &Balancer{
+		Transports:  []*Transport{transport},
+		Fingerprint: func(t *Transport) string { return "fp-" + t.Name },
+	}
+	h := &AdminHandler{Pool: bt}
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/fp-/pause", "").Code, "Authorize is required")
+
+	h.Authorize = func(*http.Request) error { return nil }
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/fp-/pause", "").Code, "unnamed transports should be identified by fingerprint")
+	assert.True(t, transport.Paused())
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/fp-/resume", "").Code)
+	assert.False(t, transport.Paused())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/0/pause", "").Code, "transports should not be identified by index")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/unknown/pause", "").Code, "unknown transport")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/standby-threshold", `{"threshold":0.25}`).Code)
+	assert.Equal(t, 0.25, bt.getStandbyThreshold())
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/standby-threshold", `{"threshold":2}`).Code)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/", "").Code, "NewTransport is required to add")
+	h.NewTransport = func(*http.Request) (*Transport, error) {
+		added := withRemaining(100)
+		added.Name = "canary"
+		return added, nil
+	}
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/", "").Code)
+	assert.Len(t, bt.transports(), 2)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/canary/pause", "").Code, "transports should be identified by name")
+	assert.True(t, bt.transports()[1].Paused())
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/fp-", "").Code)
+	assert.Len(t, bt.transports(), 1)
+	assert.NotSame(t, transport, bt.transports()[0], "removed transport should be gone")
+
+	rec := serve(http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"standby_threshold":0.25`)
+	assert.Contains(t, rec.Body.String(), `"name":"canary"`)
+	assert.Contains(t, rec.Body.String(), `"fingerprint":"fp-canary"`)
+}
@@ -0,0 +1,139 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// affinity is the transport that executed a write, preferred for reads of the same key until it expires.
+type affinity struct {
+	transport *Transport
+	until     time.Time
+}
+
+// affinitySweep is how many writes are pinned between sweeps of the expired affinities.
+const affinitySweep = 1024
+
+// affinityKey returns the key shared by writes and the reads that should see them:
+// the repository of the request (see RepositoryAffinity) if any, otherwise its path.
+func affinityKey(req *http.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+	host := strings.ToLower(req.URL.Host)
+	if repo := RepositoryAffinity(req); repo != "" {
+		return host + "/repos/" + repo
+	}
+	return host + strings.TrimPrefix(req.URL.Path, "/api/v3")
+}
+
+// RepositoryAffinity is an Affinity for Balancer that routes requests for the same repository
+// (/repos/{owner}/{repo}) to the same transport. Other requests are balanced normally.
+func RepositoryAffinity(req *http.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(req.URL.Path, "/api/v3"), "/repos/")
+	if !ok {
+		return ""
+	}
+	if parts := strings.SplitN(rest, "/", 3); len(parts) >= 2 && parts[0] != "" && parts[1] != "" {
+		return strings.ToLower(parts[0] + "/" + parts[1])
+	}
+	return ""
+}
+
+// OwnerAffinity is an Affinity for Balancer that routes requests for the same owner's repositories
+// (/repos/{owner}/...) to the same transport. Other requests are balanced normally.
+func OwnerAffinity(req *http.Request) string {
+	owner, _, _ := strings.Cut(RepositoryAffinity(req), "/")
+	return owner
+}
+
+// preferred returns the candidate the request's Affinity key hashes to, if any.
+// Rendezvous hashing is used so that adding or removing a transport only moves the keys that hashed to it.
+// Transports are identified by their Name, otherwise their fingerprint, otherwise their address, in which case
+// the keys are reshuffled every time the process restarts.
+func (bt *Balancer) preferred(req *http.Request, candidates []*Transport) *Transport {
+	if bt.Affinity == nil {
+		return nil
+	}
+	key := bt.Affinity(req)
+	if key == "" {
+		return nil
+	}
+	var selected *Transport
+	var highest uint64
+	for _, transport := range candidates {
+		id := transport.Name
+		if id == "" {
+			id = bt.fingerprint(transport)
+		}
+		if id == "" {
+			id = fmt.Sprintf("%p", transport)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := mix64(h.Sum64()); selected == nil || score > highest {
+			selected, highest = transport, score
+		}
+	}
+	return selected
+}
+
+// mix64 is the MurmurHash3 finalizer, FNV alone does not spread keys that differ only in their last bytes.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// isWrite reports if the request may modify data.
+func isWrite(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// pin records that a successful write was executed by the transport, see WriteAffinity.
+func (bt *Balancer) pin(req *http.Request, transport *Transport, resp *http.Response, now time.Time) {
+	if bt.WriteAffinity <= 0 || !isWrite(req) || resp == nil || resp.StatusCode >= http.StatusBadRequest {
+		return
+	}
+	bt.affinities.Store(affinityKey(req), &affinity{transport: transport, until: now.Add(bt.WriteAffinity)})
+	if bt.pins.Add(1)%affinitySweep == 0 {
+		bt.affinities.Range(func(key, value any) bool {
+			if !value.(*affinity).until.After(now) {
+				bt.affinities.CompareAndDelete(key, value)
+			}
+			return true
+		})
+	}
+}
+
+// pinned returns the transport that recently executed a write the read should observe, if any.
+func (bt *Balancer) pinned(req *http.Request, now time.Time) *Transport {
+	if bt.WriteAffinity <= 0 || isWrite(req) {
+		return nil
+	}
+	key := affinityKey(req)
+	val, ok := bt.affinities.Load(key)
+	if !ok {
+		return nil
+	}
+	if a := val.(*affinity); a.until.After(now) {
+		return a.transport
+	}
+	bt.affinities.CompareAndDelete(key, val)
+	return nil
+}
@@ -0,0 +1,92 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_WriteAffinity(t *testing.T) {
+	low, high := withRemaining(100), withRemaining(1000)
+	bt := &Balancer{Transports: []*Transport{low, high}, WriteAffinity: time.Minute}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	discard(resp)
+
+	low.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000})
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r/issues/1", nil)
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, high, selected, "reads of the written repository should prefer the writer")
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/other", nil)
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, low, selected, "reads of other repositories should use the Strategy")
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	assert.Nil(t, bt.pinned(req, time.Now().Add(time.Minute)), "affinity should expire")
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, low, selected, "expired affinity should be removed")
+}
+
+func TestBalancer_Affinity(t *testing.T) {
+	assert.Equal(t, "o/r", RepositoryAffinity(&http.Request{URL: &url.URL{Path: "/api/v3/repos/O/r/pulls/1"}}))
+	assert.Empty(t, RepositoryAffinity(&http.Request{URL: &url.URL{Path: "/users/o"}}))
+	assert.Equal(t, "o", OwnerAffinity(&http.Request{URL: &url.URL{Path: "/repos/o/r"}}))
+
+	transports := []*Transport{withRemaining(100), withRemaining(200), withRemaining(300), withRemaining(400)}
+	bt := &Balancer{Transports: transports, Affinity: RepositoryAffinity}
+	selectFor := func(path string) *Transport {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com"+path, nil)
+		selected, err := bt.selectTransport(req, ResourceCore, nil)
+		assert.NoError(t, err)
+		return selected
+	}
+
+	seen := make(map[*Transport]bool)
+	for i := range 32 {
+		repo := fmt.Sprintf("/repos/o/r%d", i)
+		preferred := selectFor(repo)
+		assert.Same(t, preferred, selectFor(repo+"/issues"), "requests for the same repository should use the same transport")
+		seen[preferred] = true
+	}
+	assert.Greater(t, len(seen), 1, "repositories should be spread across transports")
+	assert.Same(t, transports[3], selectFor("/users/o"), "requests without a key should use the Strategy")
+
+	preferred := selectFor("/repos/o/r")
+	preferred.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 0, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+	assert.NotSame(t, preferred, selectFor("/repos/o/r"), "exhausted preferred transport should fall back")
+}
+
+func TestAffinityKey(t *testing.T) {
+	write, _ := http.NewRequest(http.MethodPost, "https://API.github.com/repos/O/R/issues", nil)
+	read, _ := http.NewRequest(http.MethodGet, "https://api.github.com/api/v3/repos/o/r/issues/1", nil)
+	assert.Equal(t, "api.github.com/repos/o/r", affinityKey(write))
+	assert.Equal(t, affinityKey(write), affinityKey(read), "keys should match regardless of case")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/o", nil)
+	assert.Equal(t, "api.github.com/users/o", affinityKey(req))
+}
+
+func TestBalancer_Affinity_Fingerprint(t *testing.T) {
+	fingerprint := func(t *Transport) string { return fmt.Sprint(t.Limits.Load(ResourceCore).Remaining) }
+	pool := func() *Balancer {
+		return &Balancer{
+			Transports:  []*Transport{withRemaining(100), withRemaining(200), withRemaining(300), withRemaining(400)},
+			Affinity:    RepositoryAffinity,
+			Fingerprint: fingerprint,
+		}
+	}
+	a, b := pool(), pool()
+	for i := range 32 {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("https://api.github.com/repos/o/r%d", i), nil)
+		selectedA, _ := a.selectTransport(req, ResourceCore, nil)
+		selectedB, _ := b.selectTransport(req, ResourceCore, nil)
+		assert.Equal(t, a.index(selectedA), b.index(selectedB), "unnamed transports should be identified by their fingerprint")
+	}
+}
@@ -0,0 +1,255 @@
+package ghratelimit
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTokenRefresh is how long before an installation token expires that AppPool mints a new one, if Refresh is not set.
+const DefaultTokenRefresh = 10 * time.Minute
+
+// AppPool spreads a GitHub App's work across its installations, each of which has its own rate limit:
+// it enumerates the App's installations, mints an installation token for each, and keeps a Transport per installation
+// in the Pool, minting new tokens before they expire and removing the transports of uninstalled installations.
+type AppPool struct {
+	// AppID is the GitHub App's ID (or client ID), the issuer of the JWTs used to authenticate as the App.
+	AppID string
+	// PrivateKey is the GitHub App's private key, ex: parsed with x509.ParsePKCS1PrivateKey.
+	PrivateKey *rsa.PrivateKey
+	// BaseURL is the base URL of the REST API, if nil the Pool's BaseURL is used (see RateLimitURL).
+	// It is also set as the BaseURL of the Transport of each installation, so they poll the same API.
+	BaseURL *url.URL
+	// Base is the RoundTripper used for every request, if nil http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Pool is the Balancer that a Transport per installation is added to.
+	Pool *Balancer
+	// Configure, if set, is called with the Transport of each installation before it is added to the Pool,
+	// ex: to set WaitOnExhaustion. Its Base must not be replaced.
+	Configure func(installation int64, transport *Transport)
+	// Refresh is how long before an installation token expires that a new one is minted, if zero DefaultTokenRefresh is used.
+	Refresh time.Duration
+	// OnError, if set, is called with every error from Run instead of logging it.
+	OnError func(error)
+
+	mu            sync.Mutex
+	installations map[int64]*appInstallation
+}
+
+// appInstallation is the state of a single installation in an AppPool.
+type appInstallation struct {
+	transport *Transport
+	token     atomic.Pointer[string]
+	expires   time.Time // guarded by AppPool.mu
+}
+
+// installationTransport authenticates every request with the installation's current token.
+type installationTransport struct {
+	id           int64
+	installation *appInstallation
+	base         http.RoundTripper
+}
+
+// Fingerprint implements Fingerprinter, identifying the installation as its tokens rotate.
+func (it *installationTransport) Fingerprint() string {
+	return "installation:" + strconv.FormatInt(it.id, 10)
+}
+
+// RoundTrip implements http.RoundTripper
+func (it *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+*it.installation.token.Load())
+	return it.base.RoundTrip(req)
+}
+
+// base returns the RoundTripper used for every request.
+func (ap *AppPool) base() http.RoundTripper {
+	if ap.Base != nil {
+		return ap.Base
+	}
+	return http.DefaultTransport
+}
+
+// jwt returns a JSON Web Token authenticating as the GitHub App, valid for 9 minutes (GitHub allows at most 10).
+func (ap *AppPool) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": ap.AppID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ap.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("rsa.SignPKCS1v15 failed: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// baseURL returns the BaseURL, or the Pool's BaseURL if nil.
+func (ap *AppPool) baseURL() *url.URL {
+	if ap.BaseURL == nil && ap.Pool != nil {
+		return ap.Pool.BaseURL
+	}
+	return ap.BaseURL
+}
+
+// do sends a request authenticated as the GitHub App, decoding the JSON response into v.
+func (ap *AppPool) do(ctx context.Context, method, path string, query url.Values, expected int, v any) error {
+	u := apiURL(ap.baseURL(), path)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	token, err := ap.jwt(time.Now())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", DefaultAPIVersion)
+	resp, err := ap.base().RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("(http.RoundTripper).RoundTrip for %q failed: %w", u, err)
+	}
+	defer discard(resp)
+	if resp.StatusCode != expected {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("(*http.Response).StatusCode(%d) != %d for %q: %s", resp.StatusCode, expected, u, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("json.Decode for %q failed: %w", u, err)
+	}
+	return nil
+}
+
+// listInstallations returns the IDs of every installation of the GitHub App.
+func (ap *AppPool) listInstallations(ctx context.Context) ([]int64, error) {
+	const perPage = 100
+	var ids []int64
+	for page := 1; ; page++ {
+		var installations []struct {
+			ID int64 `json:"id"`
+		}
+		query := url.Values{"per_page": {strconv.Itoa(perPage)}, "page": {strconv.Itoa(page)}}
+		if err := ap.do(ctx, http.MethodGet, "/app/installations", query, http.StatusOK, &installations); err != nil {
+			return nil, err
+		}
+		for _, installation := range installations {
+			ids = append(ids, installation.ID)
+		}
+		if len(installations) < perPage {
+			return ids, nil
+		}
+	}
+}
+
+// mint creates a new installation token, returning it and when it expires.
+func (ap *AppPool) mint(ctx context.Context, installation int64) (string, time.Time, error) {
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := "/app/installations/" + strconv.FormatInt(installation, 10) + "/access_tokens"
+	if err := ap.do(ctx, http.MethodPost, path, nil, http.StatusCreated, &token); err != nil {
+		return "", time.Time{}, err
+	}
+	return token.Token, token.ExpiresAt, nil
+}
+
+// Sync enumerates the GitHub App's installations, adding a Transport to the Pool for new installations,
+// removing those of uninstalled installations and minting new tokens for any that expire within Refresh.
+func (ap *AppPool) Sync(ctx context.Context) error {
+	ids, err := ap.listInstallations(ctx)
+	if err != nil {
+		return err
+	}
+	refresh := ap.Refresh
+	if refresh <= 0 {
+		refresh = DefaultTokenRefresh
+	}
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if ap.installations == nil {
+		ap.installations = make(map[int64]*appInstallation)
+	}
+	current := make(map[int64]bool, len(ids))
+	var errs []error
+	for _, id := range ids {
+		current[id] = true
+		ai, ok := ap.installations[id]
+		if ok && time.Until(ai.expires) > refresh {
+			continue
+		}
+		token, expires, err := ap.mint(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("installation %d: %w", id, err))
+			continue
+		}
+		if ok {
+			ai.token.Store(&token)
+			ai.expires = expires
+			continue
+		}
+		ai = &appInstallation{expires: expires}
+		ai.token.Store(&token)
+		ai.transport = &Transport{
+			Name:    "installation/" + strconv.FormatInt(id, 10),
+			Base:    &installationTransport{id: id, installation: ai, base: ap.base()},
+			BaseURL: ap.baseURL(),
+		}
+		if ap.Configure != nil {
+			ap.Configure(id, ai.transport)
+		}
+		ap.installations[id] = ai
+		ap.Pool.Add(ai.transport)
+	}
+	for id, ai := range ap.installations {
+		if !current[id] {
+			ap.Pool.Remove(ai.transport)
+			delete(ap.installations, id)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run calls Sync every interval, starting immediately, until ctx is done.
+// The interval should be well below the lifetime of installation tokens (an hour) minus Refresh, ex: a minute.
+func (ap *AppPool) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ap.Sync(ctx); err != nil && ctx.Err() == nil {
+			if ap.OnError != nil {
+				ap.OnError(err)
+			} else {
+				log.Printf("(*ghratelimit.AppPool).Sync failed: %v\n", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ghratelimit
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppPool(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	installations := []int64{1, 2}
+	minted := make(map[int64]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api/v3")
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/app/") {
+			parts := strings.Split(auth, ".")
+			if !assert.Len(t, parts, 3, "app requests should use a JWT") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig), "JWT signature")
+			claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			assert.Contains(t, string(claims), `"iss":"12345"`)
+		}
+		switch {
+		case r.URL.Path == "/app/installations":
+			var body []map[string]int64
+			for _, id := range installations {
+				body = append(body, map[string]int64{"id": id})
+			}
+			_ = json.NewEncoder(w).Encode(body)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			var id int64
+			_, _ = fmt.Sscanf(r.URL.Path, "/app/installations/%d/access_tokens", &id)
+			minted[id]++
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"token":      fmt.Sprintf("token-%d-%d", id, minted[id]),
+				"expires_at": time.Now().Add(time.Hour),
+			})
+		default:
+			_, _ = w.Write([]byte(auth))
+		}
+	}))
+	defer srv.Close()
+	base, _ := url.Parse(srv.URL)
+
+	pool := &Balancer{}
+	var configured []int64
+	ap := &AppPool{
+		AppID:      "12345",
+		PrivateKey: key,
+		BaseURL:    base,
+		Pool:       pool,
+		Configure: func(id int64, transport *Transport) {
+			configured = append(configured, id)
+			assert.Equal(t, base, transport.BaseURL, "installations should poll the App's API")
+		},
+	}
+	require.NoError(t, ap.Sync(context.Background()))
+	assert.Equal(t, 2, pool.Len())
+	assert.ElementsMatch(t, []int64{1, 2}, configured)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/user", nil)
+	resp, err := pool.RoundTrip(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Regexp(t, `^token-[12]-1$`, string(body), "pool requests should use an installation token")
+
+	require.NoError(t, ap.Sync(context.Background()))
+	assert.Equal(t, map[int64]int{1: 1, 2: 1}, minted, "unexpired tokens should not be minted again")
+
+	ap.Refresh = 2 * time.Hour
+	mu.Lock()
+	installations = []int64{2}
+	mu.Unlock()
+	require.NoError(t, ap.Sync(context.Background()))
+	assert.Equal(t, 1, pool.Len(), "uninstalled installations should be removed")
+	assert.Equal(t, map[int64]int{1: 1, 2: 2}, minted, "expiring tokens should be minted again")
+
+	resp, err = pool.RoundTrip(req)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "token-2-2", string(body), "the refreshed token should be used")
+}
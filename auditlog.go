@@ -0,0 +1,151 @@
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CursorStore persists the cursors of resumable operations (ex: an AuditLogExport), so an operation interrupted
+// (ex: by exhaustion or a deadline) resumes where it stopped. Implementations must be safe for concurrent use.
+type CursorStore interface {
+	// LoadCursor returns the cursor saved for the key, or an empty string if there is none.
+	LoadCursor(key string) (string, error)
+	// SaveCursor saves the cursor for the key.
+	SaveCursor(key, cursor string) error
+}
+
+// MemoryCursorStore is an in-memory CursorStore, cursors are lost when the process exits.
+type MemoryCursorStore struct {
+	m syncMap
+}
+
+// LoadCursor implements CursorStore
+func (s *MemoryCursorStore) LoadCursor(key string) (string, error) {
+	cursor, _ := s.m.Load(key)
+	c, _ := cursor.(string)
+	return c, nil
+}
+
+// SaveCursor implements CursorStore
+func (s *MemoryCursorStore) SaveCursor(key, cursor string) error {
+	s.m.Store(key, cursor)
+	return nil
+}
+
+// nextCursor returns the "after" cursor of the rel="next" Link of a paginated response, if any.
+func nextCursor(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		for entry := range strings.SplitSeq(link, ",") {
+			target, params, ok := strings.Cut(entry, ";")
+			if !ok || !strings.Contains(params, `rel="next"`) {
+				continue
+			}
+			if u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>")); err == nil {
+				return u.Query().Get("after")
+			}
+		}
+	}
+	return ""
+}
+
+// AuditLogExport pages through the audit log of an organization or enterprise within the ResourceAuditLog budget,
+// saving the "after" cursor of the next page to Cursors once each page has been handled, so an export interrupted by
+// exhaustion (or a deadline, or a restart) resumes from the same page next time. Pages are delivered at least once.
+type AuditLogExport struct {
+	// Transport is the RoundTripper used to send requests, typically a *Transport or *Balancer.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Limits, if set, paces the pages so the remaining ResourceAuditLog requests last until the window resets,
+	// waiting for the reset once it is exhausted (bounded by the context), see PaceAll. Typically the Limits of the Transport.
+	Limits *Limits
+	// URL is the audit log endpoint, ex: https://api.github.com/orgs/{org}/audit-log?phrase=action:repo&order=asc&per_page=100.
+	URL *url.URL
+	// Cursors, if set, is where the cursor is saved. If nil, every export starts from the first page.
+	Cursors CursorStore
+	// Key is the key the cursor is saved under, if empty the URL is used.
+	Key string
+}
+
+// Run exports the audit log, calling fn with the events of each page until the last page or an error.
+// If fn returns an error the cursor is not advanced, so the page is delivered again by the next Run.
+func (e *AuditLogExport) Run(ctx context.Context, fn func(events []json.RawMessage) error) error {
+	key := e.Key
+	if key == "" {
+		key = e.URL.String()
+	}
+	var cursor string
+	if e.Cursors != nil {
+		var err error
+		if cursor, err = e.Cursors.LoadCursor(key); err != nil {
+			return fmt.Errorf("(CursorStore).LoadCursor failed: %w", err)
+		}
+	}
+	page := func(ctx context.Context) (bool, error) {
+		events, next, err := e.page(ctx, cursor)
+		if err != nil {
+			return false, err
+		}
+		if err := fn(events); err != nil {
+			return false, err
+		}
+		if next == "" {
+			return false, nil
+		}
+		cursor = next
+		if e.Cursors != nil {
+			if err := e.Cursors.SaveCursor(key, cursor); err != nil {
+				return false, fmt.Errorf("(CursorStore).SaveCursor failed: %w", err)
+			}
+		}
+		return true, nil
+	}
+	if e.Limits != nil {
+		return e.Limits.PaceAll(ctx, ResourceAuditLog, page)
+	}
+	for {
+		if more, err := page(ctx); err != nil || !more {
+			return err
+		}
+	}
+}
+
+// page fetches the page of the audit log after the cursor, returning its events and the cursor of the next page.
+func (e *AuditLogExport) page(ctx context.Context, cursor string) ([]json.RawMessage, string, error) {
+	u := *e.URL
+	if cursor != "" {
+		q := u.Query()
+		q.Set("after", cursor)
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("http.NewRequestWithContext failed: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	transport := e.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("audit log page after %q: %w", cursor, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("(*http.Response).StatusCode(%d) != 200 for %q: %s", resp.StatusCode, u.String(), string(body))
+	}
+	var events []json.RawMessage
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, "", fmt.Errorf("json.Unmarshal failed: %w", err)
+	}
+	return events, nextCursor(resp.Header), nil
+}
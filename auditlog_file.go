@@ -0,0 +1,62 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FileCursorStore is a CursorStore persisting the cursors as a JSON object in the file at Path, replaced atomically.
+type FileCursorStore struct {
+	// Path is the path of the file, it is created by the first SaveCursor.
+	Path string
+
+	mu sync.Mutex
+}
+
+// load reads the cursors from the file, a missing file has no cursors.
+func (s *FileCursorStore) load() (map[string]string, error) {
+	cursors := make(map[string]string)
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cursors, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("os.ReadFile for %q failed: %w", s.Path, err)
+	}
+	if err := json.Unmarshal(b, &cursors); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal for %q failed: %w", s.Path, err)
+	}
+	return cursors, nil
+}
+
+// LoadCursor implements CursorStore
+func (s *FileCursorStore) LoadCursor(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursors, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return cursors[key], nil
+}
+
+// SaveCursor implements CursorStore
+func (s *FileCursorStore) SaveCursor(key, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cursors, err := s.load()
+	if err != nil {
+		return err
+	}
+	cursors[key] = cursor
+	b, err := json.Marshal(cursors)
+	if err != nil {
+		return fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	return writeFile(s.Path, b)
+}
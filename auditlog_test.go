@@ -0,0 +1,70 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogExport(t *testing.T) {
+	pages := map[string]struct{ events, next string }{
+		"":   {`[{"action":"repo.create"}]`, "c1"},
+		"c1": {`[{"action":"repo.destroy"}]`, "c2"},
+		"c2": {`[{"action":"org.update_member"}]`, ""},
+	}
+	var limited bool
+	var requested []string
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, ResourceAuditLog, InferResource(req))
+		after := req.URL.Query().Get("after")
+		requested = append(requested, after)
+		if limited && after == "c1" {
+			return &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"X-Ratelimit-Remaining": []string{"0"}}, Body: io.NopCloser(strings.NewReader(`{}`)), Request: req}, nil
+		}
+		page := pages[after]
+		header := http.Header{}
+		if page.next != "" {
+			header.Set("Link", `<https://api.github.com/orgs/o/audit-log?after=`+page.next+`&per_page=1>; rel="next"`)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(page.events)), Request: req}, nil
+	})
+	u, _ := url.Parse("https://api.github.com/orgs/o/audit-log?order=asc&per_page=1")
+	store := &FileCursorStore{Path: filepath.Join(t.TempDir(), "cursors.json")}
+	export := &AuditLogExport{Transport: base, Limits: &Limits{}, URL: u, Cursors: store}
+
+	var actions []string
+	collect := func(events []json.RawMessage) error {
+		for _, event := range events {
+			var e struct{ Action string }
+			assert.NoError(t, json.Unmarshal(event, &e))
+			actions = append(actions, e.Action)
+		}
+		return nil
+	}
+	limited = true
+	assert.Error(t, export.Run(context.Background(), collect), "the export should be interrupted by the rate limit")
+	assert.Equal(t, []string{"repo.create"}, actions)
+	cursor, err := store.LoadCursor(u.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", cursor, "the cursor of the next page should be checkpointed")
+
+	limited = false
+	errFailed := errors.New("failed")
+	assert.ErrorIs(t, export.Run(context.Background(), func([]json.RawMessage) error { return errFailed }), errFailed)
+	cursor, _ = (&FileCursorStore{Path: store.Path}).LoadCursor(u.String())
+	assert.Equal(t, "c1", cursor, "the cursor should not advance past a page that was not handled")
+
+	assert.NoError(t, export.Run(context.Background(), collect))
+	assert.Equal(t, []string{"repo.create", "repo.destroy", "org.update_member"}, actions, "the export should resume from the checkpoint")
+	assert.Equal(t, []string{"", "c1", "c1", "c1", "c2"}, requested)
+}
@@ -2,49 +2,484 @@ package ghratelimit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // BalancingTransport distributes requests to the transport with the highest "remaining" rate limit to execute the request.
 // This can be used to distributes requests across multiple GitHub authentication tokens or applications.
+// Use a Balancer to configure the selection (ex: a Strategy, Failover or Health) or to add and remove transports while in use.
 type BalancingTransport []*Transport
 
 // Poll calls (*Transport).Poll for every transport
 func (bt BalancingTransport) Poll(ctx context.Context, interval time.Duration, u *url.URL) {
-	for _, transport := range bt {
-		go transport.Poll(ctx, interval, u)
-	}
+	(&Balancer{Transports: bt}).Poll(ctx, interval, u)
 	<-ctx.Done()
 }
 
 // RoundTrip implements http.RoundTripper
 func (bt BalancingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if len(bt) == 0 {
-		return nil, fmt.Errorf("no transports available")
+	return (&Balancer{Transports: bt}).RoundTrip(req)
+}
+
+// Balancer distributes requests to a transport selected by its Strategy (by default, the transport with the highest "remaining" rate limit) to execute the request.
+// Unlike a BalancingTransport, it is configurable and transports can be added and removed while it is in use.
+type Balancer struct {
+	// Transports is the pool of transports that requests are distributed across.
+	// It must not be modified directly once the Balancer is in use, use Add and Remove instead.
+	Transports []*Transport
+	// BaseURL is the base URL of the REST API used by Poll for transports without a BaseURL of their own, see RateLimitURL.
+	BaseURL *url.URL
+	// UnknownResource is the resource type attributed to requests whose resource cannot be inferred (see InferResource).
+	// If empty, they are rejected with ErrUnknownResource, use ResourceOther to send them without any throttling.
+	UnknownResource Resource
+	// CostEstimator, if set, estimates how many points a request will consume (ex: GraphQL queries or expensive endpoints),
+	// transports known to have fewer requests remaining than the estimate are avoided.
+	// It should typically match the CostEstimator of the Transports.
+	CostEstimator func(*http.Request) uint64
+	// Strategy selects which transport executes each request.
+	// If nil, HighestRemaining is used. Use SetStrategy to replace it once the Balancer is in use.
+	Strategy Strategy
+	// ResourceStrategies overrides the Strategy for specific resource types,
+	// ex: RoundRobin for ResourceCore but HighestRemaining for ResourceSearch.
+	ResourceStrategies map[Resource]Strategy
+	// DeadLetter, if set, receives a sanitized description of every request rejected by the Balancer.
+	DeadLetter DeadLetterSink
+	// Failover is the maximum number of additional transports a request is retried on
+	// if the selected transport returns an error or a rate-limited response. Non-idempotent requests (see RetryAborted)
+	// only fail over on errors that mean nothing was sent (ex: ErrRateLimited or ErrCircuitOpen), to avoid duplicating writes.
+	// Once every attempt has failed, the errors are joined, each wrapped in an *AttemptError.
+	// Requests exceeding their latency budget fail over to every other transport regardless, see ContextWithLatencyBudget,
+	// while requests with an ordering key never fail over, see ContextWithOrderingKey.
+	Failover int
+	// StandbyThreshold is the fraction (0.0 to 1.0) of the active transports' capacity for a resource
+	// below which transports marked as Standby are also considered for selection.
+	StandbyThreshold float64
+	// FailOnSaturation, if true, rejects requests with ErrSaturated when every transport is at its MaxConcurrency.
+	// Otherwise the request is queued on the selected transport until it has a free slot.
+	FailOnSaturation bool
+	// Health, if set, evicts transports from selection after consecutive failures, ex: because the token was revoked.
+	Health *HealthPolicy
+	// OnEvent is called for notable events, such as the Strategy being replaced.
+	OnEvent func(Event)
+	// WriteAffinity, if non-zero, is how long after a successful write (ex: a POST) reads of the same repository
+	// (or path, outside of /repos/) prefer the transport that executed the write, as replication lag may serve
+	// stale data to the others.
+	WriteAffinity time.Duration
+	// Affinity, if set, returns a key (ex: RepositoryAffinity or OwnerAffinity) that is consistently routed to the same
+	// transport while it has quota, as GitHub's caching and some secondary rate limits behave better that way.
+	// Requests with an empty key, or whose preferred transport is exhausted, are selected by the Strategy.
+	// Keys are only stable across restarts for transports with a Name or a fingerprint (see Fingerprint).
+	Affinity func(*http.Request) string
+	// Floors is the minimum remaining requests per resource type below which a transport is avoided, except by PriorityHigh
+	// requests, ex: {ResourceCore: 50} to keep a reserve for interactive use. If every transport is below its floor
+	// (or exhausted), the request is sent to one anyway, see WaitOnExhaustion.
+	Floors map[Resource]uint64
+	// Fingerprint, if set, returns a non-reversible fingerprint of a transport's credential (ex: using FingerprintToken),
+	// used to detect the same credential being added to the pool twice. If nil, (*Transport).Fingerprint is used.
+	Fingerprint func(*Transport) string
+	// Fallback, if set, is called when every transport is exhausted for the inferred resource type, to rewrite the request
+	// to an equivalent endpoint on a different resource type (ex: a GraphQL query when ResourceCore is empty).
+	// Selection is then re-run for the returned request and resource type, return a nil request to send it unchanged.
+	Fallback func(*http.Request) (*http.Request, Resource, error)
+	// FetchConcurrency is the maximum number of transports refreshed at once by Fetch.
+	// If zero, DefaultFetchConcurrency is used.
+	FetchConcurrency int
+	// Hooks, if set, are called as each transport is selected, and as each request is dispatched to it and its response parsed.
+	Hooks *Hooks
+	// MultiHost, if true, allows a single pool to span several GitHub instances (ex: github.com and a GitHub Enterprise Server):
+	// each transport only serves requests to the host of its BaseURL (api.github.com by default), so limits are only compared
+	// among the transports bound to the request's host. Requests without a host (ex: "/repos/o/r") are served by any transport,
+	// their URL is resolved against the BaseURL of the selected transport.
+	MultiHost bool
+	// OrderingKey, if set, returns the ordering key of requests without one set by ContextWithOrderingKey,
+	// ex: SCIMOrderingKey to serialize the operations on each user.
+	OrderingKey func(*http.Request) string
+
+	mu               sync.RWMutex // guards replacing Transports
+	standbyThreshold atomic.Pointer[float64]
+	strategy         atomic.Pointer[Strategy]
+	fallbacks        map[Resource]Resource // set by ApplyPolicy
+	sequencer        sequencer
+	affinities       syncMap // affinityKey -> *affinity
+	pins             atomic.Uint64
+}
+
+// Len returns the number of transports in the pool.
+func (bt *Balancer) Len() int {
+	return len(bt.transports())
+}
+
+// transports returns a consistent snapshot of Transports.
+// Transports is only ever replaced (never modified in-place) while holding mu, so the snapshot is safe to use without the lock.
+func (bt *Balancer) transports() []*Transport {
+	bt.mu.RLock()
+	defer bt.mu.RUnlock()
+	return bt.Transports
+}
+
+// SetStandbyThreshold atomically replaces the StandbyThreshold while the Balancer is in use.
+func (bt *Balancer) SetStandbyThreshold(threshold float64) {
+	bt.standbyThreshold.Store(&threshold)
+}
+
+// getStandbyThreshold returns the threshold set by SetStandbyThreshold, or the StandbyThreshold field.
+func (bt *Balancer) getStandbyThreshold() float64 {
+	if threshold := bt.standbyThreshold.Load(); threshold != nil {
+		return *threshold
+	}
+	return bt.StandbyThreshold
+}
+
+// SetStrategy atomically replaces the Strategy while the Balancer is in use, emitting an EventStrategyChanged.
+// In-flight selections complete with the previous Strategy, the state of the pool is unaffected.
+func (bt *Balancer) SetStrategy(strategy Strategy) {
+	prev := bt.strategy.Swap(&strategy)
+	from := bt.Strategy
+	if prev != nil {
+		from = *prev
+	}
+	bt.emit(Event{Kind: EventStrategyChanged, Message: fmt.Sprintf("%s -> %s", strategyName(from), strategyName(strategy))})
+}
+
+// getStrategy returns the strategy for the resource type from ResourceStrategies,
+// or the strategy set by SetStrategy, or the Strategy field, or HighestRemaining.
+func (bt *Balancer) getStrategy(resource Resource) Strategy {
+	if strategy, ok := bt.ResourceStrategies[resource]; ok && strategy != nil {
+		return strategy
+	}
+	if strategy := bt.strategy.Load(); strategy != nil && *strategy != nil {
+		return *strategy
+	}
+	if bt.Strategy != nil {
+		return bt.Strategy
+	}
+	return HighestRemaining{}
+}
+
+// strategyName describes the strategy for events, ex: "ghratelimit.HighestRemaining".
+func strategyName(strategy Strategy) string {
+	if strategy == nil {
+		return fmt.Sprintf("%T", HighestRemaining{})
+	}
+	return fmt.Sprintf("%T", strategy)
+}
+
+// Add appends the transport to the pool, it is safe to call while the Balancer is in use,
+// ex: to add a transport for a freshly minted token. A running Poll does not poll it, call (*Transport).Poll for it.
+// If it shares a credential with a transport already in the pool (see Fingerprint), an EventDuplicateCredential is emitted.
+func (bt *Balancer) Add(transport *Transport) {
+	bt.mu.Lock()
+	bt.Transports = append(slices.Clip(bt.Transports), transport)
+	bt.mu.Unlock()
+	bt.checkDuplicate(transport)
+}
+
+// Remove deletes the transport from the pool, reporting if it was found. It is safe to call while the Balancer
+// is in use, requests already sent to the transport are unaffected (see (*Transport).Drain to wait for them).
+func (bt *Balancer) Remove(transport *Transport) bool {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	idx := slices.Index(bt.Transports, transport)
+	if idx < 0 {
+		return false
+	}
+	bt.Transports = slices.Delete(slices.Clone(bt.Transports), idx, idx+1)
+	return true
+}
+
+// Poll calls (*Transport).Poll for every transport, returning once they have all stopped.
+// If u is nil, the /rate_limit endpoint of each transport's BaseURL (or the Balancer's) is used.
+func (bt *Balancer) Poll(ctx context.Context, interval time.Duration, u *url.URL) {
+	var wg sync.WaitGroup
+	for _, transport := range bt.transports() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pu := u
+			if pu == nil && transport.BaseURL == nil {
+				pu = RateLimitURL(bt.BaseURL)
+			}
+			transport.Poll(ctx, interval, pu)
+		}()
+	}
+	wg.Wait()
+}
+
+// ErrNoTransports is returned by (*Balancer).RoundTrip when there are no transports eligible for selection,
+// ex: because they have all been paused.
+var ErrNoTransports = errors.New("no transports available")
+
+// ErrUnknownResource is returned by (*Balancer).RoundTrip when the resource of a request cannot be inferred
+// (ex: it has no URL) and UnknownResource is not set.
+var ErrUnknownResource = errors.New("unknown resource")
+
+// AttemptError is the failure of a single transport while a Balancer was failing over.
+type AttemptError struct {
+	// Index is the position of the transport in Transports.
+	Index int
+	// Name is the Name of the transport, if any.
+	Name string
+	// Transport is the transport that failed.
+	Transport *Transport
+	// Err is the error returned by the transport, or a description of the rate-limited response.
+	Err error
+}
+
+// Error implements error
+func (e *AttemptError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("transport %q: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("transport %d: %v", e.Index, e.Err)
+}
+
+// Unwrap allows errors.Is and errors.As to match the underlying error.
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// selectTransport uses the Strategy to pick a transport for the request, excluding any that were already tried.
+// It returns nil if there are no eligible transports.
+func (bt *Balancer) selectTransport(req *http.Request, resource Resource, tried map[*Transport]bool) (*Transport, error) {
+	now := time.Now()
+	_, override := EmergencyOverrideFromContext(req.Context())
+	standby := bt.StandbyActive(resource)
+	transports := bt.transports()
+	eligible := make([]*Transport, 0, len(transports))
+	for _, transport := range transports {
+		transport.observeMember(resource)
+		if tried[transport] || (bt.MultiHost && !transport.serves(req)) || transport.Paused() || (transport.Standby && !standby) || (!override && !transport.Healthy()) || transport.CircuitOpen() {
+			continue
+		}
+		eligible = append(eligible, transport)
+	}
+	if len(eligible) == 0 {
+		return nil, nil
+	}
+
+	priority := PriorityFromContext(req.Context())
+	cost := estimateCost(bt.CostEstimator, req)
+	candidates := make([]*Transport, 0, len(eligible))
+	unsaturated := 0
+	for _, transport := range eligible {
+		if transport.Saturated() {
+			continue
+		}
+		unsaturated++
+		if override {
+			candidates = append(candidates, transport)
+			continue
+		}
+		if remaining, capped := transport.capRemaining(resource); capped && remaining < cost {
+			continue
+		}
+		if rate := transport.Limits.Load(resource); rate != nil && (rate.Remaining < cost || (priority < PriorityHigh && rate.Remaining < bt.Floors[resource])) {
+			continue
+		}
+		if transport.permits(priority, resource) && transport.admit(now) {
+			candidates = append(candidates, transport)
+		}
+	}
+	if unsaturated == 0 && bt.FailOnSaturation {
+		debugf("every transport is saturated for %s %s", req.Method, req.URL)
+		return nil, ErrSaturated
+	}
+	if len(candidates) == 0 {
+		candidates = eligible
+	}
+
+	selected := bt.pinned(req, now)
+	if !slices.Contains(candidates, selected) {
+		selected = bt.preferred(req, candidates)
+	}
+	if selected == nil {
+		selected = bt.getStrategy(resource).Select(req, resource, candidates)
+	}
+	if selected == nil {
+		selected = candidates[rand.Intn(len(candidates))]
+	}
+	if Debug() {
+		var remaining uint64
+		if rate := selected.Limits.Load(resource); rate != nil {
+			remaining = rate.Remaining
+		}
+		debugf("selected transport %s of %d candidates for %s %s (resource %s, remaining %d)", bt.identify(selected), len(candidates), req.Method, req.URL, resource, remaining)
+	}
+	return selected, nil
+}
+
+// StandbyActive reports if the transports marked as Standby are currently considered for selection for the resource type,
+// because the remaining capacity of the active transports has dropped below the StandbyThreshold.
+// Only active transports that may currently be selected count, not those that are paused, unhealthy or whose circuit is open.
+func (bt *Balancer) StandbyActive(resource Resource) bool {
+	threshold := bt.getStandbyThreshold()
+	if threshold <= 0 {
+		return false
+	}
+	var active int
+	var remaining, limit uint64
+	for _, transport := range bt.transports() {
+		if transport.Standby || !transport.available() {
+			continue
+		}
+		active++
+		if rate := transport.Limits.Load(resource); rate != nil {
+			remaining += rate.Remaining
+			limit += rate.Limit
+		}
+	}
+	if active == 0 {
+		return true
+	}
+	if limit == 0 {
+		return false
+	}
+	return float64(remaining)/float64(limit) < threshold
+}
+
+// index returns the position of the transport in Transports, or -1.
+func (bt *Balancer) index(transport *Transport) int {
+	return slices.Index(bt.transports(), transport)
+}
+
+// identify returns the Name of the transport, otherwise its fingerprint (see Fingerprint), otherwise its index
+// (which shifts as transports are added and removed).
+func (bt *Balancer) identify(transport *Transport) string {
+	if transport.Name != "" {
+		return transport.Name
+	}
+	if fingerprint := bt.fingerprint(transport); fingerprint != "" {
+		return fingerprint
+	}
+	return strconv.Itoa(bt.index(transport))
+}
+
+// RoundTrip implements http.RoundTripper
+func (bt *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(bt.transports()) == 0 {
+		return nil, reject(bt.DeadLetter, req, "", ErrNoTransports)
 	}
 
 	resource := InferResource(req)
+	if resource == "" && bt.UnknownResource != "" {
+		resource = bt.UnknownResource
+		req = req.WithContext(ContextWithResource(req.Context(), resource))
+	}
 	if resource == "" {
-		return nil, fmt.Errorf("unknown resource for request: %q", req.URL)
+		return nil, reject(bt.DeadLetter, req, resource, fmt.Errorf("%w for request: %q", ErrUnknownResource, req.URL))
 	}
+	next, resource, err := bt.fallback(req, resource)
+	if err != nil {
+		return nil, reject(bt.DeadLetter, req, resource, err)
+	}
+	req = next
 
-	var bestTransport *Transport
-	var bestRemaining uint64
-	for _, transport := range bt {
-		if rate := transport.Limits.Load(resource); rate != nil {
-			if rate.Remaining > bestRemaining {
-				bestRemaining = rate.Remaining
-				bestTransport = transport
+	var seq *sequence
+	if key := requestOrderingKey(req, bt.OrderingKey); key != "" {
+		var done func()
+		if seq, done, err = bt.sequencer.enqueue(req.Context(), key); err != nil {
+			return nil, reject(bt.DeadLetter, req, resource, err)
+		}
+		defer done()
+	}
+
+	tried := make(map[*Transport]bool)
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		selected, err := bt.selectOrdered(seq, req, resource, tried)
+		if err != nil {
+			return nil, reject(bt.DeadLetter, req, resource, errors.Join(append(errs, err)...))
+		}
+		if selected == nil {
+			if len(errs) == 0 {
+				return nil, reject(bt.DeadLetter, req, resource, ErrNoTransports)
+			}
+			return nil, errors.Join(errs...)
+		}
+		tried[selected] = true
+
+		sent := req
+		if bt.MultiHost {
+			sent = selected.rebase(req)
+		}
+		info := HookInfo{Request: sent, Transport: selected, Resource: resource, Rate: selected.Limits.Load(resource)}
+		bt.Hooks.selected(info)
+		info.Start = time.Now()
+		if err := bt.Hooks.request(info); err != nil {
+			return nil, reject(bt.DeadLetter, req, resource, errors.Join(append(errs, err)...))
+		}
+		resp, err := selected.RoundTrip(bt.labelTransport(sent, selected))
+		bt.Hooks.response(info, resp, err, time.Now())
+		if err == nil {
+			bt.pin(req, selected, resp, time.Now())
+		}
+		if bt.Health != nil && req.Context().Err() == nil {
+			selected.health.record(bt.Health, unhealthy(resource, resp, err), selected.clock().Now())
+		}
+		overBudget := errors.Is(err, ErrLatencyBudgetExceeded) // nothing was sent, so failing over is always safe
+		// A non-idempotent request that failed after it may have been sent is not sent again, it could duplicate a write.
+		retryable := DefaultRetryable(resource, resp, nil)
+		if err != nil {
+			retryable = idempotent(req) || unsent(err)
+		}
+		if (attempt >= bt.Failover && !overBudget) || seq != nil || req.Context().Err() != nil || !retryable {
+			if err != nil && len(errs) > 0 {
+				return nil, errors.Join(append(errs, &AttemptError{Index: bt.index(selected), Name: selected.Name, Transport: selected, Err: err})...)
 			}
+			return resp, err
+		}
+		if err == nil {
+			err = fmt.Errorf("rate limited: %s", resp.Status)
 		}
+		errs = append(errs, &AttemptError{Index: bt.index(selected), Name: selected.Name, Transport: selected, Err: err})
+		debugf("transport %s failed for %s %s, failing over: %v", bt.identify(selected), req.Method, req.URL, err)
+		if overBudget {
+			bt.emit(budgeted(req, resource, selected.Limits.Load(resource), BudgetFailover, fmt.Sprintf("transport %s exceeded the latency budget, failing over", bt.identify(selected))))
+		}
+		bt.emit(retried(req, resource, fmt.Sprintf("transport %s failed, failing over: %v", bt.identify(selected), err)))
+
+		next, rerr := rewind(req)
+		if rerr != nil || len(tried) == len(bt.transports()) {
+			if resp != nil {
+				return resp, nil
+			}
+			return nil, errors.Join(errs...)
+		}
+		discard(resp)
+		req = next
 	}
+}
 
-	if bestTransport == nil {
-		return bt[rand.Intn(len(bt))].RoundTrip(req)
+// Wait blocks until any transport has requests remaining for the given resource type,
+// or until the soonest rate-limit window resets.
+func (bt *Balancer) Wait(ctx context.Context, resource Resource) error {
+	var soonest *Transport
+	var soonestReset uint64
+	for _, transport := range bt.transports() {
+		rate := transport.Limits.Load(resource)
+		if rate == nil || rate.Remaining > 0 {
+			return nil
+		}
+		if soonest == nil || rate.Reset < soonestReset {
+			soonest, soonestReset = transport, rate.Reset
+		}
+	}
+	if soonest == nil {
+		return nil
+	}
+	err := soonest.Limits.Wait(ctx, resource)
+	var rlErr *RateLimitError
+	if errors.As(err, &rlErr) {
+		rlErr.Transport = soonest.Name
 	}
-	return bestTransport.RoundTrip(req)
+	return err
 }
@@ -0,0 +1,212 @@
+package ghratelimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failing returns a Transport whose requests always fail with err.
+func failing(err error) *Transport {
+	return &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, err
+	})}
+}
+
+func TestBalancingTransport(t *testing.T) {
+	low, high := withRemaining(1000), withRemaining(5000)
+	var sent []*Transport
+	for _, transport := range []*Transport{low, high} {
+		base := transport.Base
+		transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = append(sent, transport)
+			return base.RoundTrip(req)
+		})
+	}
+	bt := BalancingTransport{low, high}
+	assert.Len(t, bt, 2)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := bt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Transport{high}, sent, "the transport with the most remaining should be selected")
+
+	_, err = BalancingTransport{}.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoTransports)
+}
+
+func TestBalancer_Failover(t *testing.T) {
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	named := failing(errB)
+	named.Name = "app-b"
+	bt := &Balancer{
+		Transports: []*Transport{failing(errA), named},
+		Strategy:   &RoundRobin{},
+		Failover:   2,
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "8e03978e")
+	_, err := bt.RoundTrip(req)
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+	var attemptErr *AttemptError
+	assert.ErrorAs(t, err, &attemptErr)
+	assert.Equal(t, 0, attemptErr.Index, "first attempt should be transport 0")
+	assert.ErrorContains(t, err, `transport "app-b": b failed`, "named transports should be identified by name")
+
+	bt.Transports = append(bt.Transports[:1], &Transport{Base: okResponse()})
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "8e03978e")
+	bt.Strategy = &RoundRobin{}
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "should failover to the healthy transport")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	bt.Failover = 0
+	bt.Strategy = &RoundRobin{}
+	_, err = bt.RoundTrip(req)
+	assert.ErrorIs(t, err, errA, "without Failover the error should be returned as-is")
+}
+
+func TestBalancer_Failover_NonIdempotent(t *testing.T) {
+	var sent int
+	ok := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		return okResponse().RoundTrip(req)
+	})}
+	bt := &Balancer{
+		Transports: []*Transport{failing(io.ErrUnexpectedEOF), ok},
+		Strategy:   &RoundRobin{},
+		Failover:   1,
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	_, err := bt.RoundTrip(req)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Zero(t, sent, "a write that may have been sent should not fail over")
+
+	bt.Transports[0] = failing(fmt.Errorf("transport a: %w", ErrCircuitOpen))
+	bt.Strategy = &RoundRobin{}
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	resp, err := bt.RoundTrip(req)
+	if assert.NoError(t, err, "a write that was not sent should fail over") {
+		discard(resp)
+	}
+	assert.Equal(t, 1, sent)
+}
+
+func TestBalancer_Standby(t *testing.T) {
+	active, standby := withRemaining(1000), withRemaining(5000)
+	standby.Standby = true
+	bt := &Balancer{
+		Transports:       []*Transport{active, standby},
+		StandbyThreshold: 0.1,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, err := bt.selectTransport(req, ResourceCore, nil)
+	assert.NoError(t, err)
+	assert.Same(t, active, selected, "standby should not be selected above the threshold")
+	assert.False(t, bt.StandbyActive(ResourceCore))
+
+	active.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 4900, Remaining: 100})
+	assert.True(t, bt.StandbyActive(ResourceCore))
+	selected, err = bt.selectTransport(req, ResourceCore, nil)
+	assert.NoError(t, err)
+	assert.Same(t, standby, selected, "standby should be selected below the threshold")
+}
+
+func TestBalancer_Standby_Unavailable(t *testing.T) {
+	active, standby := withRemaining(5000), withRemaining(5000)
+	standby.Standby = true
+	bt := &Balancer{
+		Transports:       []*Transport{active, standby},
+		StandbyThreshold: 0.1,
+	}
+	assert.False(t, bt.StandbyActive(ResourceCore))
+
+	active.Pause()
+	assert.True(t, bt.StandbyActive(ResourceCore), "paused transports should not count as capacity")
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, err := bt.selectTransport(req, ResourceCore, nil)
+	assert.NoError(t, err)
+	assert.Same(t, standby, selected, "standby should be selected when no active transport is available")
+}
+
+func TestBalancer_AddRemove(t *testing.T) {
+	expired, fresh := withRemaining(1000), withRemaining(100)
+	bt := &Balancer{Transports: []*Transport{expired}}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+				if resp, err := bt.RoundTrip(req); assert.NoError(t, err) {
+					discard(resp)
+				}
+			}
+		}()
+	}
+	bt.Add(fresh)
+	assert.Equal(t, 2, bt.Len())
+	assert.True(t, bt.Remove(expired))
+	assert.False(t, bt.Remove(expired), "removing twice should report not found")
+	wg.Wait()
+
+	assert.Equal(t, 1, bt.Len())
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, fresh, selected, "removed transports should not be selected")
+}
+
+func TestBalancer_Fallback(t *testing.T) {
+	var paths []string
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		paths = append(paths, req.URL.Path)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}, Request: req}, nil
+	})}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 10})
+	transport.Limits.Store(nil, ResourceGraphQL, &Rate{Limit: 5000, Remaining: 5000})
+	var events []Event
+	bt := &Balancer{
+		Transports: []*Transport{transport},
+		OnEvent:    func(event Event) { events = append(events, event) },
+		Fallback: func(req *http.Request) (*http.Request, Resource, error) {
+			next, _ := http.NewRequestWithContext(req.Context(), http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"{viewer{login}}"}`))
+			return next, ResourceGraphQL, nil
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/user", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err)
+	discard(resp)
+	assert.Equal(t, []string{"/user"}, paths, "should not fall back while core has requests remaining")
+
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 0})
+	resp, err = bt.RoundTrip(req)
+	assert.NoError(t, err)
+	discard(resp)
+	assert.Equal(t, []string{"/user", "/graphql"}, paths, "should fall back once core is exhausted")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventFallback, events[0].Kind)
+		assert.Equal(t, ResourceGraphQL, events[0].Resource)
+	}
+
+	errDecline := errors.New("no equivalent")
+	bt.Fallback = func(*http.Request) (*http.Request, Resource, error) { return nil, "", errDecline }
+	_, err = bt.RoundTrip(req)
+	assert.ErrorIs(t, err, errDecline)
+
+	bt.Fallback = func(*http.Request) (*http.Request, Resource, error) { return nil, "", nil }
+	resp, err = bt.RoundTrip(req)
+	assert.NoError(t, err, "a declined fallback should send the request unchanged")
+	discard(resp)
+	assert.Equal(t, "/user", paths[len(paths)-1])
+}
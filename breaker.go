@@ -0,0 +1,125 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen matches every *CircuitOpenError via errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned by a Transport whose CircuitBreaker is open, without sending the request to GitHub.
+type CircuitOpenError struct {
+	// Transport is the Name of the transport, if any.
+	Transport string
+	// Until is when the circuit breaker half-opens, allowing probe requests.
+	Until time.Time
+}
+
+// Error implements error
+func (e *CircuitOpenError) Error() string {
+	msg := "circuit breaker open"
+	if e.Transport != "" {
+		msg += fmt.Sprintf(": transport=%q", e.Transport)
+	}
+	if !e.Until.IsZero() {
+		msg += " until " + e.Until.Format(time.RFC3339)
+	}
+	return msg
+}
+
+// Is reports if the target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitBreaker configures a Transport to stop sending requests after consecutive rate-limited or server error responses,
+// protecting GitHub (and the credential) from being hammered during an incident.
+// While open, requests fail immediately with a *CircuitOpenError and a Balancer selects other transports.
+// After the Cooldown it half-opens, letting Probes requests through: the circuit closes if one succeeds and re-opens if one fails.
+type CircuitBreaker struct {
+	// Failures is the number of consecutive rate-limited (see DefaultRetryable), 5xx or failed requests that open the circuit.
+	// If less than 1, the circuit never opens.
+	Failures int
+	// Cooldown is how long the circuit stays open before half-opening.
+	Cooldown time.Duration
+	// Probes is the maximum number of concurrent requests allowed while half-open, if less than 1 a single probe is allowed.
+	Probes int
+}
+
+// breaker is the state of a transport's CircuitBreaker.
+type breaker struct {
+	mu         sync.Mutex
+	failures   int
+	openUntil  time.Time // zero while closed
+	probes     int       // in-flight probe requests while half-open
+	generation uint64    // incremented every time the circuit opens, so stale outcomes are ignored
+}
+
+// allow reserves permission to send a request, returning a function to record its outcome.
+// A failed outcome is a rate-limited, 5xx or failed request, a neutral outcome (ex: the context was cancelled) is neither.
+func (b *breaker) allow(policy *CircuitBreaker, name string, now time.Time) (func(failed, neutral bool), error) {
+	if policy == nil || policy.Failures < 1 {
+		return func(bool, bool) {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	probe := false
+	if !b.openUntil.IsZero() {
+		if now.Before(b.openUntil) || b.probes >= max(policy.Probes, 1) {
+			return nil, &CircuitOpenError{Transport: name, Until: b.openUntil}
+		}
+		b.probes++
+		probe = true
+	}
+	generation := b.generation
+	return func(failed, neutral bool) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if probe {
+			b.probes--
+		}
+		if neutral || generation != b.generation {
+			return
+		}
+		if !failed {
+			b.failures, b.openUntil = 0, time.Time{}
+			return
+		}
+		b.failures++
+		if probe || b.failures >= policy.Failures {
+			b.openUntil = now.Add(policy.Cooldown)
+			b.generation++
+		}
+	}, nil
+}
+
+// open reports if the circuit is open and not yet half-open.
+func (b *breaker) open(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero() && now.Before(b.openUntil)
+}
+
+// CircuitOpen reports if the transport's CircuitBreaker is open, rejecting requests.
+func (t *Transport) CircuitOpen() bool {
+	return t.breaker.open(t.clock().Now())
+}
+
+// breakerOutcome classifies the outcome of a request for the CircuitBreaker.
+func breakerOutcome(resource Resource, resp *http.Response, err error) (failed, neutral bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, true
+	}
+	if DefaultRetryable(resource, resp, err) {
+		return true, false
+	}
+	if err != nil {
+		return true, false
+	}
+	return resp.StatusCode >= 500, false
+}
@@ -0,0 +1,74 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Breaker(t *testing.T) {
+	var status, requests atomic.Int64
+	status.Store(http.StatusBadGateway)
+	transport := &Transport{
+		Name: "flaky",
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests.Add(1)
+			return &http.Response{StatusCode: int(status.Load()), Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+		}),
+		Breaker: &CircuitBreaker{Failures: 2, Cooldown: 50 * time.Millisecond},
+	}
+	get := func() error {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			discard(resp)
+		}
+		return err
+	}
+
+	assert.NoError(t, get())
+	assert.False(t, transport.CircuitOpen())
+	assert.NoError(t, get())
+	assert.True(t, transport.CircuitOpen(), "circuit should open after consecutive failures")
+	err := get()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	var openErr *CircuitOpenError
+	if assert.ErrorAs(t, err, &openErr) {
+		assert.Equal(t, "flaky", openErr.Transport)
+		assert.False(t, openErr.Until.IsZero())
+	}
+	assert.Equal(t, int64(2), requests.Load(), "open circuit should not send requests")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, transport.CircuitOpen(), "circuit should half-open after the cool-down")
+	assert.NoError(t, get())
+	assert.True(t, transport.CircuitOpen(), "failed probe should re-open the circuit")
+
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusOK)
+	assert.NoError(t, get())
+	assert.NoError(t, get())
+	assert.False(t, transport.CircuitOpen(), "successful probe should close the circuit")
+	assert.Equal(t, int64(5), requests.Load())
+}
+
+func TestBalancer_Breaker(t *testing.T) {
+	broken, healthy := withStatus(http.StatusServiceUnavailable), withRemaining(100)
+	broken.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000})
+	broken.Breaker = &CircuitBreaker{Failures: 1, Cooldown: time.Hour}
+	bt := &Balancer{Transports: []*Transport{broken, healthy}}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp, err = bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "transport with an open circuit should not be selected")
+	assert.True(t, bt.Status()[0].CircuitOpen)
+}
@@ -0,0 +1,74 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrLatencyBudgetExceeded is wrapped by the error of a request that would have to wait longer than its latency budget
+// for its rate-limit window to reset, see ContextWithLatencyBudget.
+var ErrLatencyBudgetExceeded = errors.New("latency budget exceeded")
+
+// BudgetDecision is the choice made for a request with a latency budget, reported by an EventLatencyBudget.
+type BudgetDecision string
+
+const (
+	// BudgetWait is decided when the rate-limit window resets within the budget, the request waits for it.
+	BudgetWait BudgetDecision = "wait"
+	// BudgetFailFast is decided when the rate-limit window resets after the budget, the request fails with ErrLatencyBudgetExceeded.
+	BudgetFailFast BudgetDecision = "fail_fast"
+	// BudgetFailover is decided by a Balancer when a transport failed fast, the request is sent to another transport.
+	BudgetFailover BudgetDecision = "failover"
+)
+
+// ContextWithLatencyBudget returns a copy of ctx whose requests may wait at most budget for an exhausted rate limit to reset
+// (see WaitOnExhaustion) independently of its deadline, ex: an interactive request that should rather fail (or fail over
+// to another transport of a Balancer) than wait minutes, while keeping a generous deadline for the request itself.
+// Each decision is reported as an EventLatencyBudget.
+func ContextWithLatencyBudget(ctx context.Context, budget time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey, budget)
+}
+
+// LatencyBudgetFromContext returns the latency budget set by ContextWithLatencyBudget, if any.
+func LatencyBudgetFromContext(ctx context.Context) (time.Duration, bool) {
+	budget, ok := ctx.Value(budgetKey).(time.Duration)
+	return budget, ok
+}
+
+// budgeted describes the decision made for a request with a latency budget as an EventLatencyBudget.
+func budgeted(req *http.Request, resource Resource, rate *Rate, decision BudgetDecision, message string) Event {
+	return Event{
+		Kind:           EventLatencyBudget,
+		Resource:       resource,
+		Rate:           rate,
+		Decision:       decision,
+		IdempotencyKey: IdempotencyKeyFromContext(req.Context()),
+		Message:        fmt.Sprintf("%s %s: %s", req.Method, req.URL.Path, message),
+	}
+}
+
+// budget decides if a request may wait for the resource type's rate limit to reset within its latency budget (if any),
+// returning an error wrapping ErrLatencyBudgetExceeded if not.
+func (t *Transport) budget(req *http.Request, resource Resource, cost uint64) error {
+	budget, ok := LatencyBudgetFromContext(req.Context())
+	if !ok {
+		return nil
+	}
+	rate := t.Limits.Load(resource)
+	if rate == nil || rate.Remaining >= cost {
+		return nil
+	}
+	wait := rate.ResetTime().Sub(t.clock().Now())
+	if wait <= 0 {
+		return nil
+	}
+	if wait <= budget {
+		t.Limits.emit(budgeted(req, resource, rate, BudgetWait, fmt.Sprintf("waiting %s for the rate limit to reset, within the %s budget", wait, budget)))
+		return nil
+	}
+	t.Limits.emit(budgeted(req, resource, rate, BudgetFailFast, fmt.Sprintf("the rate limit resets in %s, after the %s budget", wait, budget)))
+	return fmt.Errorf("%w: the rate limit resets in %s", ErrLatencyBudgetExceeded, wait.Round(time.Second))
+}
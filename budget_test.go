@@ -0,0 +1,103 @@
+package ghratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/bored-engineer/github-rate-limit-http-transport/ghratelimittest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyBudget(t *testing.T) {
+	var mu sync.Mutex
+	var decisions []ghratelimit.BudgetDecision
+	record := func(event ghratelimit.Event) {
+		if event.Kind == ghratelimit.EventLatencyBudget {
+			mu.Lock()
+			defer mu.Unlock()
+			decisions = append(decisions, event.Decision)
+		}
+	}
+	var clock ghratelimittest.Clock
+	server := &ghratelimittest.Server{Now: clock.Now}
+	transport := &ghratelimit.Transport{Base: server, WaitOnExhaustion: true, Limits: ghratelimit.Limits{Clock: &clock, OnEvent: record}}
+	server.Exhaust(ghratelimit.ResourceCore)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+	assert.Empty(t, decisions, "requests without a budget should not be reported")
+
+	_, err = transport.RoundTrip(req.WithContext(ghratelimit.ContextWithLatencyBudget(context.Background(), time.Minute)))
+	assert.ErrorIs(t, err, ghratelimit.ErrLatencyBudgetExceeded, "should fail fast if the reset is after the budget")
+	var rlErr *ghratelimit.RateLimitError
+	assert.ErrorAs(t, err, &rlErr)
+	assert.Equal(t, []ghratelimit.BudgetDecision{ghratelimit.BudgetFailFast}, decisions)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req.WithContext(ghratelimit.ContextWithLatencyBudget(context.Background(), 2*time.Hour)))
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond, "should wait within the budget")
+	clock.Advance(time.Hour)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("did not unblock when the virtual clock reached the reset")
+	}
+	assert.Equal(t, []ghratelimit.BudgetDecision{ghratelimit.BudgetFailFast, ghratelimit.BudgetWait}, decisions)
+}
+
+func TestLatencyBudget_Failover(t *testing.T) {
+	var clock ghratelimittest.Clock
+	server := &ghratelimittest.Server{Now: clock.Now}
+	a := &ghratelimit.Transport{Name: "a", Base: server, WaitOnExhaustion: true, Limits: ghratelimit.Limits{Clock: &clock}}
+	b := &ghratelimit.Transport{Name: "b", Base: server, WaitOnExhaustion: true, Limits: ghratelimit.Limits{Clock: &clock}}
+	a.Limits.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 5000, Reset: uint64(clock.Now().Add(time.Hour).Unix())})
+	b.Limits.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 5000, Reset: uint64(clock.Now().Add(10 * time.Minute).Unix())})
+
+	var mu sync.Mutex
+	var decisions []string
+	record := func(name string) func(ghratelimit.Event) {
+		return func(event ghratelimit.Event) {
+			if event.Kind == ghratelimit.EventLatencyBudget {
+				mu.Lock()
+				defer mu.Unlock()
+				decisions = append(decisions, name+" "+string(event.Decision))
+			}
+		}
+	}
+	a.Limits.OnEvent, b.Limits.OnEvent = record("a"), record("b")
+	bt := &ghratelimit.Balancer{
+		Transports: []*ghratelimit.Transport{a, b},
+		Strategy: ghratelimit.StrategyFunc(func(_ *http.Request, _ ghratelimit.Resource, candidates []*ghratelimit.Transport) *ghratelimit.Transport {
+			return candidates[0]
+		}),
+		OnEvent: record("bt"),
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	done := make(chan error, 1)
+	go func() {
+		resp, err := bt.RoundTrip(req.WithContext(ghratelimit.ContextWithLatencyBudget(context.Background(), 15*time.Minute)))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond, "should wait for the transport within the budget")
+	clock.Advance(10 * time.Minute)
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "should fail over even if Failover is zero")
+	case <-time.After(time.Second):
+		t.Fatal("did not unblock when the virtual clock reached the reset")
+	}
+	assert.Equal(t, []string{"a fail_fast", "bt failover", "b wait"}, decisions)
+}
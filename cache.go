@@ -0,0 +1,260 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CachedResponse is a response stored by a CacheStore.
+type CachedResponse struct {
+	// ETag is the validator sent as If-None-Match to revalidate the response.
+	ETag string `json:"etag"`
+	// LastModified is the validator sent as If-Modified-Since to revalidate the response, if it has no ETag.
+	LastModified string `json:"last_modified,omitempty"`
+	// StatusCode is the status code of the original response.
+	StatusCode int `json:"status_code"`
+	// Header is the headers of the original response.
+	Header http.Header `json:"header"`
+	// Body is the body of the original response.
+	Body []byte `json:"body"`
+	// Stored is when the response was stored (or last revalidated).
+	Stored time.Time `json:"stored"`
+}
+
+// CacheStore stores responses for a CachingTransport.
+// Implementations must be safe for concurrent use, ex: LRUCacheStore, DiskCacheStore or backed by Redis.
+type CacheStore interface {
+	// Get returns the response stored for the key, if any.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores the response for the key.
+	Set(key string, resp *CachedResponse)
+	// Delete removes the response stored for the key, if any.
+	Delete(key string)
+}
+
+// MemoryCacheStore is an unbounded in-memory CacheStore, see LRUCacheStore for a bounded one.
+type MemoryCacheStore struct {
+	m syncMap
+}
+
+// Get implements CacheStore
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	val, ok := s.m.Load(key)
+	if !ok {
+		return nil, false
+	}
+	cr, ok := val.(*CachedResponse)
+	return cr, ok
+}
+
+// Set implements CacheStore
+func (s *MemoryCacheStore) Set(key string, resp *CachedResponse) {
+	s.m.Store(key, resp)
+}
+
+// Delete implements CacheStore
+func (s *MemoryCacheStore) Delete(key string) {
+	s.m.Delete(key)
+}
+
+// CachingTransport sends conditional requests for GET requests it has previously seen a validator for:
+// If-None-Match for an ETag (preferred) or If-Modified-Since for a Last-Modified.
+// GitHub does not count 304 Not Modified responses against the core rate limit, so this avoids spending quota on unchanged data.
+// The cached response is served (with the headers of the 304 response) when GitHub reports it is unchanged.
+type CachingTransport struct {
+	// Base is the base RoundTripper used to make HTTP requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Store is where responses are cached.
+	// If nil, requests are not cached.
+	Store CacheStore
+	// Invalidate returns the paths of the cached GET responses made stale by a successful write (ex: a PATCH),
+	// so this process does not serve stale data after mutating it. Responses for every query of a path are invalidated.
+	// If nil, DefaultInvalidate is used.
+	Invalidate func(*http.Request) []string
+	// StaleWhileRevalidate lists the requests (ex: repository metadata) whose cached responses are served immediately,
+	// without waiting for GitHub, while being revalidated in the background. The first matching rule applies.
+	StaleWhileRevalidate []StaleRule
+	// NegativeCache lists the requests whose 404 Not Found responses are cached for a short time,
+	// so repeatedly probing for nonexistent resources does not spend quota. The first matching rule applies.
+	NegativeCache []NegativeRule
+
+	etagHits, lastModifiedHits     atomic.Uint64
+	misses, stale, negative, bytes atomic.Uint64
+	paths                          syncMap // URL path -> *syncMap of cache keys
+	revalidating                   syncMap // cache key -> struct{}
+	background                     sync.WaitGroup
+}
+
+// CacheStats are the statistics of a CachingTransport.
+type CacheStats struct {
+	// Hits is the number of responses served from the cache because GitHub reported them unchanged.
+	Hits uint64 `json:"hits"`
+	// ETagHits is the number of Hits revalidated with If-None-Match.
+	ETagHits uint64 `json:"etag_hits"`
+	// LastModifiedHits is the number of Hits revalidated with If-Modified-Since.
+	LastModifiedHits uint64 `json:"last_modified_hits"`
+	// Misses is the number of cacheable requests that were not cached, or had changed.
+	Misses uint64 `json:"misses"`
+	// Stale is the number of responses served from the cache without waiting for revalidation, see StaleWhileRevalidate.
+	Stale uint64 `json:"stale"`
+	// Negative is the number of 404 Not Found responses served from the cache, see NegativeCache.
+	Negative uint64 `json:"negative"`
+	// Bytes is the total size of the response bodies served from the cache.
+	Bytes uint64 `json:"bytes"`
+}
+
+// Stats returns the statistics of the cache, ex: to export as metrics.
+func (ct *CachingTransport) Stats() CacheStats {
+	etagHits, lastModifiedHits := ct.etagHits.Load(), ct.lastModifiedHits.Load()
+	return CacheStats{
+		Hits:             etagHits + lastModifiedHits,
+		ETagHits:         etagHits,
+		LastModifiedHits: lastModifiedHits,
+		Misses:           ct.misses.Load(),
+		Stale:            ct.stale.Load(),
+		Negative:         ct.negative.Load(),
+		Bytes:            ct.bytes.Load(),
+	}
+}
+
+// base returns the RoundTripper used to make HTTP requests.
+func (ct *CachingTransport) base() http.RoundTripper {
+	if ct.Base != nil {
+		return ct.Base
+	}
+	return http.DefaultTransport
+}
+
+// CacheKey returns the key a request is cached under, derived from the URL, Accept header and credentials.
+func CacheKey(req *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.Header.Get("Authorization")))
+	return req.URL.String() + "\x00" + req.Header.Get("Accept") + "\x00" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// RoundTrip implements http.RoundTripper
+func (ct *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := ct.base()
+	if ct.Store != nil && isWrite(req) {
+		resp, err := base.RoundTrip(req)
+		if err == nil {
+			ct.invalidate(req, resp)
+		}
+		return resp, err
+	}
+	if ct.Store == nil || req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return base.RoundTrip(req)
+	}
+
+	key := CacheKey(req)
+	cached, ok := ct.Store.Get(key)
+	if ok && cached.StatusCode == http.StatusNotFound {
+		if ct.serveNegative(req, cached) {
+			ct.negative.Add(1)
+			return cached.response(req, nil), nil
+		}
+		cached, ok = nil, false
+	}
+	if ok && ct.serveStale(req, cached) {
+		ct.revalidate(req, key, cached)
+		ct.stale.Add(1)
+		ct.bytes.Add(uint64(len(cached.Body)))
+		return cached.response(req, nil), nil
+	}
+	return ct.fetch(base, req, key, cached, ok)
+}
+
+// fetch sends the request, conditionally if there is a cached response, caching the response if it has a validator.
+func (ct *CachingTransport) fetch(base http.RoundTripper, req *http.Request, key string, cached *CachedResponse, ok bool) (*http.Response, error) {
+	if ok {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		} else {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ok && resp.StatusCode == http.StatusNotModified:
+		discard(resp)
+		revalidated := *cached
+		revalidated.Stored = time.Now()
+		ct.Store.Set(key, &revalidated)
+		if cached.ETag != "" {
+			ct.etagHits.Add(1)
+		} else {
+			ct.lastModifiedHits.Add(1)
+		}
+		ct.bytes.Add(uint64(len(cached.Body)))
+		return cached.response(req, resp.Header), nil
+	case resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""):
+		body, err := readBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		ct.index(req, key)
+		ct.Store.Set(key, &CachedResponse{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			StatusCode:   resp.StatusCode,
+			Header:       resp.Header.Clone(),
+			Body:         body,
+			Stored:       time.Now(),
+		})
+	case resp.StatusCode == http.StatusNotFound && ct.negativeTTL(req) > 0:
+		if err := ct.storeNegative(req, key, resp); err != nil {
+			return nil, err
+		}
+	}
+	ct.misses.Add(1)
+	return resp, nil
+}
+
+// readBody reads and closes the response body (even if reading it fails), replacing it with a copy that can be read again.
+func readBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return nil, fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// response reconstructs the cached response, overlaid with the headers of the revalidation response (ex: X-RateLimit-*).
+func (cr *CachedResponse) response(req *http.Request, header http.Header) *http.Response {
+	merged := cr.Header.Clone()
+	for key, values := range header {
+		merged[key] = values
+	}
+	merged.Set("Content-Length", strconv.Itoa(len(cr.Body)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        merged,
+		Body:          io.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
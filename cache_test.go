@@ -0,0 +1,200 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// etagServer returns a RoundTripper that serves body with the given ETag, responding 304 to matching conditional requests.
+func etagServer(etag, body string, requests *[]*http.Request) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		if req.Header.Get("If-None-Match") == etag {
+			return &http.Response{
+				StatusCode: http.StatusNotModified,
+				Header:     http.Header{"Etag": []string{etag}, "X-Ratelimit-Remaining": []string{"4999"}},
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{etag}, "X-Ratelimit-Remaining": []string{"4998"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestCachingTransport(t *testing.T) {
+	var requests []*http.Request
+	ct := &CachingTransport{
+		Base:  etagServer(`"v1"`, `{"login":"bored-engineer"}`, &requests),
+		Store: &MemoryCacheStore{},
+	}
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		resp, err := ct.RoundTrip(req)
+		assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, `{"login":"bored-engineer"}`, string(body))
+	}
+	assert.Len(t, requests, 2)
+	assert.Empty(t, requests[0].Header.Get("If-None-Match"))
+	assert.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"), "second request should be conditional")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	req.Header.Set("Authorization", "Bearer other")
+	_, err := ct.RoundTrip(req)
+	assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+	assert.Empty(t, requests[2].Header.Get("If-None-Match"), "cache should be keyed by credential")
+	assert.Equal(t, CacheStats{Hits: 1, ETagHits: 1, Misses: 2, Bytes: 26}, ct.Stats())
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	resp := func(body string) *CachedResponse { return &CachedResponse{ETag: `"v"`, Body: []byte(body)} }
+	s := &LRUCacheStore{MaxBytes: 30}
+	s.Set("a", resp("0123456789"))
+	s.Set("b", resp("0123456789"))
+	_, ok := s.Get("a")
+	assert.True(t, ok)
+	s.Set("c", resp("0123456789"))
+	assert.Equal(t, 2, s.Len(), "store should be bounded by MaxBytes")
+	_, ok = s.Get("b")
+	assert.False(t, ok, "least recently used response should be evicted")
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(28), s.Size())
+
+	s.Set("big", resp(strings.Repeat("x", 100)))
+	_, ok = s.Get("big")
+	assert.False(t, ok, "responses larger than MaxBytes should not be stored")
+
+	s = &LRUCacheStore{MaxEntries: 1}
+	s.Set("a", resp(""))
+	s.Set("b", resp(""))
+	assert.Equal(t, 1, s.Len(), "store should be bounded by MaxEntries")
+	s.Delete("b")
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, int64(0), s.Size())
+}
+
+func TestCachingTransport_Invalidate(t *testing.T) {
+	var requests []*http.Request
+	ct := &CachingTransport{Base: etagServer(`"v1"`, `[]`, &requests), Store: &MemoryCacheStore{}}
+	get := func(url string) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		resp, err := ct.RoundTrip(req)
+		assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+		discard(resp)
+	}
+	get("https://api.github.com/repos/o/r/issues?state=open")
+	get("https://api.github.com/repos/o/r/pulls")
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues/1", strings.NewReader(`{}`))
+	resp, err := ct.RoundTrip(req)
+	assert.NoError(t, err)
+	discard(resp)
+
+	requests = nil
+	get("https://api.github.com/repos/o/r/issues?state=open")
+	get("https://api.github.com/repos/o/r/pulls")
+	if assert.Len(t, requests, 2) {
+		assert.Empty(t, requests[0].Header.Get("If-None-Match"), "parent collection should be invalidated by the write")
+		assert.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"), "unrelated paths should stay cached")
+	}
+
+	assert.Equal(t, []string{"/repos/o/r/issues/1", "/repos/o/r/issues"}, DefaultInvalidate(req))
+}
+
+func TestCachingTransport_NegativeCache(t *testing.T) {
+	var requests int
+	ct := &CachingTransport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Header:     http.Header{"X-Ratelimit-Remaining": []string{"4998"}},
+				Body:       io.NopCloser(strings.NewReader(`{"message":"Not Found"}`)),
+				Request:    req,
+			}, nil
+		}),
+		Store:         &MemoryCacheStore{},
+		NegativeCache: []NegativeRule{{Match: MatchPathPrefix("/repos/"), TTL: time.Minute}},
+	}
+
+	get := func(ctx context.Context, path string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com"+path, nil)
+		resp, err := ct.RoundTrip(req)
+		assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, `{"message":"Not Found"}`, string(body))
+	}
+	get(context.Background(), "/repos/o/missing")
+	get(context.Background(), "/repos/o/missing")
+	assert.Equal(t, 1, requests, "404 should be served from the cache")
+	get(ContextWithCacheBypass(context.Background()), "/repos/o/missing")
+	assert.Equal(t, 2, requests, "bypass should always ask GitHub")
+	get(context.Background(), "/users/missing")
+	get(context.Background(), "/users/missing")
+	assert.Equal(t, 4, requests, "404 should only be cached for matching paths")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/missing", nil)
+	cached, _ := ct.Store.Get(CacheKey(req))
+	cached.Stored = time.Now().Add(-time.Hour)
+	get(context.Background(), "/repos/o/missing")
+	assert.Equal(t, 5, requests, "404 should expire after TTL")
+	assert.Equal(t, uint64(1), ct.Stats().Negative)
+}
+
+func TestCachingTransport_LastModified(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var requests []*http.Request
+	ct := &CachingTransport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req)
+			if req.Header.Get("If-Modified-Since") == lastModified {
+				return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Last-Modified": []string{lastModified}},
+				Body:       io.NopCloser(strings.NewReader(`{"login":"bored-engineer"}`)),
+				Request:    req,
+			}, nil
+		}),
+		Store: &MemoryCacheStore{},
+	}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		resp, err := ct.RoundTrip(req)
+		assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, `{"login":"bored-engineer"}`, string(body))
+	}
+	assert.Equal(t, lastModified, requests[1].Header.Get("If-Modified-Since"), "second request should be conditional")
+	assert.Empty(t, requests[1].Header.Get("If-None-Match"))
+	assert.Equal(t, CacheStats{Hits: 1, LastModifiedHits: 1, Misses: 1, Bytes: 26}, ct.Stats())
+
+	requests = nil
+	ct.Base = etagServer(`"v1"`, `{}`, &requests)
+	ct.Store = &MemoryCacheStore{}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, _ := ct.RoundTrip(req)
+	discard(resp)
+	cached, _ := ct.Store.Get(CacheKey(req))
+	cached.LastModified = lastModified
+	_, err := ct.RoundTrip(req)
+	assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+	assert.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"), "ETag should be preferred")
+	assert.Empty(t, requests[1].Header.Get("If-Modified-Since"))
+}
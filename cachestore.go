@@ -0,0 +1,110 @@
+package ghratelimit
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheMaxBytes is the size LRUCacheStore is bounded to if MaxBytes is not set.
+const DefaultCacheMaxBytes = 32 << 20
+
+// LRUCacheStore is an in-memory CacheStore bounded in size, evicting the least recently used responses first.
+// The zero value is ready to use.
+type LRUCacheStore struct {
+	// MaxBytes is the maximum total size of the cached responses, if zero DefaultCacheMaxBytes is used.
+	MaxBytes int64
+	// MaxEntries, if non-zero, is the maximum number of cached responses.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   list.List // of *lruEntry, most recently used first
+	size    int64
+}
+
+// lruEntry is a response in a LRUCacheStore.
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+	size int64
+}
+
+// cachedSize approximates the memory used by a cached response.
+func cachedSize(key string, resp *CachedResponse) int64 {
+	size := len(key) + len(resp.ETag) + len(resp.LastModified) + len(resp.Body)
+	for name, values := range resp.Header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return int64(size)
+}
+
+// Get implements CacheStore
+func (s *LRUCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).resp, true
+}
+
+// Set implements CacheStore
+// Responses larger than MaxBytes are not stored.
+func (s *LRUCacheStore) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maxBytes := s.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	s.remove(key)
+	size := cachedSize(key, resp)
+	if size > maxBytes {
+		return
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]*list.Element)
+	}
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, resp: resp, size: size})
+	s.size += size
+	for s.size > maxBytes || (s.MaxEntries > 0 && len(s.entries) > s.MaxEntries) {
+		s.remove(s.order.Back().Value.(*lruEntry).key)
+	}
+}
+
+// Delete implements CacheStore
+func (s *LRUCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(key)
+}
+
+// remove deletes the entry for the key if any, s.mu must be held.
+func (s *LRUCacheStore) remove(key string) {
+	elem, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.order.Remove(elem)
+	delete(s.entries, key)
+	s.size -= elem.Value.(*lruEntry).size
+}
+
+// Len returns the number of cached responses.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Size returns the approximate total size in bytes of the cached responses.
+func (s *LRUCacheStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
@@ -0,0 +1,68 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// DiskCacheStore is a CacheStore persisting each response as a JSON file in Dir, so the cache survives restarts
+// without holding the responses in memory. Failures to read or write a file are treated as a cache miss.
+type DiskCacheStore struct {
+	// Dir is the directory the responses are stored in, it is created if it does not exist.
+	Dir string
+}
+
+// path returns the file the response for the key is stored in, keys are hashed as they contain URLs.
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements CacheStore
+func (s *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	b, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var resp CachedResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// Set implements CacheStore
+func (s *DiskCacheStore) Set(key string, resp *CachedResponse) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return
+	}
+	// Write to a temporary file first so a concurrent Get never observes a partial response.
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// Delete implements CacheStore
+func (s *DiskCacheStore) Delete(key string) {
+	_ = os.Remove(s.path(key))
+}
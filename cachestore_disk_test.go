@@ -0,0 +1,26 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskCacheStore(t *testing.T) {
+	s := &DiskCacheStore{Dir: filepath.Join(t.TempDir(), "cache")}
+	_, ok := s.Get("https://api.github.com/users/bored-engineer")
+	assert.False(t, ok)
+	s.Set("https://api.github.com/users/bored-engineer", &CachedResponse{ETag: `"v1"`, StatusCode: http.StatusOK, Body: []byte("{}")})
+	cached, ok := s.Get("https://api.github.com/users/bored-engineer")
+	if assert.True(t, ok, "response should be read back from disk") {
+		assert.Equal(t, `"v1"`, cached.ETag)
+		assert.Equal(t, []byte("{}"), cached.Body)
+	}
+	s.Delete("https://api.github.com/users/bored-engineer")
+	_, ok = s.Get("https://api.github.com/users/bored-engineer")
+	assert.False(t, ok)
+}
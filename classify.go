@@ -0,0 +1,62 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Classify reports the rate-limit resource the request will consume, an estimate of how many points it will cost,
+// and if it is a write operation (which also carries a higher secondary rate limit risk).
+// Every request is estimated to cost 1 point, use (*Transport).Classify to estimate it with a CostEstimator.
+// It does not send the request, so it can be used by external schedulers to reason about a request's quota impact.
+func Classify(req *http.Request) (resource Resource, cost uint64, write bool) {
+	return classify(req, nil)
+}
+
+// Classify is like the package-level Classify, but estimates the cost as the transport would, using its CostEstimator.
+func (t *Transport) Classify(req *http.Request) (resource Resource, cost uint64, write bool) {
+	return classify(req, t.CostEstimator)
+}
+
+// classify implements Classify, estimating the cost with the estimator (at least 1).
+func classify(req *http.Request, estimator func(*http.Request) uint64) (resource Resource, cost uint64, write bool) {
+	resource = InferResource(req)
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		write = false
+	case http.MethodPost:
+		if resource == ResourceGraphQL {
+			write = isGraphQLMutation(req)
+		} else {
+			write = true
+		}
+	default:
+		write = true
+	}
+	return resource, estimateCost(estimator, req), write
+}
+
+// maxGraphQLSniff is the maximum number of bytes of a GraphQL request body that are inspected.
+const maxGraphQLSniff = 64 << 10
+
+// isGraphQLMutation inspects the request body (via GetBody) to determine if it is a GraphQL mutation.
+// If the body cannot be obtained, the request is assumed to be a query.
+func isGraphQLMutation(req *http.Request) bool {
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+	var payload struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, maxGraphQLSniff)).Decode(&payload); err != nil {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(payload.Query), "mutation")
+}
@@ -0,0 +1,43 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/search/code?q=x", nil)
+	resource, cost, write := Classify(req)
+	assert.Equal(t, ResourceCodeSearch, resource)
+	assert.Equal(t, uint64(1), cost)
+	assert.False(t, write)
+
+	transport := &Transport{CostEstimator: func(req *http.Request) uint64 {
+		if req.URL.Path == "/graphql" {
+			return 50
+		}
+		return 0
+	}}
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"query { viewer { login } }"}`))
+	_, cost, _ = Classify(req)
+	assert.Equal(t, uint64(1), cost, "the cost should default to 1")
+	_, cost, _ = transport.Classify(req)
+	assert.Equal(t, uint64(50), cost, "the cost should be estimated by the CostEstimator")
+
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	_, cost, write = transport.Classify(req)
+	assert.Equal(t, uint64(1), cost, "the cost should be at least 1")
+	assert.True(t, write, "POST should be a write")
+
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":"query { viewer { login } }"}`))
+	resource, _, write = Classify(req)
+	assert.Equal(t, ResourceGraphQL, resource)
+	assert.False(t, write, "GraphQL query should not be a write")
+
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{"query":" mutation { addStar(input: {}) { clientMutationId } }"}`))
+	_, _, write = Classify(req)
+	assert.True(t, write, "GraphQL mutation should be a write")
+}
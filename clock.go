@@ -0,0 +1,66 @@
+package ghratelimit
+
+import "time"
+
+// Clock is the source of time for reset calculations, waits and polling,
+// replace it (see Limits.Clock) to test exhaustion and reset behavior without real sleeps.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker delivering ticks every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, see time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+}
+
+// SystemClock is the Clock used if none is set, it uses the time package.
+var SystemClock Clock = systemClock{}
+
+// systemClock implements Clock using the time package.
+type systemClock struct{}
+
+// Now implements Clock
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker implements Clock
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTicker implements Ticker using a time.Ticker.
+type systemTicker struct {
+	*time.Ticker
+}
+
+// C implements Ticker
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// clock returns the Clock of the limits, or SystemClock if not set.
+func (l *Limits) clock() Clock {
+	if l.Clock != nil {
+		return l.Clock
+	}
+	return SystemClock
+}
+
+// clock returns the Clock of the transport's Limits.
+func (t *Transport) clock() Clock {
+	return t.Limits.clock()
+}
@@ -0,0 +1,40 @@
+package ghratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/bored-engineer/github-rate-limit-http-transport/ghratelimittest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	var clock ghratelimittest.Clock
+	server := &ghratelimittest.Server{Now: clock.Now}
+	transport := &ghratelimit.Transport{Base: server, WaitOnExhaustion: true, Limits: ghratelimit.Limits{Clock: &clock}}
+
+	server.Exhaust(ghratelimit.ResourceCore)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req.WithContext(context.Background()))
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond, "should wait for the reset")
+	clock.Advance(time.Hour)
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "should be sent once the virtual clock reaches the reset")
+	case <-time.After(time.Second):
+		t.Fatal("did not unblock when the virtual clock reached the reset")
+	}
+	assert.Equal(t, uint64(1), transport.Limits.Load(ghratelimit.ResourceCore).Used)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// admin sends an administrative request to a ghratelimit.AdminHandler.
+func admin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ExitOnError)
+	base := fs.String("url", os.Getenv("GHRATELIMIT_ADMIN_URL"), "URL the AdminHandler is mounted at (default $GHRATELIMIT_ADMIN_URL)")
+	token := fs.String("token", os.Getenv("GHRATELIMIT_ADMIN_TOKEN"), "bearer token sent to the AdminHandler (default $GHRATELIMIT_ADMIN_TOKEN)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: admin [flags] status\n")
+		fmt.Fprintf(fs.Output(), "       admin [flags] <pause|resume|drain|remove> <name|fingerprint>\n")
+		fmt.Fprintf(fs.Output(), "       admin [flags] debug <on|off>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || *base == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	u, err := url.Parse(*base)
+	if err != nil {
+		return fmt.Errorf("url.Parse for %q failed: %w", *base, err)
+	}
+	var method string
+	var payload io.Reader
+	switch action := fs.Arg(0); {
+	case action == "status" && fs.NArg() == 1:
+		method, u = http.MethodGet, u.JoinPath("/")
+	case action == "debug" && fs.NArg() == 2 && (fs.Arg(1) == "on" || fs.Arg(1) == "off"):
+		method, u = http.MethodPut, u.JoinPath("debug")
+		payload = strings.NewReader(fmt.Sprintf(`{"enabled": %t}`, fs.Arg(1) == "on"))
+	case action == "remove" && fs.NArg() == 2:
+		method, u = http.MethodDelete, u.JoinPath(fs.Arg(1))
+	case fs.NArg() == 2:
+		method, u = http.MethodPost, u.JoinPath(fs.Arg(1), action)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), payload)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	if *token != "" {
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("(*http.Client).Do for %q failed: %w", u, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("(*http.Response).Body.Read for %q failed: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("(*http.Response).StatusCode(%d) != 200 for %q: %s", resp.StatusCode, u, string(body))
+	}
+	_, err = os.Stdout.Write(body)
+	return err
+}
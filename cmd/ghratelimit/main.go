@@ -0,0 +1,58 @@
+// Command ghratelimit provides operational tooling built on the ghratelimit package.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+)
+
+// commands maps each subcommand name to its implementation.
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"replay": replay,
+	"admin":  admin,
+	"verify": verify,
+}
+
+// tokenTransport adds the GitHub token to every request.
+type tokenTransport struct {
+	token string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  replay    re-execute dead-lettered requests once budget is available\n")
+	fmt.Fprintf(os.Stderr, "  admin     inspect, pause, resume, drain or remove transports via an AdminHandler\n")
+	fmt.Fprintf(os.Stderr, "  verify    smoke test a token against the GitHub API\n")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := cmd(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", os.Args[0], os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// replay re-executes the dead-letters read from the files (or stdin) in args.
+func replay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token used to replay requests (default $GITHUB_TOKEN)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: replay [flags] [file...]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	transport := &ghratelimit.Transport{
+		Base:             &tokenTransport{token: *token},
+		WaitOnExhaustion: true,
+	}
+	replayer := &ghratelimit.Replayer{
+		Transport: transport,
+		Waiter:    &transport.Limits,
+		OnResult: func(dl *ghratelimit.DeadLetter, resp *http.Response, err error) {
+			if err != nil {
+				fmt.Printf("%s %s: %v\n", dl.Method, dl.URL, err)
+			} else {
+				fmt.Printf("%s %s: %s\n", dl.Method, dl.URL, resp.Status)
+			}
+		},
+	}
+
+	var readers []io.Reader
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("os.Open for %q failed: %w", name, err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	var errs []error
+	for _, r := range readers {
+		if err := replayer.Replay(ctx, ghratelimit.ReadDeadLetters(r)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// verify runs ghratelimit.Verify against the GitHub API, ex: as a smoke test after a GitHub Enterprise Server upgrade.
+func verify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token to verify (default $GITHUB_TOKEN)")
+	api := fs.String("url", os.Getenv("GITHUB_API_URL"), "base URL of the GitHub API, ex: a GitHub Enterprise Server instance (default $GITHUB_API_URL)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: verify [flags]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	transport := &ghratelimit.Transport{Base: &tokenTransport{token: *token}}
+	if *api != "" {
+		base, err := url.Parse(*api)
+		if err != nil {
+			return fmt.Errorf("url.Parse for %q failed: %w", *api, err)
+		}
+		transport.BaseURL = base
+	}
+	if err := ghratelimit.Verify(ctx, transport); err != nil {
+		return err
+	}
+	fmt.Println(transport.Limits.String())
+	return nil
+}
@@ -0,0 +1,117 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// CoalescingTransport single-flights concurrent identical GET requests through one upstream request, fanning its response
+// out to every waiting caller (each with its own copy of the body), ex: repository metadata fetched by many workers at once.
+// Place it in front of the Transport (or Balancer) so only the shared request consumes quota.
+type CoalescingTransport struct {
+	// Base is the base RoundTripper used to make HTTP requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Key returns the key identical requests share, requests with an empty key are not coalesced.
+	// If nil, CoalesceKey is used.
+	Key func(*http.Request) string
+
+	calls           syncMap // key -> *coalescedCall
+	sent, coalesced atomic.Uint64
+}
+
+// coalescedCall is an upstream request shared by identical requests, its result is set once done is closed.
+type coalescedCall struct {
+	done chan struct{}
+	resp *CachedResponse
+	err  error
+}
+
+// errCoalesceAborted is the result of a shared request whose RoundTrip panicked.
+var errCoalesceAborted = errors.New("coalesced request aborted")
+
+// CoalesceStats are the statistics of a CoalescingTransport.
+type CoalesceStats struct {
+	// Sent is the number of coalescable requests sent upstream.
+	Sent uint64 `json:"sent"`
+	// Coalesced is the number of requests that waited for the response of an identical request instead of being sent.
+	Coalesced uint64 `json:"coalesced"`
+}
+
+// Stats returns the statistics of the coalescing, ex: to export as metrics.
+func (ct *CoalescingTransport) Stats() CoalesceStats {
+	return CoalesceStats{Sent: ct.sent.Load(), Coalesced: ct.coalesced.Load()}
+}
+
+// CoalesceKey returns the key a GET request is coalesced under: its CacheKey (URL, Accept header and credentials)
+// and the other request headers that change the response, ex: If-None-Match. Other methods are not coalesced.
+func CoalesceKey(req *http.Request) string {
+	if req.Method != http.MethodGet || req.Body != nil && req.Body != http.NoBody {
+		return ""
+	}
+	key := CacheKey(req)
+	for _, header := range []string{"X-GitHub-Api-Version", "If-None-Match", "If-Modified-Since", "Range"} {
+		key += "\x00" + req.Header.Get(header)
+	}
+	return key
+}
+
+// base returns the RoundTripper used to make HTTP requests.
+func (ct *CoalescingTransport) base() http.RoundTripper {
+	if ct.Base != nil {
+		return ct.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper
+func (ct *CoalescingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	keyFn := ct.Key
+	if keyFn == nil {
+		keyFn = CoalesceKey
+	}
+	key := keyFn(req)
+	if key == "" {
+		return ct.base().RoundTrip(req)
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	if val, loaded := ct.calls.LoadOrStore(key, call); loaded {
+		ct.coalesced.Add(1)
+		inflight := val.(*coalescedCall)
+		select {
+		case <-inflight.done:
+		case <-req.Context().Done():
+			return nil, &WaitError{Op: "coalesced request", Err: context.Cause(req.Context())}
+		}
+		if inflight.err != nil {
+			// the shared request was cancelled by (or panicked in) its caller, not this one, so send it independently
+			if errors.Is(inflight.err, context.Canceled) || errors.Is(inflight.err, context.DeadlineExceeded) || inflight.err == errCoalesceAborted {
+				return ct.base().RoundTrip(req)
+			}
+			return nil, inflight.err
+		}
+		return inflight.resp.response(req, nil), nil
+	}
+
+	ct.sent.Add(1)
+	call.err = errCoalesceAborted
+	defer func() {
+		ct.calls.Delete(key)
+		close(call.done)
+	}()
+	resp, err := ct.base().RoundTrip(req)
+	if err == nil {
+		var body []byte
+		if body, err = readBody(resp); err == nil {
+			call.resp = &CachedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body}
+		}
+	}
+	call.err = err
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
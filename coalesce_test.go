@@ -0,0 +1,159 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalescingTransport(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	ct := &CoalescingTransport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"name":"r"}`)),
+			Request:    req,
+		}, nil
+	})}
+
+	const n = 5
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+			resp, err := ct.RoundTrip(req)
+			if assert.NoError(t, err) {
+				assert.Same(t, req, resp.Request)
+				b, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				bodies[i] = string(b)
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool { return ct.Stats().Coalesced == n-1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int64(1), calls.Load(), "identical requests should share one upstream request")
+	for _, body := range bodies {
+		assert.Equal(t, `{"name":"r"}`, body, "every caller should receive its own copy of the body")
+	}
+	assert.Equal(t, CoalesceStats{Sent: 1, Coalesced: n - 1}, ct.Stats())
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("{}"))
+	resp, err := ct.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(2), calls.Load())
+	assert.Equal(t, uint64(1), ct.Stats().Sent, "writes should not be coalesced")
+}
+
+func TestCoalescingTransport_Cancelled(t *testing.T) {
+	started := make(chan struct{}, 2)
+	ct := &CoalescingTransport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		if req.Header.Get("X-Leader") != "" {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return okResponse().RoundTrip(req)
+	}), Key: func(*http.Request) string { return "key" }}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	leader.Header.Set("X-Leader", "1")
+	done := make(chan error, 1)
+	go func() {
+		_, err := ct.RoundTrip(leader)
+		done <- err
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+		resp, err := ct.RoundTrip(req)
+		if err == nil {
+			discard(resp)
+		}
+		waiter <- err
+	}()
+	assert.Eventually(t, func() bool { return ct.Stats().Coalesced == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.NoError(t, <-waiter, "waiters should send the request themselves if the shared request was cancelled")
+}
+
+// trackedBody is a response body that fails to be read, recording if it was closed.
+type trackedBody struct{ closed atomic.Bool }
+
+func (b *trackedBody) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
+func (b *trackedBody) Close() error             { b.closed.Store(true); return nil }
+
+func TestCoalescingTransport_ReadError(t *testing.T) {
+	body := &trackedBody{}
+	ct := &CoalescingTransport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body, Request: req}, nil
+	})}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	_, err := ct.RoundTrip(req)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.True(t, body.closed.Load(), "the body should be closed if it cannot be read")
+}
+
+func TestCoalescingTransport_Panic(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int64
+	ct := &CoalescingTransport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+			panic("leader panicked")
+		}
+		return okResponse().RoundTrip(req)
+	}), Key: func(*http.Request) string { return "key" }}
+
+	go func() {
+		defer func() { _ = recover() }()
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+		_, _ = ct.RoundTrip(req)
+	}()
+	<-started
+	followed := make(chan error)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+		resp, err := ct.RoundTrip(req)
+		if err == nil {
+			discard(resp)
+		}
+		followed <- err
+	}()
+	assert.Eventually(t, func() bool { return ct.Stats().Coalesced == 1 }, time.Second, time.Millisecond)
+	close(release)
+	select {
+	case err := <-followed:
+		assert.NoError(t, err, "followers should send the request themselves if the leader panicked")
+	case <-time.After(time.Second):
+		t.Fatal("followers should not hang if the leader panicked")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	resp, err := ct.RoundTrip(req)
+	if assert.NoError(t, err, "later requests should not join the aborted call") {
+		discard(resp)
+	}
+}
@@ -0,0 +1,95 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrSaturated is returned by (*Balancer).RoundTrip when FailOnSaturation is set
+// and every transport is at its MaxConcurrency.
+var ErrSaturated = errors.New("all transports are at their maximum concurrency")
+
+// InFlight returns the number of requests currently being executed by the transport.
+// A request is in-flight until its response body is closed.
+func (t *Transport) InFlight() int {
+	return int(t.inflight.Load())
+}
+
+// Saturated reports if the transport is at its MaxConcurrency.
+// Downloads in their own pool (see MaxDownloadConcurrency) are not counted.
+func (t *Transport) Saturated() bool {
+	return t.MaxConcurrency > 0 && t.InFlight()-int(t.downloads.Load()) >= t.MaxConcurrency
+}
+
+// isolated reports if requests of the resource type use the separate download pool, see MaxDownloadConcurrency.
+func (t *Transport) isolated(resource Resource) bool {
+	return resource == ResourceUntracked && t.MaxDownloadConcurrency > 0
+}
+
+// semaphore returns the concurrency slots for requests of the resource type, nil if they are unlimited.
+func (t *Transport) semaphore(resource Resource) chan struct{} {
+	if t.isolated(resource) {
+		t.downloadSemOnce.Do(func() {
+			t.downloadSem = make(chan struct{}, t.MaxDownloadConcurrency)
+		})
+		return t.downloadSem
+	}
+	if t.MaxConcurrency <= 0 {
+		return nil
+	}
+	t.semOnce.Do(func() {
+		t.sem = make(chan struct{}, t.MaxConcurrency)
+	})
+	return t.sem
+}
+
+// acquire reserves a concurrency slot for a request, blocking until one is available if MaxConcurrency
+// (or MaxDownloadConcurrency for downloads) is set. The returned function releases the slot and must be called exactly once.
+func (t *Transport) acquire(req *http.Request, resource Resource) (func(), error) {
+	sem := t.semaphore(resource)
+	if sem == nil {
+		t.inflight.Add(1)
+		return t.release, nil
+	}
+	select {
+	case sem <- struct{}{}:
+	default:
+		t.queued.Add(1)
+		select {
+		case sem <- struct{}{}:
+			t.queued.Add(-1)
+		case <-req.Context().Done():
+			t.queued.Add(-1)
+			return nil, &WaitError{Op: "concurrency slot", Resource: resource, Err: context.Cause(req.Context())}
+		}
+	}
+	isolated := t.isolated(resource)
+	if isolated {
+		t.downloads.Add(1)
+	}
+	t.inflight.Add(1)
+	return func() {
+		<-sem
+		if isolated {
+			t.downloads.Add(-1)
+		}
+		t.release()
+	}, nil
+}
+
+// releaseBody calls release once the response body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close implements io.Closer
+func (rb *releaseBody) Close() error {
+	err := rb.ReadCloser.Close()
+	rb.once.Do(rb.release)
+	return err
+}
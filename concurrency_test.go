@@ -0,0 +1,62 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_MaxConcurrency(t *testing.T) {
+	transport := &Transport{Base: okResponse(), MaxConcurrency: 1}
+	bt := &Balancer{Transports: []*Transport{transport}, FailOnSaturation: true}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, 1, transport.InFlight())
+	assert.True(t, transport.Saturated(), "transport should be saturated until the body is closed")
+
+	_, err = bt.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrSaturated)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = transport.RoundTrip(req.WithContext(ctx))
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "should wait for a concurrency slot")
+
+	assert.NoError(t, resp.Body.Close())
+	assert.NoError(t, resp.Body.Close(), "double close should not release twice")
+	assert.Equal(t, 0, transport.InFlight())
+	assert.False(t, transport.Saturated())
+
+	resp, err = bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	discard(resp)
+}
+
+func TestTransport_MaxDownloadConcurrency(t *testing.T) {
+	transport := &Transport{Base: okResponse(), MaxConcurrency: 1, MaxDownloadConcurrency: 1}
+	download, _ := http.NewRequest(http.MethodGet, "https://codeload.github.com/o/r/tar.gz/main", nil)
+	resp, err := transport.RoundTrip(download)
+	assert.NoError(t, err, "(*Transport).RoundTrip failed")
+	assert.False(t, transport.Saturated(), "downloads should not hold API concurrency slots")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = transport.RoundTrip(download.WithContext(ctx))
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "should wait for a download slot")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	api, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "API calls should not wait for downloads")
+	assert.True(t, transport.Saturated())
+	assert.Equal(t, 2, transport.InFlight())
+	discard(api)
+	discard(resp)
+	assert.Equal(t, 0, transport.InFlight())
+}
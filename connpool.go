@@ -0,0 +1,58 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"time"
+)
+
+// MaxConcurrentRequests is the maximum number of concurrent requests GitHub allows per credential,
+// a ConnectionPool never opens more connections per host than this.
+const MaxConcurrentRequests = 100
+
+// DefaultMaxConnsPerHost is the MaxConnsPerHost of a ConnectionPool if neither it nor the transport's MaxConcurrency is set.
+const DefaultMaxConnsPerHost = 10
+
+// ConnectionPool configures a dedicated *http.Transport (and so a dedicated connection pool) for each member of a Balancer,
+// as sharing one across many credentials causes head-of-line blocking that defeats balancing.
+type ConnectionPool struct {
+	// MaxConnsPerHost is the maximum number of connections per host of each transport.
+	// If zero, the MaxConcurrency of the transport is used, or DefaultMaxConnsPerHost. It is capped at MaxConcurrentRequests.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long idle connections are kept open, if zero http.DefaultTransport's is used.
+	IdleConnTimeout time.Duration
+}
+
+// NewTransport returns a new *http.Transport for the transport, cloned from http.DefaultTransport with its own connection pool.
+// Every connection may be kept idle, so bursts do not churn connections.
+func (p *ConnectionPool) NewTransport(transport *Transport) *http.Transport {
+	conns := p.MaxConnsPerHost
+	if conns <= 0 && transport != nil {
+		conns = transport.MaxConcurrency
+	}
+	if conns <= 0 {
+		conns = DefaultMaxConnsPerHost
+	}
+	conns = min(conns, MaxConcurrentRequests)
+
+	rt := http.DefaultTransport.(*http.Transport).Clone()
+	rt.MaxConnsPerHost = conns
+	rt.MaxIdleConnsPerHost = conns
+	if p.IdleConnTimeout > 0 {
+		rt.IdleConnTimeout = p.IdleConnTimeout
+	}
+	return rt
+}
+
+// Partition gives every transport in the pool its own *http.Transport from NewTransport, it must be called before the pool is in use.
+// The Base of each transport is replaced with the result of base, which should wrap the *http.Transport with the transport's
+// credential (ex: an oauth2.Transport). If base is nil, the *http.Transport becomes the Base as-is.
+func (p *ConnectionPool) Partition(bt *Balancer, base func(*Transport, *http.Transport) http.RoundTripper) {
+	for _, transport := range bt.transports() {
+		rt := p.NewTransport(transport)
+		if base == nil {
+			transport.Base = rt
+		} else {
+			transport.Base = base(transport, rt)
+		}
+	}
+}
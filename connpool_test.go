@@ -0,0 +1,35 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionPool(t *testing.T) {
+	var pool ConnectionPool
+	rt := pool.NewTransport(&Transport{})
+	assert.Equal(t, DefaultMaxConnsPerHost, rt.MaxConnsPerHost)
+	assert.Equal(t, DefaultMaxConnsPerHost, rt.MaxIdleConnsPerHost)
+	assert.NotSame(t, http.DefaultTransport, rt)
+	assert.Equal(t, 25, pool.NewTransport(&Transport{MaxConcurrency: 25}).MaxConnsPerHost, "MaxConcurrency should be the default")
+	assert.Equal(t, MaxConcurrentRequests, pool.NewTransport(&Transport{MaxConcurrency: 500}).MaxConnsPerHost, "should be capped")
+
+	pool = ConnectionPool{MaxConnsPerHost: 4, IdleConnTimeout: time.Second}
+	a, b := &Transport{}, &Transport{MaxConcurrency: 50}
+	pool.Partition(&Balancer{Transports: []*Transport{a, b}}, nil)
+	if assert.IsType(t, &http.Transport{}, a.Base) && assert.IsType(t, &http.Transport{}, b.Base) {
+		assert.NotSame(t, a.Base, b.Base, "every transport should have its own connection pool")
+		assert.Equal(t, 4, a.Base.(*http.Transport).MaxConnsPerHost)
+		assert.Equal(t, time.Second, b.Base.(*http.Transport).IdleConnTimeout)
+	}
+
+	var wrapped []*Transport
+	pool.Partition(&Balancer{Transports: []*Transport{a}}, func(transport *Transport, rt *http.Transport) http.RoundTripper {
+		wrapped = append(wrapped, transport)
+		return roundTripperFunc(rt.RoundTrip)
+	})
+	assert.Equal(t, []*Transport{a}, wrapped)
+}
@@ -0,0 +1,107 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type of all context keys defined by this package.
+type contextKey int
+
+const (
+	callerKey contextKey = iota
+	priorityKey
+	overrideKey
+	resourceKey
+	bypassKey
+	versionKey
+	baseKey
+	idempotencyKey
+	budgetKey
+	orderingKey
+)
+
+// ContextWithCaller returns a copy of ctx labelled with the name of the caller (ex: an internal service or job).
+// The label is recorded wherever requests are attributed, such as in dead-letters.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// CallerFromContext returns the caller label set by ContextWithCaller, if any.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey).(string)
+	return caller
+}
+
+// ContextWithPriority returns a copy of ctx whose requests are scheduled with the given priority, see Transport.Reserve.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, priority)
+}
+
+// PriorityFromContext returns the priority set by ContextWithPriority, or PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	priority, _ := ctx.Value(priorityKey).(Priority)
+	return priority
+}
+
+// ContextWithEmergencyOverride returns a copy of ctx whose requests bypass local policies (Pace, Reserve, HardCap, RampUp and
+// health eviction) for incident-response tooling. GitHub's actual rate limits still apply (ex: WaitOnExhaustion).
+// Every overridden request is logged and emits an EventEmergencyOverride with the reason for auditing.
+func ContextWithEmergencyOverride(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, overrideKey, reason)
+}
+
+// EmergencyOverrideFromContext returns the reason set by ContextWithEmergencyOverride, if any.
+func EmergencyOverrideFromContext(ctx context.Context) (string, bool) {
+	reason, ok := ctx.Value(overrideKey).(string)
+	return reason, ok
+}
+
+// ContextWithResource returns a copy of ctx whose requests are attributed to the given resource type,
+// overriding InferResource for balancing and exhaustion checks, ex: for custom or future endpoints.
+func ContextWithResource(ctx context.Context, resource Resource) context.Context {
+	return context.WithValue(ctx, resourceKey, resource)
+}
+
+// ResourceFromContext returns the resource type set by ContextWithResource, if any.
+func ResourceFromContext(ctx context.Context) Resource {
+	resource, _ := ctx.Value(resourceKey).(Resource)
+	return resource
+}
+
+// ContextWithCacheBypass returns a copy of ctx whose requests are never answered by a CachingTransport without asking GitHub,
+// ignoring NegativeCache and StaleWhileRevalidate, ex: for correctness-critical existence checks.
+func ContextWithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey, true)
+}
+
+// CacheBypassFromContext reports if ContextWithCacheBypass was used.
+func CacheBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey).(bool)
+	return bypass
+}
+
+// ContextWithAPIVersion returns a copy of ctx whose requests are sent with the given X-GitHub-Api-Version by a Transport,
+// overriding its APIVersion and any version set on the request.
+func ContextWithAPIVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionKey, version)
+}
+
+// APIVersionFromContext returns the API version set by ContextWithAPIVersion, if any.
+func APIVersionFromContext(ctx context.Context) string {
+	version, _ := ctx.Value(versionKey).(string)
+	return version
+}
+
+// ContextWithBase returns a copy of ctx whose requests are sent by a Transport using base instead of its Base,
+// ex: to route specific requests via a proxy while keeping the same rate-limit accounting and state.
+// The Base is replaced entirely, so base must add any credentials the Base would have.
+func ContextWithBase(ctx context.Context, base http.RoundTripper) context.Context {
+	return context.WithValue(ctx, baseKey, base)
+}
+
+// BaseFromContext returns the RoundTripper set by ContextWithBase, if any.
+func BaseFromContext(ctx context.Context) http.RoundTripper {
+	base, _ := ctx.Value(baseKey).(http.RoundTripper)
+	return base
+}
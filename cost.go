@@ -0,0 +1,11 @@
+package ghratelimit
+
+import "net/http"
+
+// estimateCost returns the number of points the request is estimated to consume per the estimator, at least 1.
+func estimateCost(estimator func(*http.Request) uint64, req *http.Request) uint64 {
+	if estimator == nil {
+		return 1
+	}
+	return max(estimator(req), 1)
+}
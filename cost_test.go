@@ -0,0 +1,36 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostEstimator(t *testing.T) {
+	estimator := func(req *http.Request) uint64 {
+		if req.URL.Path == "/graphql" {
+			return 50
+		}
+		return 1
+	}
+	low, high := &Transport{Base: okResponse(), CostEstimator: estimator, WaitOnExhaustion: true}, withRemaining(1000)
+	low.Limits.Store(nil, ResourceGraphQL, &Rate{Limit: 5000, Remaining: 10, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+	high.Limits.Store(nil, ResourceGraphQL, &Rate{Limit: 5000, Remaining: 1000})
+
+	bt := &Balancer{Transports: []*Transport{low, high}, Strategy: FirstAboveThreshold{}, CostEstimator: estimator}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", nil)
+	selected, _ := bt.selectTransport(req, ResourceGraphQL, nil)
+	assert.Same(t, high, selected, "transports without enough remaining for the estimated cost should be avoided")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := low.RoundTrip(req.WithContext(ctx))
+	assert.ErrorIs(t, err, ErrRateLimited, "WaitOnExhaustion should wait when the estimated cost exceeds the remaining")
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err = low.RoundTrip(req)
+	assert.NoError(t, err, "cheaper requests should not wait")
+}
@@ -0,0 +1,115 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sensitiveHeaders are never included in a DeadLetter.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// DeadLetter is a sanitized description of a request that was rejected before being sent to GitHub.
+type DeadLetter struct {
+	// SchemaVersion is the SchemaVersion the DeadLetter was encoded with.
+	SchemaVersion int `json:"schema_version"`
+	// Time is when the request was rejected.
+	Time time.Time `json:"time"`
+	// Caller is the label set via ContextWithCaller, if any.
+	Caller string `json:"caller,omitempty"`
+	// IdempotencyKey is the key set via ContextWithIdempotencyKey, if any.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+	// URL is the URL of the request, excluding any user info.
+	URL string `json:"url"`
+	// Header is the request headers, excluding any credentials.
+	Header http.Header `json:"header,omitempty"`
+	// Body is the request body, if it could be obtained via (*http.Request).GetBody.
+	Body []byte `json:"body,omitempty"`
+	// Resource is the inferred rate-limit resource of the request, if known.
+	Resource Resource `json:"resource,omitempty"`
+	// Error is the reason the request was rejected.
+	Error string `json:"error"`
+}
+
+// NewDeadLetter creates a sanitized DeadLetter from the rejected request.
+func NewDeadLetter(req *http.Request, resource Resource, reason error) (*DeadLetter, error) {
+	dl := &DeadLetter{
+		SchemaVersion:  SchemaVersion,
+		Time:           time.Now(),
+		Caller:         CallerFromContext(req.Context()),
+		IdempotencyKey: IdempotencyKeyFromContext(req.Context()),
+		Method:         req.Method,
+		Header:         req.Header.Clone(),
+		Resource:       resource,
+	}
+	if reason != nil {
+		dl.Error = reason.Error()
+	}
+	if req.URL != nil {
+		u := *req.URL
+		u.User = nil
+		dl.URL = u.String()
+	}
+	for _, key := range sensitiveHeaders {
+		dl.Header.Del(key)
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("(*http.Request).GetBody failed: %w", err)
+		}
+		defer body.Close()
+		if dl.Body, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("(*http.Request).GetBody.Read failed: %w", err)
+		}
+	}
+	return dl, nil
+}
+
+// DeadLetterSink receives requests that were rejected, so they can be replayed later instead of silently dropped.
+type DeadLetterSink interface {
+	WriteDeadLetter(*DeadLetter) error
+}
+
+// JSONDeadLetterSink writes each DeadLetter as a line of JSON to Writer.
+// It is safe for concurrent use.
+type JSONDeadLetterSink struct {
+	mu sync.Mutex
+	// Writer is where the JSON lines are written.
+	Writer io.Writer
+}
+
+// WriteDeadLetter implements DeadLetterSink
+func (s *JSONDeadLetterSink) WriteDeadLetter(dl *DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.Writer.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("(io.Writer).Write failed: %w", err)
+	}
+	return nil
+}
+
+// reject records the rejected request to the sink (if not nil), returning the reason.
+func reject(sink DeadLetterSink, req *http.Request, resource Resource, reason error) error {
+	if sink == nil {
+		return reason
+	}
+	dl, err := NewDeadLetter(req, resource, reason)
+	if err == nil {
+		err = sink.WriteDeadLetter(dl)
+	}
+	if err != nil {
+		return errors.Join(reason, fmt.Errorf("dead-letter failed: %w", err))
+	}
+	return reason
+}
@@ -0,0 +1,26 @@
+package ghratelimit
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+var debug atomic.Bool
+
+// SetDebug enables or disables logging (via the log package) of every selection and throttling decision made by
+// Transport and Balancer, ex: to capture detailed traces during an incident without a restart.
+func SetDebug(enabled bool) {
+	debug.Store(enabled)
+}
+
+// Debug reports if decision logging is enabled, see SetDebug.
+func Debug() bool {
+	return debug.Load()
+}
+
+// debugf logs the decision if decision logging is enabled.
+func debugf(format string, args ...any) {
+	if debug.Load() {
+		log.Printf("ghratelimit: "+format+"\n", args...)
+	}
+}
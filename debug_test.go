@@ -0,0 +1,31 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug(t *testing.T) {
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetOutput(prev)
+		SetDebug(false)
+	})
+
+	bt := &Balancer{Transports: []*Transport{withRemaining(5000)}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Empty(t, buf.String(), "decisions should not be logged by default")
+
+	SetDebug(true)
+	_, err = bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Contains(t, buf.String(), "selected transport 0 of 1 candidates for GET https://api.github.com/users/bored-engineer (resource core, remaining 5000)")
+}
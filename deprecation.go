@@ -0,0 +1,155 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxDeprecations bounds the number of deprecated endpoints tracked by Limits.
+const maxDeprecations = 1024
+
+// Deprecation describes an endpoint that GitHub signalled as deprecated via the Deprecation (RFC 9745)
+// or Sunset (RFC 8594) response headers. Deprecated endpoints often move to a different rate-limit resource.
+type Deprecation struct {
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+	// Path is the URL path of the latest request, excluding any /api/v3 prefix.
+	Path string `json:"path"`
+	// Route is the template of the Path, with owners, repositories, numbers and SHAs replaced by placeholders,
+	// ex: "/repos/{owner}/{repo}/issues/{number}". Deprecations are tracked per Method and Route.
+	Route string `json:"route"`
+	// Resource is the rate-limit resource of the response, if known.
+	Resource Resource `json:"resource,omitempty"`
+	// Deprecated is when the endpoint was (or will be) deprecated, zero if the header did not include a date.
+	Deprecated time.Time `json:"deprecated,omitzero"`
+	// Sunset is when the endpoint will stop responding, if known.
+	Sunset time.Time `json:"sunset,omitzero"`
+	// Link is the documentation of the deprecation or sunset, if any.
+	Link string `json:"link,omitempty"`
+	// Count is the number of deprecated responses observed.
+	Count uint64 `json:"count"`
+}
+
+// deprecation is a Deprecation tracked by Limits.
+type deprecation struct {
+	mu    sync.Mutex
+	info  Deprecation // guarded by mu, the latest observation excluding its Count
+	count atomic.Uint64
+}
+
+// ParseDeprecation parses the Deprecation and Sunset headers of a response, returning nil if it has neither.
+// The Method, Path and Resource are not set.
+func ParseDeprecation(header http.Header) *Deprecation {
+	deprecation, sunset := header.Get("Deprecation"), header.Get("Sunset")
+	if deprecation == "" && sunset == "" {
+		return nil
+	}
+	d := &Deprecation{}
+	if unix, ok := strings.CutPrefix(deprecation, "@"); ok {
+		if sec, err := strconv.ParseInt(unix, 10, 64); err == nil {
+			d.Deprecated = time.Unix(sec, 0).UTC()
+		}
+	} else if t, err := http.ParseTime(deprecation); err == nil {
+		d.Deprecated = t
+	}
+	if t, err := http.ParseTime(sunset); err == nil {
+		d.Sunset = t
+	}
+	for _, link := range header.Values("Link") {
+		for entry := range strings.SplitSeq(link, ",") {
+			target, params, ok := strings.Cut(entry, ";")
+			if !ok || !strings.Contains(params, `rel="deprecation"`) && !strings.Contains(params, `rel="sunset"`) {
+				continue
+			}
+			d.Link = strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return d
+}
+
+// routeParams are the placeholders of the segment following a top-level path segment.
+var routeParams = map[string]string{
+	"users":       "{username}",
+	"orgs":        "{org}",
+	"enterprises": "{enterprise}",
+	"gists":       "{gist_id}",
+}
+
+// routeTemplate returns the template of an API path, see Deprecation.Route.
+func routeTemplate(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for idx, segment := range segments {
+		switch {
+		case idx == 1 && segments[0] == "repos":
+			segments[idx] = "{owner}"
+		case idx == 2 && segments[0] == "repos":
+			segments[idx] = "{repo}"
+		case idx == 1 && routeParams[segments[0]] != "":
+			segments[idx] = routeParams[segments[0]]
+		case segment != "" && strings.Trim(segment, "0123456789") == "":
+			segments[idx] = "{number}"
+		case len(segment) == 40 && strings.Trim(segment, "0123456789abcdef") == "":
+			segments[idx] = "{sha}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// deprecated records a response that signalled its endpoint is deprecated (see ParseResponseInfo),
+// emitting an EventDeprecated the first time the endpoint is observed.
+func (l *Limits) deprecated(d Deprecation) {
+	if d.Route == "" {
+		d.Route = routeTemplate(d.Path)
+	}
+	key := d.Method + " " + d.Route
+	val, ok := l.deprecations.Load(key)
+	if !ok {
+		if l.deprecationCount.Add(1) > maxDeprecations {
+			l.deprecationCount.Add(-1)
+			return
+		}
+		if val, ok = l.deprecations.LoadOrStore(key, &deprecation{info: d}); ok {
+			l.deprecationCount.Add(-1)
+		}
+	}
+	tracked := val.(*deprecation)
+	if ok {
+		tracked.mu.Lock()
+		tracked.info = d
+		tracked.mu.Unlock()
+	}
+	if tracked.count.Add(1) != 1 {
+		return
+	}
+	msg := key + " is deprecated"
+	if !d.Deprecated.IsZero() {
+		msg += " since " + d.Deprecated.UTC().Format(time.DateOnly)
+	}
+	if !d.Sunset.IsZero() {
+		msg += fmt.Sprintf(", sunset %s", d.Sunset.UTC().Format(time.DateOnly))
+	}
+	if d.Link != "" {
+		msg += " (" + d.Link + ")"
+	}
+	l.emit(Event{Kind: EventDeprecated, Resource: d.Resource, Rate: l.Load(d.Resource), Message: msg})
+}
+
+// Deprecations returns the deprecated endpoints observed in responses, ex: to export as metrics.
+func (l *Limits) Deprecations() []Deprecation {
+	var deprecations []Deprecation
+	l.deprecations.Range(func(_, value any) bool {
+		tracked := value.(*deprecation)
+		tracked.mu.Lock()
+		d := tracked.info
+		tracked.mu.Unlock()
+		d.Count = tracked.count.Load()
+		deprecations = append(deprecations, d)
+		return true
+	})
+	return deprecations
+}
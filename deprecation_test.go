@@ -0,0 +1,83 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeprecation(t *testing.T) {
+	assert.Nil(t, ParseDeprecation(http.Header{}))
+
+	d := ParseDeprecation(http.Header{
+		"Deprecation": []string{"@1688169599"},
+		"Sunset":      []string{"Wed, 11 Nov 2026 23:59:59 GMT"},
+		"Link":        []string{`<https://api.github.com/next>; rel="next", <https://docs.github.com/changelog>; rel="deprecation"; type="text/html"`},
+	})
+	assert.Equal(t, time.Unix(1688169599, 0).UTC(), d.Deprecated)
+	assert.Equal(t, time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC), d.Sunset)
+	assert.Equal(t, "https://docs.github.com/changelog", d.Link)
+
+	d = ParseDeprecation(http.Header{"Deprecation": []string{"true"}})
+	assert.True(t, d.Deprecated.IsZero(), "legacy boolean deprecation should not have a date")
+}
+
+func TestLimits_Deprecations(t *testing.T) {
+	var events []Event
+	limits := &Limits{OnEvent: func(e Event) { events = append(events, e) }}
+	req, _ := http.NewRequest(http.MethodGet, "https://github.example.com/api/v3/repos/o/r/legacy", nil)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"X-Ratelimit-Resource":  []string{"core"},
+			"X-Ratelimit-Limit":     []string{"5000"},
+			"X-Ratelimit-Remaining": []string{"4999"},
+			"X-Ratelimit-Used":      []string{"1"},
+			"X-Ratelimit-Reset":     []string{"1745121612"},
+			"Sunset":                []string{"Wed, 11 Nov 2026 23:59:59 GMT"},
+		},
+		Request: req,
+	}
+	for range 3 {
+		assert.NoError(t, limits.Parse(resp))
+	}
+	assert.Equal(t, []Deprecation{{
+		Method:   http.MethodGet,
+		Path:     "/repos/o/r/legacy",
+		Route:    "/repos/{owner}/{repo}/legacy",
+		Resource: ResourceCore,
+		Sunset:   time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC),
+		Count:    3,
+	}}, limits.Deprecations())
+	if assert.Len(t, events, 1, "the event should only be emitted the first time") {
+		assert.Equal(t, EventDeprecated, events[0].Kind)
+		assert.Equal(t, "GET /repos/{owner}/{repo}/legacy is deprecated, sunset 2026-11-11", events[0].Message)
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	for path, route := range map[string]string{
+		"/repos/o/r/issues/42": "/repos/{owner}/{repo}/issues/{number}",
+		"/repos/o/r/commits/0123456789abcdef0123456789abcdef01234567": "/repos/{owner}/{repo}/commits/{sha}",
+		"/users/octocat/repos": "/users/{username}/repos",
+		"/orgs/acme/teams":     "/orgs/{org}/teams",
+		"/repositories/123":    "/repositories/{number}",
+		"/rate_limit":          "/rate_limit",
+	} {
+		assert.Equal(t, route, routeTemplate(path), path)
+	}
+}
+
+func TestLimits_Deprecations_Route(t *testing.T) {
+	var limits Limits
+	for _, path := range []string{"/repos/o/r/issues/1", "/repos/other/repo/issues/2"} {
+		limits.deprecated(Deprecation{Method: http.MethodGet, Path: path})
+	}
+	deprecations := limits.Deprecations()
+	if assert.Len(t, deprecations, 1, "deprecations should be tracked per route") {
+		assert.Equal(t, "/repos/{owner}/{repo}/issues/{number}", deprecations[0].Route)
+		assert.Equal(t, uint64(2), deprecations[0].Count)
+	}
+}
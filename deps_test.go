@@ -0,0 +1,69 @@
+package ghratelimit
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDependencies ensures the core module only imports the standard library (and itself), including its commands and
+// examples, as any other import would be required by the core go.mod. Integrations with third-party dependencies belong
+// in their own modules (ex: ghratelimitprom), which are skipped.
+func TestDependencies(t *testing.T) {
+	const module = "github.com/bored-engineer/github-rate-limit-http-transport"
+	err := filepath.WalkDir(".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file == "." {
+				return nil
+			}
+			if name := d.Name(); name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(file, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			first, _, _ := strings.Cut(path, "/")
+			if strings.Contains(first, ".") && path != module && !strings.HasPrefix(path, module+"/") {
+				t.Errorf("%s imports third-party package %q", file, path)
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+// TestDependencies_Minimal ensures the package built with the ghratelimit_minimal tag does not import packages
+// unavailable in constrained environments (ex: WASM or TinyGo).
+func TestDependencies_Minimal(t *testing.T) {
+	ctx := build.Default
+	ctx.BuildTags = append(ctx.BuildTags, "ghratelimit_minimal")
+	pkg, err := ctx.ImportDir(".", 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, path := range []string{"os", "os/signal", "runtime/pprof"} {
+		assert.NotContains(t, pkg.Imports, path)
+	}
+}
@@ -0,0 +1,46 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// simulate answers the request from the transport's Limits without sending it, see DryRun.
+// The request consumes cost requests of its resource type's rate limit, starting a new window once the reset has elapsed.
+// If there are not enough requests remaining, a 403 primary rate-limit response is returned instead.
+// Requests of resource types whose rate limit is unknown are answered without rate-limit headers.
+func (t *Transport) simulate(req *http.Request, resource Resource, cost uint64) *http.Response {
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Request:    req,
+	}
+	body := "{}"
+	if rate := t.Limits.Load(resource); rate != nil {
+		sim := *rate
+		if now := t.clock().Now(); !sim.ResetTime().After(now) {
+			sim.Used, sim.Remaining, sim.Reset = 0, sim.Limit, uint64(now.Add(windowLength(resource)).Unix())
+		}
+		if sim.Remaining < cost {
+			resp.Status, resp.StatusCode = "403 Forbidden", http.StatusForbidden
+			body = fmt.Sprintf(`{"message":"API rate limit exceeded for %s (dry run)."}`, resource)
+		} else {
+			sim.Used, sim.Remaining = sim.Used+cost, sim.Remaining-cost
+		}
+		resp.Header.Set("X-Ratelimit-Limit", strconv.FormatUint(sim.Limit, 10))
+		resp.Header.Set("X-Ratelimit-Used", strconv.FormatUint(sim.Used, 10))
+		resp.Header.Set("X-Ratelimit-Remaining", strconv.FormatUint(sim.Remaining, 10))
+		resp.Header.Set("X-Ratelimit-Reset", strconv.FormatUint(sim.Reset, 10))
+		resp.Header.Set("X-Ratelimit-Resource", resource.String())
+	}
+	resp.Body = io.NopCloser(strings.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp
+}
@@ -0,0 +1,49 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_DryRun(t *testing.T) {
+	transport := &Transport{DryRun: true, Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("DryRun should never send requests")
+		return nil, nil
+	})}
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 4998, Remaining: 2, Reset: reset})
+	transport.Limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Used: 30, Remaining: 0, Reset: uint64(time.Now().Add(-time.Second).Unix())})
+
+	send := func(rawURL string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		resp, err := transport.RoundTrip(req)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.NotEmpty(t, body)
+		return resp
+	}
+	for range 2 {
+		assert.Equal(t, http.StatusOK, send("https://api.github.com/repos/o/r").StatusCode)
+	}
+	assert.Equal(t, &Rate{Limit: 5000, Used: 5000, Remaining: 0, Reset: reset}, transport.Limits.Load(ResourceCore), "the simulated limits should be consumed")
+	assert.Equal(t, http.StatusForbidden, send("https://api.github.com/repos/o/r").StatusCode, "exhausted limits should be rate limited")
+
+	assert.Equal(t, http.StatusOK, send("https://api.github.com/search/issues?q=x").StatusCode)
+	if rate := transport.Limits.Load(ResourceSearch); assert.NotNil(t, rate) {
+		assert.Equal(t, uint64(29), rate.Remaining, "an elapsed reset should start a new simulated window")
+		assert.Greater(t, rate.Reset, uint64(time.Now().Unix()))
+	}
+
+	resp := send("https://api.github.com/graphql")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("X-Ratelimit-Remaining"), "unknown rate limits should not be simulated")
+
+	assert.Equal(t, uint64(3), transport.Accounting()[UsageKey{Resource: ResourceCore}].Requests)
+}
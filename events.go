@@ -0,0 +1,111 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EventKind identifies the type of an Event.
+type EventKind string
+
+const (
+	// EventClamped is emitted when a rate limit with absurd values was clamped to a sane range before being stored.
+	EventClamped EventKind = "clamped"
+	// EventStateError is emitted when the state of a Transport could not be restored from (or saved to) its StateFile.
+	EventStateError EventKind = "state_error"
+	// EventSharedError is emitted when the SharedStore of Limits could not be read or written.
+	EventSharedError EventKind = "shared_error"
+	// EventStrategyChanged is emitted when the Strategy of a Balancer is replaced by SetStrategy.
+	EventStrategyChanged EventKind = "strategy_changed"
+	// EventThreshold is emitted when the remaining fraction of a rate limit drops below one of the Limits' Thresholds.
+	EventThreshold EventKind = "threshold"
+	// EventExhausted is emitted when a rate limit has no requests remaining.
+	EventExhausted EventKind = "exhausted"
+	// EventReset is emitted when a new rate-limit window is observed.
+	EventReset EventKind = "reset"
+	// EventEmergencyOverride is emitted for every request that bypasses local policies, see ContextWithEmergencyOverride.
+	EventEmergencyOverride EventKind = "emergency_override"
+	// EventDeprecated is emitted the first time a response signals its endpoint is deprecated, see Deprecations.
+	EventDeprecated EventKind = "deprecated"
+	// EventDuplicateCredential is emitted when a transport added to a Balancer shares its credential with another.
+	EventDuplicateCredential EventKind = "duplicate_credential"
+	// EventCorruptEntry is emitted when an entry of Limits has an unexpected type, it is skipped instead of stopping iteration.
+	EventCorruptEntry EventKind = "corrupt_entry"
+	// EventFallback is emitted when a request is rewritten to a different resource type by the Fallback of a Balancer.
+	EventFallback EventKind = "fallback"
+	// EventRetry is emitted when a request is sent again, by the Retry or RetryAborted of a Transport
+	// or by a Balancer failing over, see ContextWithIdempotencyKey.
+	EventRetry EventKind = "retry"
+	// EventLatencyBudget is emitted when a request with a latency budget waits, fails fast or fails over, see ContextWithLatencyBudget.
+	EventLatencyBudget EventKind = "latency_budget"
+)
+
+// Event is a notable occurrence observed by Limits or a Balancer, delivered to the OnEvent hook.
+type Event struct {
+	// Kind is the type of event.
+	Kind EventKind `json:"kind"`
+	// Resource is the rate-limit resource the event applies to.
+	Resource Resource `json:"resource,omitempty"`
+	// Rate is the rate limit at the time of the event, if any.
+	Rate *Rate `json:"rate,omitempty"`
+	// Message is a human readable description of the event.
+	Message string `json:"message"`
+	// Threshold is the threshold that was crossed, for EventThreshold.
+	Threshold float64 `json:"threshold,omitempty"`
+	// Decision is the choice made for a request with a latency budget, for EventLatencyBudget.
+	Decision BudgetDecision `json:"decision,omitempty"`
+	// IdempotencyKey is the key set via ContextWithIdempotencyKey of the request the event applies to, if any.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, embedding the SchemaVersion.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		SchemaVersion int `json:"schema_version"`
+		event
+	}{SchemaVersion, event(e)})
+}
+
+// transitions emits the events for the transition of the resource type's rate limit from prev (if any) to rate.
+func (l *Limits) transitions(resource Resource, prev, rate *Rate) {
+	if prev != nil && (rate.Reset > prev.Reset || (rate.Reset == prev.Reset && rate.Remaining == rate.Limit && prev.Remaining < prev.Limit)) {
+		if l.OnReset != nil {
+			l.OnReset(resource, rate, prev.Used)
+		}
+		l.emit(Event{Kind: EventReset, Resource: resource, Rate: rate, Message: fmt.Sprintf("window reset, %d remaining, %d used in the previous window", rate.Remaining, prev.Used)})
+		prev = nil // thresholds are re-armed for the new window
+	}
+	if l.OnEvent == nil {
+		return
+	}
+	if rate.Limit > 0 {
+		before := 1.0
+		if prev != nil && prev.Limit > 0 {
+			before = float64(prev.Remaining) / float64(prev.Limit)
+		}
+		after := float64(rate.Remaining) / float64(rate.Limit)
+		for _, threshold := range l.Thresholds {
+			if after < threshold && before >= threshold {
+				l.emit(Event{Kind: EventThreshold, Resource: resource, Rate: rate, Threshold: threshold, Message: fmt.Sprintf("remaining %.1f%% < %.1f%%", after*100, threshold*100)})
+			}
+		}
+	}
+	if rate.Remaining == 0 && (prev == nil || prev.Remaining > 0) {
+		l.emit(Event{Kind: EventExhausted, Resource: resource, Rate: rate, Message: "no requests remaining until " + rate.ResetTime().UTC().Format("15:04:05")})
+	}
+}
+
+// emit delivers the event to the OnEvent hook, if set.
+func (l *Limits) emit(event Event) {
+	if l.OnEvent != nil {
+		l.OnEvent(event)
+	}
+}
+
+// emit delivers the event to the OnEvent hook, if set.
+func (bt *Balancer) emit(event Event) {
+	if bt.OnEvent != nil {
+		bt.OnEvent(event)
+	}
+}
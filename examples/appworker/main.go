@@ -0,0 +1,161 @@
+// Command appworker fetches the star count of repositories as a GitHub App, spreading the requests across the App's
+// installations (each of which has its own rate limit) with an AppPool that keeps their tokens fresh.
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// newAppPool returns an AppPool adding a Transport per installation of the App to a Balancer,
+// each waiting for its rate limit to reset once exhausted.
+func newAppPool(appID string, key *rsa.PrivateKey, api *url.URL, base http.RoundTripper) *ghratelimit.AppPool {
+	return &ghratelimit.AppPool{
+		AppID:      appID,
+		PrivateKey: key,
+		BaseURL:    api,
+		Base:       base,
+		Pool:       &ghratelimit.Balancer{},
+		Configure: func(_ int64, transport *ghratelimit.Transport) {
+			transport.BaseURL = api
+			transport.WaitOnExhaustion = true
+			transport.Optimistic = true
+		},
+	}
+}
+
+// worker processes jobs using the REST API at api.
+type worker struct {
+	client      *http.Client
+	api         *url.URL
+	concurrency int
+}
+
+// stars returns the star count of a repository, ex: "octocat/hello-world".
+func (w *worker) stars(ctx context.Context, repo string) (int, error) {
+	u := w.api.JoinPath("repos", repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: unexpected status %s", u, resp.Status)
+	}
+	var body struct {
+		Stars int `json:"stargazers_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("GET %s: (*json.Decoder).Decode failed: %w", u, err)
+	}
+	return body.Stars, nil
+}
+
+// run fetches the star count of every repository, returning the joined errors of any that failed.
+func (w *worker) run(ctx context.Context, repos []string) (map[string]int, error) {
+	var mu sync.Mutex
+	var errs []error
+	stars := make(map[string]int, len(repos))
+	sem := make(chan struct{}, max(w.concurrency, 1))
+	var wg sync.WaitGroup
+	for _, repo := range repos {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			n, err := w.stars(ctx, repo)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", repo, err))
+				return
+			}
+			stars[repo] = n
+		}()
+	}
+	wg.Wait()
+	return stars, errors.Join(errs...)
+}
+
+// readKey parses the PEM encoded private key of a GitHub App.
+func readKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile for %q failed: %w", path, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %q", path)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParsePKCS1PrivateKey for %q failed: %w", path, err)
+	}
+	return key, nil
+}
+
+func main() {
+	appID := flag.String("app-id", os.Getenv("GITHUB_APP_ID"), "GitHub App ID (default $GITHUB_APP_ID)")
+	keyPath := flag.String("key", os.Getenv("GITHUB_APP_PRIVATE_KEY"), "path of the GitHub App's PEM private key (default $GITHUB_APP_PRIVATE_KEY)")
+	api := flag.String("url", "https://api.github.com", "base URL of the GitHub API, ex: a GitHub Enterprise Server instance")
+	concurrency := flag.Int("concurrency", 10, "maximum number of repositories fetched concurrently")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] owner/repo...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *appID == "" || *keyPath == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	base, err := url.Parse(*api)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "url.Parse for %q failed: %v\n", *api, err)
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ap := newAppPool(*appID, key, base, http.DefaultTransport)
+	if err := ap.Sync(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "(*ghratelimit.AppPool).Sync failed: %v\n", err)
+		os.Exit(1)
+	}
+	go ap.Run(ctx, time.Minute)
+
+	w := &worker{client: &http.Client{Transport: ap.Pool}, api: base, concurrency: *concurrency}
+	stars, err := w.run(ctx, flag.Args())
+	for _, repo := range slices.Sorted(maps.Keys(stars)) {
+		fmt.Printf("%s: %d\n", repo, stars[repo])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
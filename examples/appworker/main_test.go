@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/bored-engineer/github-rate-limit-http-transport/ghratelimittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeApp serves the App endpoints itself and every other request from the fake server of the installation's token.
+type fakeApp struct {
+	installations map[string]*ghratelimittest.Server
+}
+
+// RoundTrip implements http.RoundTripper
+func (fa *fakeApp) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(req.URL.Path, "/app/") {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		server, ok := fa.installations[token]
+		if !ok {
+			return nil, fmt.Errorf("unknown token %q", token)
+		}
+		return server.RoundTrip(req)
+	}
+	rec := httptest.NewRecorder()
+	if req.URL.Path == "/app/installations" {
+		var body []map[string]int64
+		for id := range int64(len(fa.installations)) {
+			body = append(body, map[string]int64{"id": id + 1})
+		}
+		_ = json.NewEncoder(rec).Encode(body)
+	} else {
+		var id int64
+		_, _ = fmt.Sscanf(req.URL.Path, "/app/installations/%d/access_tokens", &id)
+		rec.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(rec).Encode(map[string]any{"token": fmt.Sprintf("token-%d", id), "expires_at": time.Now().Add(time.Hour)})
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func TestWorker(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]int{"stargazers_count": len(r.URL.Path)})
+	})
+	// each installation alone cannot complete the jobs (6 requests)
+	limits := map[ghratelimit.Resource]uint64{ghratelimit.ResourceCore: 3}
+	fa := &fakeApp{installations: map[string]*ghratelimittest.Server{
+		"token-1": {Limits: limits, Handler: handler},
+		"token-2": {Limits: limits, Handler: handler},
+	}}
+	api, _ := url.Parse("https://api.github.com")
+	ap := newAppPool("12345", key, api, fa)
+	require.NoError(t, ap.Sync(t.Context()))
+	assert.Equal(t, 2, ap.Pool.Len(), "a transport should be added per installation")
+
+	w := &worker{client: &http.Client{Transport: ap.Pool}, api: api, concurrency: 2}
+	var repos []string
+	for idx := range 6 {
+		repos = append(repos, fmt.Sprintf("acme/repo-%d", idx))
+	}
+	stars, err := w.run(t.Context(), repos)
+	require.NoError(t, err)
+	assert.Len(t, stars, 6)
+	assert.Equal(t, len("/repos/acme/repo-0"), stars["acme/repo-0"])
+	for token, server := range fa.installations {
+		assert.Equal(t, uint64(3), server.Rate(ghratelimit.ResourceCore).Used, token)
+	}
+}
@@ -0,0 +1,171 @@
+// Command crawler lists the languages of every repository in a GitHub organization, balancing the requests across
+// several tokens so the crawl is limited by their combined rate limit rather than a single token's.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// tokenTransport adds the GitHub token to every request.
+type tokenTransport struct {
+	token string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newPool returns a Balancer with a Transport per base, each waiting for its rate limit to reset once exhausted
+// and decrementing it as requests are sent, so concurrent requests do not over-commit a nearly exhausted token.
+func newPool(bases []http.RoundTripper, api *url.URL) *ghratelimit.Balancer {
+	pool := &ghratelimit.Balancer{}
+	for idx, base := range bases {
+		pool.Add(&ghratelimit.Transport{
+			Name:             "token/" + strconv.Itoa(idx),
+			Base:             base,
+			BaseURL:          api,
+			WaitOnExhaustion: true,
+			Optimistic:       true,
+			MaxConcurrency:   10,
+		})
+	}
+	return pool
+}
+
+// crawler fetches the repositories of an organization from the REST API at api.
+type crawler struct {
+	client      *http.Client
+	api         *url.URL
+	concurrency int
+}
+
+// get decodes the JSON response of a GET request to the path (relative to the api) into v.
+func (c *crawler) get(ctx context.Context, path string, query url.Values, v any) error {
+	u := c.api.JoinPath(path)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: (*json.Decoder).Decode failed: %w", u, err)
+	}
+	return nil
+}
+
+// repos lists the names of every repository in the organization.
+func (c *crawler) repos(ctx context.Context, org string) ([]string, error) {
+	var names []string
+	for page := 1; ; page++ {
+		var repos []struct {
+			Name string `json:"name"`
+		}
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		if err := c.get(ctx, "orgs/"+org+"/repos", query, &repos); err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			names = append(names, repo.Name)
+		}
+		if len(repos) < 100 {
+			return names, nil
+		}
+	}
+}
+
+// crawl returns the languages of every repository in the organization.
+func (c *crawler) crawl(ctx context.Context, org string) (map[string][]string, error) {
+	names, err := c.repos(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	var firstErr error
+	languages := make(map[string][]string, len(names))
+	sem := make(chan struct{}, max(c.concurrency, 1))
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			var bytes map[string]int
+			err := c.get(ctx, "repos/"+org+"/"+name+"/languages", nil, &bytes)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			languages[name] = slices.Sorted(maps.Keys(bytes))
+		}()
+	}
+	wg.Wait()
+	return languages, firstErr
+}
+
+func main() {
+	org := flag.String("org", "", "GitHub organization to crawl")
+	tokens := flag.String("tokens", os.Getenv("GITHUB_TOKENS"), "comma-separated GitHub tokens to balance across (default $GITHUB_TOKENS)")
+	api := flag.String("url", "https://api.github.com", "base URL of the GitHub API, ex: a GitHub Enterprise Server instance")
+	concurrency := flag.Int("concurrency", 10, "maximum number of repositories fetched concurrently")
+	flag.Parse()
+	if *org == "" || *tokens == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	base, err := url.Parse(*api)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "url.Parse for %q failed: %v\n", *api, err)
+		os.Exit(2)
+	}
+
+	var bases []http.RoundTripper
+	for _, token := range strings.Split(*tokens, ",") {
+		bases = append(bases, &tokenTransport{token: strings.TrimSpace(token)})
+	}
+	c := &crawler{
+		client:      &http.Client{Transport: newPool(bases, base)},
+		api:         base,
+		concurrency: *concurrency,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	languages, err := c.crawl(ctx, *org)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "crawl of %s failed: %v\n", *org, err)
+		os.Exit(1)
+	}
+	for _, name := range slices.Sorted(maps.Keys(languages)) {
+		fmt.Printf("%s/%s: %s\n", *org, name, strings.Join(languages[name], ", "))
+	}
+}
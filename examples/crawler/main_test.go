@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/bored-engineer/github-rate-limit-http-transport/ghratelimittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawl(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		repos := []map[string]string{}
+		if page, _ := strconv.Atoi(r.URL.Query().Get("page")); page == 1 {
+			for idx := range 5 {
+				repos = append(repos, map[string]string{"name": fmt.Sprintf("repo-%d", idx)})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(repos)
+	})
+	mux.HandleFunc("GET /repos/acme/{repo}/languages", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]int{"Go": 100, "Shell": 10})
+	})
+
+	// each token alone cannot complete the crawl (6 requests)
+	limits := map[ghratelimit.Resource]uint64{ghratelimit.ResourceCore: 3}
+	a := &ghratelimittest.Server{Limits: limits, Handler: mux}
+	b := &ghratelimittest.Server{Limits: limits, Handler: mux}
+	api, _ := url.Parse("https://api.github.com")
+	c := &crawler{
+		client:      &http.Client{Transport: newPool([]http.RoundTripper{a, b}, api)},
+		api:         api,
+		concurrency: 2,
+	}
+
+	languages, err := c.crawl(t.Context(), "acme")
+	require.NoError(t, err)
+	assert.Len(t, languages, 5)
+	assert.Equal(t, []string{"Go", "Shell"}, languages["repo-3"])
+	assert.Equal(t, uint64(6), a.Rate(ghratelimit.ResourceCore).Used+b.Rate(ghratelimit.ResourceCore).Used)
+	assert.NotZero(t, a.Rate(ghratelimit.ResourceCore).Used, "both tokens should be used")
+	assert.NotZero(t, b.Rate(ghratelimit.ResourceCore).Used, "both tokens should be used")
+}
@@ -0,0 +1,137 @@
+// Command scanner runs GitHub code searches (ex: for leaked credentials) and prints every match, paginating each search
+// at the pace of the small code_search rate limit rather than tripping it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// tokenTransport adds the GitHub token to every request.
+type tokenTransport struct {
+	token string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// maxPages is the number of pages GitHub returns for a search, at most 1000 results.
+const maxPages = 10
+
+// match is a file matching a code search.
+type match struct {
+	Repository string
+	Path       string
+}
+
+// scanner runs code searches using the REST API at api.
+type scanner struct {
+	transport *ghratelimit.Transport
+	api       *url.URL
+	perPage   int
+}
+
+// page fetches a page of the code search results, reporting if there are more.
+func (s *scanner) page(ctx context.Context, query string, page int) ([]match, bool, error) {
+	u := s.api.JoinPath("search/code")
+	u.RawQuery = url.Values{"q": {query}, "per_page": {strconv.Itoa(s.perPage)}, "page": {strconv.Itoa(page)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := s.transport.RoundTrip(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("GET %s: unexpected status %s", u, resp.Status)
+	}
+	var body struct {
+		Items []struct {
+			Path       string `json:"path"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("GET %s: (*json.Decoder).Decode failed: %w", u, err)
+	}
+	matches := make([]match, 0, len(body.Items))
+	for _, item := range body.Items {
+		matches = append(matches, match{Repository: item.Repository.FullName, Path: item.Path})
+	}
+	return matches, len(body.Items) == s.perPage && page < maxPages, nil
+}
+
+// scan runs each code search in turn, returning every match.
+func (s *scanner) scan(ctx context.Context, queries []string) ([]match, error) {
+	var matches []match
+	for _, query := range queries {
+		page := 0
+		err := s.transport.Limits.PaceAll(ctx, ghratelimit.ResourceCodeSearch, func(ctx context.Context) (bool, error) {
+			page++
+			found, more, err := s.page(ctx, query, page)
+			matches = append(matches, found...)
+			return more, err
+		})
+		if err != nil {
+			return matches, fmt.Errorf("search for %q: %w", query, err)
+		}
+	}
+	return matches, nil
+}
+
+func main() {
+	token := flag.String("token", os.Getenv("GITHUB_TOKEN"), "GitHub token (default $GITHUB_TOKEN)")
+	api := flag.String("url", "https://api.github.com", "base URL of the GitHub API, ex: a GitHub Enterprise Server instance")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] query...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *token == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	base, err := url.Parse(*api)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "url.Parse for %q failed: %v\n", *api, err)
+		os.Exit(2)
+	}
+
+	s := &scanner{
+		transport: &ghratelimit.Transport{
+			Base:             &tokenTransport{token: *token},
+			BaseURL:          base,
+			WaitOnExhaustion: true,
+		},
+		api:     base,
+		perPage: 100,
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	matches, err := s.scan(ctx, flag.Args())
+	for _, m := range matches {
+		fmt.Printf("%s: %s\n", m.Repository, m.Path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/bored-engineer/github-rate-limit-http-transport/ghratelimittest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	var clock ghratelimittest.Clock
+	server := &ghratelimittest.Server{
+		Now:    clock.Now,
+		Limits: map[ghratelimit.Resource]uint64{ghratelimit.ResourceCodeSearch: 2},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 2 full pages and a partial one
+			n := 2
+			if page, _ := strconv.Atoi(r.URL.Query().Get("page")); page == 3 {
+				n = 1
+			}
+			items := make([]map[string]any, n)
+			for idx := range items {
+				items[idx] = map[string]any{"path": r.URL.Query().Get("q"), "repository": map[string]string{"full_name": "acme/repo"}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+		}),
+	}
+	api, _ := url.Parse("https://api.github.com")
+	transport := &ghratelimit.Transport{Base: server, BaseURL: api, WaitOnExhaustion: true}
+	transport.Limits.Clock = &clock
+	s := &scanner{transport: transport, api: api, perPage: 2}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// 6 requests at 2 per window need the clock to move
+		matches, err := s.scan(t.Context(), []string{"password", "secret"})
+		assert.NoError(t, err)
+		if assert.Len(t, matches, 10) {
+			assert.Equal(t, match{Repository: "acme/repo", Path: "secret"}, matches[9])
+		}
+	}()
+	start := clock.Now()
+	for {
+		select {
+		case <-done:
+			assert.GreaterOrEqual(t, clock.Now().Sub(start), 2*time.Minute, "the searches should wait for the window to reset")
+			return
+		case <-time.After(time.Millisecond):
+			if clock.Waiters() > 0 {
+				clock.Advance(10 * time.Second)
+			}
+		}
+	}
+}
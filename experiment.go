@@ -0,0 +1,73 @@
+package ghratelimit
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+// Experiment is a Strategy that routes a fraction of selections through an experimental Treatment strategy
+// and the rest through the Control strategy, keeping separate stats for each arm to evaluate strategies on live traffic.
+// It must not be copied after first use.
+type Experiment struct {
+	// Control is the strategy used for most selections.
+	// If nil, HighestRemaining is used.
+	Control Strategy
+	// Treatment is the experimental strategy.
+	// If nil, every selection uses Control.
+	Treatment Strategy
+	// Fraction is the fraction (0.0 to 1.0) of selections routed through Treatment.
+	Fraction float64
+
+	mu                 sync.Mutex
+	control, treatment ArmStats
+}
+
+// ArmStats are the selection stats of one arm of an Experiment.
+type ArmStats struct {
+	// Selections is the number of selections routed through the arm.
+	Selections uint64
+	// Abstentions is the number of those selections where the strategy did not select a transport.
+	Abstentions uint64
+	// Exhausted is the number of selections of a transport known to have no requests remaining.
+	Exhausted uint64
+	// Headroom is the mean fraction (0.0 to 1.0) of its rate limit the selected transport had remaining.
+	Headroom float64
+}
+
+// Select implements Strategy
+func (e *Experiment) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	strategy, stats := e.Control, &e.control
+	if e.Treatment != nil && rand.Float64() < e.Fraction {
+		strategy, stats = e.Treatment, &e.treatment
+	}
+	if strategy == nil {
+		strategy = HighestRemaining{}
+	}
+	selected := strategy.Select(req, resource, candidates)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats.Selections++
+	if selected == nil {
+		stats.Abstentions++
+		return nil
+	}
+	var headroom float64
+	if rate := selected.Limits.Load(resource); rate != nil && rate.Limit > 0 {
+		headroom = float64(rate.Remaining) / float64(rate.Limit)
+		if rate.Remaining == 0 {
+			stats.Exhausted++
+		}
+	}
+	selections := float64(stats.Selections - stats.Abstentions)
+	stats.Headroom += (headroom - stats.Headroom) / selections
+	return selected
+}
+
+// Stats returns a snapshot of the stats of the control and treatment arms.
+func (e *Experiment) Stats() (control, treatment ArmStats) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.control, e.treatment
+}
@@ -0,0 +1,25 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperiment(t *testing.T) {
+	a, b := withRemaining(1000), withRemaining(4000)
+	e := &Experiment{Treatment: FirstAboveThreshold{Threshold: 500}, Fraction: 0.25}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	for range 1000 {
+		if e.Select(req, ResourceCore, []*Transport{a, b}) == nil {
+			t.Fatal("experiment should always select a transport")
+		}
+	}
+
+	control, treatment := e.Stats()
+	assert.Equal(t, uint64(1000), control.Selections+treatment.Selections)
+	assert.InDelta(t, 250, treatment.Selections, 75, "treatment should receive its fraction of selections")
+	assert.InDelta(t, 0.8, control.Headroom, 0.001, "control (HighestRemaining) should select b")
+	assert.InDelta(t, 0.2, treatment.Headroom, 0.001, "treatment (FirstAboveThreshold) should select a")
+}
@@ -0,0 +1,42 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// exhausted reports if every transport that could be selected is known to have fewer requests remaining
+// for the resource type than the request is estimated to cost.
+func (bt *Balancer) exhausted(req *http.Request, resource Resource) bool {
+	cost := estimateCost(bt.CostEstimator, req)
+	var known bool
+	for _, transport := range bt.transports() {
+		if transport.Paused() {
+			continue
+		}
+		transport.observeMember(resource)
+		rate := transport.Limits.Load(resource)
+		if rate == nil || rate.Remaining >= cost {
+			return false
+		}
+		known = true
+	}
+	return known
+}
+
+// fallback rewrites the request using the Fallback hook if every transport is exhausted for the resource type.
+// If there is no Fallback, or it declines by returning a nil request, the original request and resource type are returned.
+func (bt *Balancer) fallback(req *http.Request, resource Resource) (*http.Request, Resource, error) {
+	if bt.Fallback == nil || !bt.exhausted(req, resource) {
+		return req, resource, nil
+	}
+	next, to, err := bt.Fallback(req)
+	if err != nil {
+		return nil, resource, fmt.Errorf("fallback from %s failed: %w", resource, err)
+	}
+	if next == nil || to == "" || to == resource {
+		return req, resource, nil
+	}
+	bt.emit(Event{Kind: EventFallback, Resource: to, IdempotencyKey: IdempotencyKeyFromContext(req.Context()), Message: fmt.Sprintf("%s exhausted on every transport, falling back to %s for %s %s", resource, to, req.Method, req.URL.Path)})
+	return next.WithContext(ContextWithResource(next.Context(), to)), to, nil
+}
@@ -0,0 +1,83 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultFetchConcurrency is the number of transports refreshed at once by (*Balancer).Fetch if FetchConcurrency is not set.
+const DefaultFetchConcurrency = 8
+
+// Fetch refreshes the rate limits of every transport in the pool concurrently (see (*Limits).Fetch),
+// at most FetchConcurrency at a time. If u is nil, the /rate_limit endpoint of each transport's BaseURL
+// (or the pool's BaseURL) is used. The errors of any transports that failed are joined.
+func (bt *Balancer) Fetch(ctx context.Context, u *url.URL) error {
+	concurrency := bt.FetchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultFetchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, transport := range bt.transports() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("transport %s: %w", bt.identify(transport), context.Cause(ctx)))
+				mu.Unlock()
+				return
+			}
+			defer func() { <-sem }()
+			fu := u
+			if fu == nil {
+				if transport.BaseURL != nil {
+					fu = RateLimitURL(transport.BaseURL)
+				} else {
+					fu = RateLimitURL(bt.BaseURL)
+				}
+			}
+			if err := transport.Limits.Fetch(ctx, transport, fu); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("transport %s: %w", bt.identify(transport), err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// TotalRemaining returns the number of requests remaining for the resource type summed across the pool,
+// transports whose rate limit is unknown are not counted.
+func (bt *Balancer) TotalRemaining(resource Resource) (total uint64) {
+	for _, transport := range bt.transports() {
+		if rate := transport.Limits.Load(resource); rate != nil {
+			total += rate.Remaining
+		}
+	}
+	return total
+}
+
+// SoonestReset returns the earliest time a rate-limit window for the resource type resets across the pool,
+// or the zero value if the rate limit is unknown for every transport.
+func (bt *Balancer) SoonestReset(resource Resource) (soonest time.Time) {
+	for _, transport := range bt.transports() {
+		rate := transport.Limits.Load(resource)
+		if rate == nil {
+			continue
+		}
+		if reset := rate.ResetTime(); soonest.IsZero() || reset.Before(soonest) {
+			soonest = reset
+		}
+	}
+	return soonest
+}
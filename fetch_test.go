@@ -0,0 +1,46 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_Fetch(t *testing.T) {
+	var inflight, peak atomic.Int64
+	limited := func(remaining, reset uint64) *Transport {
+		return &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if n := inflight.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			defer inflight.Add(-1)
+			time.Sleep(10 * time.Millisecond)
+			body := fmt.Sprintf(`{"resources":{"core":{"limit":5000,"used":%d,"remaining":%d,"reset":%d}}}`, 5000-remaining, remaining, reset)
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+		})}
+	}
+	errFetch := errors.New("fetch failed")
+	broken := failing(errFetch)
+	broken.Name = "broken"
+	bt := &Balancer{
+		Transports:       []*Transport{limited(100, 1700000300), limited(200, 1700000100), limited(300, 1700000200), broken},
+		FetchConcurrency: 2,
+	}
+	assert.Zero(t, bt.TotalRemaining(ResourceCore))
+	assert.Zero(t, bt.SoonestReset(ResourceCore), "unknown rate limits should have no reset")
+
+	err := bt.Fetch(context.Background(), nil)
+	assert.ErrorIs(t, err, errFetch)
+	assert.ErrorContains(t, err, "transport broken:")
+	assert.LessOrEqual(t, peak.Load(), int64(2), "FetchConcurrency should bound the parallelism")
+	assert.Equal(t, uint64(600), bt.TotalRemaining(ResourceCore))
+	assert.Equal(t, time.Unix(1700000100, 0), bt.SoonestReset(ResourceCore))
+}
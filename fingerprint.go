@@ -0,0 +1,76 @@
+package ghratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Fingerprinter is implemented by authenticating RoundTrippers (ex: the Base of a Transport)
+// that can identify their credential without revealing it, see FingerprintToken.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// FingerprintToken returns a non-reversible fingerprint of a token (a truncated SHA-256), safe to log and compare.
+func FingerprintToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Fingerprint returns the fingerprint of the transport's credential if its Base is a Fingerprinter, otherwise "".
+func (t *Transport) Fingerprint() string {
+	if f, ok := t.Base.(Fingerprinter); ok {
+		return f.Fingerprint()
+	}
+	return ""
+}
+
+// fingerprint returns the fingerprint of the transport's credential, see Fingerprint.
+func (bt *Balancer) fingerprint(transport *Transport) string {
+	if bt.Fingerprint != nil {
+		return bt.Fingerprint(transport)
+	}
+	return transport.Fingerprint()
+}
+
+// duplicateOf returns a transport in the pool (other than transport) with the same credential, if any.
+func (bt *Balancer) duplicateOf(transport *Transport) *Transport {
+	fingerprint := bt.fingerprint(transport)
+	if fingerprint == "" {
+		return nil
+	}
+	for _, other := range bt.transports() {
+		if other != transport && bt.fingerprint(other) == fingerprint {
+			return other
+		}
+	}
+	return nil
+}
+
+// Duplicates returns the transports sharing a credential, keyed by its fingerprint.
+// A credential added to the pool twice silently skews balancing and double-counts its budget.
+func (bt *Balancer) Duplicates() map[string][]*Transport {
+	byFingerprint := make(map[string][]*Transport)
+	for _, transport := range bt.transports() {
+		if fingerprint := bt.fingerprint(transport); fingerprint != "" {
+			byFingerprint[fingerprint] = append(byFingerprint[fingerprint], transport)
+		}
+	}
+	for fingerprint, transports := range byFingerprint {
+		if len(transports) < 2 {
+			delete(byFingerprint, fingerprint)
+		}
+	}
+	return byFingerprint
+}
+
+// checkDuplicate emits an EventDuplicateCredential if the transport shares its credential with another in the pool.
+func (bt *Balancer) checkDuplicate(transport *Transport) {
+	if other := bt.duplicateOf(transport); other != nil {
+		bt.emit(Event{
+			Kind:    EventDuplicateCredential,
+			Message: fmt.Sprintf("transport %s has the same credential (%s) as transport %s", bt.identify(transport), bt.fingerprint(transport), bt.identify(other)),
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenBase is an authenticating RoundTripper for tests.
+type tokenBase struct {
+	http.RoundTripper
+	token string
+}
+
+// Fingerprint implements Fingerprinter
+func (tb *tokenBase) Fingerprint() string {
+	return FingerprintToken(tb.token)
+}
+
+func TestFingerprintToken(t *testing.T) {
+	fingerprint := FingerprintToken("ghp_secret")
+	assert.Equal(t, fingerprint, FingerprintToken("ghp_secret"))
+	assert.NotEqual(t, fingerprint, FingerprintToken("ghp_other"))
+	assert.NotContains(t, fingerprint, "secret")
+	assert.True(t, strings.HasPrefix(fingerprint, "sha256:"))
+}
+
+func TestBalancer_Duplicates(t *testing.T) {
+	var events []Event
+	a := &Transport{Name: "a", Base: &tokenBase{RoundTripper: okResponse(), token: "one"}}
+	b := &Transport{Name: "b", Base: &tokenBase{RoundTripper: okResponse(), token: "two"}}
+	bt := &Balancer{Transports: []*Transport{a, b, {Name: "anonymous"}}, OnEvent: func(e Event) { events = append(events, e) }}
+	assert.Empty(t, bt.Duplicates())
+
+	c := &Transport{Name: "c", Base: &tokenBase{RoundTripper: okResponse(), token: "one"}}
+	bt.Add(c)
+	assert.Equal(t, map[string][]*Transport{FingerprintToken("one"): {a, c}}, bt.Duplicates())
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventDuplicateCredential, events[0].Kind)
+		assert.Contains(t, events[0].Message, `transport c has the same credential`)
+	}
+
+	bt.Fingerprint = func(transport *Transport) string { return "same" }
+	assert.Len(t, bt.Duplicates()["same"], 4, "Fingerprint should override the Base")
+
+	h := &AdminHandler{
+		Pool:      &Balancer{Transports: []*Transport{a}},
+		Authorize: func(*http.Request) error { return nil },
+		NewTransport: func(*http.Request) (*Transport, error) {
+			return &Transport{Base: &tokenBase{RoundTripper: okResponse(), token: "one"}}, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusConflict, rec.Code, "duplicate credentials should not be added")
+	assert.Equal(t, 1, h.Pool.Len())
+}
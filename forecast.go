@@ -0,0 +1,106 @@
+package ghratelimit
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Never is returned by EstimateWait when the requests can never be made, ex: because the limit is zero.
+const Never = time.Duration(math.MaxInt64)
+
+// windowLength returns the length of GitHub's rate-limit window for the resource type,
+// the search resources reset every minute while the rest reset every hour.
+func windowLength(resource Resource) time.Duration {
+	switch resource {
+	case ResourceSearch, ResourceCodeSearch:
+		return time.Minute
+	default:
+		return time.Hour
+	}
+}
+
+// forecast is the capacity of a rate limit over time: avail requests now, then limit requests at first and every window after.
+type forecast struct {
+	avail, limit uint64
+	first        time.Duration
+}
+
+// forecastRate returns the forecast of the rate limit, if the window has already reset the full limit is available now.
+func forecastRate(rate *Rate, window time.Duration, now time.Time) forecast {
+	if until := rate.ResetTime().Sub(now); until > 0 {
+		return forecast{avail: rate.Remaining, limit: rate.Limit, first: until}
+	}
+	return forecast{avail: rate.Limit, limit: rate.Limit, first: window}
+}
+
+// available returns the number of requests that can be made within d.
+func (f forecast) available(d, window time.Duration) uint64 {
+	if d < f.first {
+		return f.avail
+	}
+	return f.avail + f.limit*uint64(1+(d-f.first)/window)
+}
+
+// estimateWait returns how long until n requests can be made across the forecasts, see EstimateWait.
+func estimateWait(forecasts []forecast, window time.Duration, n uint64) time.Duration {
+	var avail, limit uint64
+	var last time.Duration
+	for _, f := range forecasts {
+		avail += f.avail
+		limit += f.limit
+		last = max(last, f.first)
+	}
+	if avail >= n {
+		return 0
+	}
+	if limit == 0 {
+		return Never
+	}
+	windows := (n - avail + limit - 1) / limit
+	if windows > uint64(Never-last)/uint64(window) {
+		return Never
+	}
+	hi := last + time.Duration(windows)*window
+	return time.Duration(sort.Search(int(hi)+1, func(d int) bool {
+		var total uint64
+		for _, f := range forecasts {
+			total += f.available(time.Duration(d), window)
+		}
+		return total >= n
+	}))
+}
+
+// EstimateWait returns how long until n requests of the resource type can be made, given the remaining requests
+// and the reset of the current window (subsequent windows are assumed to have the same limit), ex: to decide
+// whether to start a large batch job now or after the next reset. It returns 0 if the rate limit is unknown,
+// or Never if the requests can never be made.
+func (l *Limits) EstimateWait(resource Resource, n uint64) time.Duration {
+	return l.estimateWaitAt(resource, n, l.clock().Now())
+}
+
+// estimateWaitAt returns how long after now until n requests of the resource type can be made, see EstimateWait.
+func (l *Limits) estimateWaitAt(resource Resource, n uint64, now time.Time) time.Duration {
+	rate := l.Load(resource)
+	if rate == nil {
+		return 0
+	}
+	window := windowLength(resource)
+	return estimateWait([]forecast{forecastRate(rate, window, now)}, window, n)
+}
+
+// EstimateWait returns how long until n requests of the resource type can be made across the pool, see (*Limits).EstimateWait.
+// It returns 0 if the rate limit of any transport is unknown.
+func (bt *Balancer) EstimateWait(resource Resource, n uint64) time.Duration {
+	window := windowLength(resource)
+	now := time.Now()
+	var forecasts []forecast
+	for _, transport := range bt.transports() {
+		rate := transport.Limits.Load(resource)
+		if rate == nil {
+			return 0
+		}
+		forecasts = append(forecasts, forecastRate(rate, window, now))
+	}
+	return estimateWait(forecasts, window, n)
+}
@@ -0,0 +1,38 @@
+package ghratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_estimateWait(t *testing.T) {
+	a := forecast{avail: 100, limit: 5000, first: 10 * time.Minute}
+	b := forecast{avail: 0, limit: 1000, first: 5 * time.Minute}
+	assert.Zero(t, estimateWait([]forecast{a}, time.Hour, 100))
+	assert.Equal(t, 10*time.Minute, estimateWait([]forecast{a}, time.Hour, 101), "should wait for the reset")
+	assert.Equal(t, 10*time.Minute+2*time.Hour, estimateWait([]forecast{a}, time.Hour, 12000), "should span multiple windows")
+	assert.Equal(t, 5*time.Minute, estimateWait([]forecast{a, b}, time.Hour, 1100), "the pool should combine resets")
+	assert.Equal(t, 10*time.Minute, estimateWait([]forecast{a, b}, time.Hour, 1101))
+	assert.Equal(t, Never, estimateWait([]forecast{{}}, time.Hour, 1), "a zero limit should never be satisfied")
+}
+
+func TestLimits_EstimateWait(t *testing.T) {
+	var limits Limits
+	assert.Zero(t, limits.EstimateWait(ResourceCore, 5000), "unknown rate limits should not wait")
+
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 4000, Remaining: 1000, Reset: uint64(reset.Unix())})
+	assert.Zero(t, limits.EstimateWait(ResourceCore, 1000))
+	assert.InDelta(t, time.Until(reset), limits.EstimateWait(ResourceCore, 5000), float64(time.Second))
+	assert.InDelta(t, time.Until(reset)+time.Hour, limits.EstimateWait(ResourceCore, 7000), float64(time.Second))
+
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Used: 30, Remaining: 0, Reset: uint64(time.Now().Add(-time.Second).Unix())})
+	assert.Zero(t, limits.EstimateWait(ResourceSearch, 30), "an elapsed reset should make the full limit available")
+	assert.InDelta(t, time.Minute, limits.EstimateWait(ResourceSearch, 60), float64(time.Second), "search windows are a minute")
+
+	bt := &Balancer{Transports: []*Transport{withRemaining(100), withRemaining(200)}}
+	assert.Zero(t, bt.EstimateWait(ResourceCore, 300))
+	assert.Zero(t, bt.EstimateWait(ResourceSearch, 1), "unknown rate limits should not wait")
+}
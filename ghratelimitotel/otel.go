@@ -0,0 +1,123 @@
+// Package ghratelimitotel provides OpenTelemetry instrumentation for ghratelimit.
+// It is a separate module so that the core package does not depend on OpenTelemetry.
+package ghratelimitotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer and meter.
+const ScopeName = "github.com/bored-engineer/github-rate-limit-http-transport/ghratelimitotel"
+
+const (
+	// TransportKey is the attribute holding the identity of the transport that executed the request.
+	TransportKey = attribute.Key("ghratelimit.transport")
+	// ResourceKey is the attribute holding the rate-limit resource.
+	ResourceKey = attribute.Key("ghratelimit.resource")
+	// RemainingBeforeKey is the attribute holding the remaining rate limit before the request was sent.
+	RemainingBeforeKey = attribute.Key("ghratelimit.remaining.before")
+	// RemainingAfterKey is the attribute holding the remaining rate limit reported by the response.
+	RemainingAfterKey = attribute.Key("ghratelimit.remaining.after")
+)
+
+// roundTripper records a span around each request executed by the Base of a *ghratelimit.Transport.
+type roundTripper struct {
+	name   string
+	base   http.RoundTripper
+	limits *ghratelimit.Limits
+	tracer trace.Tracer
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := ghratelimit.InferResource(req)
+	attrs := []attribute.KeyValue{
+		TransportKey.String(rt.name),
+		ResourceKey.String(resource.String()),
+	}
+	if rate := rt.limits.Load(resource); rate != nil {
+		attrs = append(attrs, RemainingBeforeKey.Int64(int64(rate.Remaining)))
+	}
+	ctx, span := rt.tracer.Start(req.Context(), "ghratelimit.RoundTrip",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	resp, err := rt.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	if rate, err := ghratelimit.ParseRate(resp.Header); err == nil {
+		span.SetAttributes(RemainingAfterKey.Int64(int64(rate.Remaining)))
+	}
+	return resp, nil
+}
+
+// Instrument records a span for every request executed by the transport, identified by name (or the transport's Name if empty).
+// When the transport is a member of a ghratelimit.Balancer, the span identifies the selected transport.
+// It wraps the transport's Base, so it must be called before the transport is used.
+func Instrument(name string, transport *ghratelimit.Transport, tp trace.TracerProvider) {
+	base := transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if name == "" {
+		name = transport.Name
+	}
+	transport.Base = &roundTripper{
+		name:   name,
+		base:   base,
+		limits: &transport.Limits,
+		tracer: tp.Tracer(ScopeName),
+	}
+}
+
+// RecordMetrics records gauges for the limit, used, remaining and reset of every resource from the Notify hook of limits.
+// Any existing Notify hook is still called. It must be called before limits is used.
+func RecordMetrics(name string, limits *ghratelimit.Limits, mp metric.MeterProvider) error {
+	meter := mp.Meter(ScopeName)
+	limit, err := meter.Int64Gauge("github.rate_limit.limit", metric.WithDescription("The maximum number of requests that can be made in the current rate limit window"))
+	if err != nil {
+		return fmt.Errorf("(metric.Meter).Int64Gauge failed: %w", err)
+	}
+	used, err := meter.Int64Gauge("github.rate_limit.used", metric.WithDescription("Number of requests made in the current rate limit window"))
+	if err != nil {
+		return fmt.Errorf("(metric.Meter).Int64Gauge failed: %w", err)
+	}
+	remaining, err := meter.Int64Gauge("github.rate_limit.remaining", metric.WithDescription("Number of requests remaining in the current rate limit window"))
+	if err != nil {
+		return fmt.Errorf("(metric.Meter).Int64Gauge failed: %w", err)
+	}
+	reset, err := meter.Int64Gauge("github.rate_limit.reset", metric.WithDescription("Unix timestamp when the current rate limit window resets"), metric.WithUnit("s"))
+	if err != nil {
+		return fmt.Errorf("(metric.Meter).Int64Gauge failed: %w", err)
+	}
+
+	notify := limits.Notify
+	limits.Notify = func(resp *http.Response, resource ghratelimit.Resource, rate *ghratelimit.Rate) {
+		if notify != nil {
+			notify(resp, resource, rate)
+		}
+		ctx := context.Background()
+		if resp != nil && resp.Request != nil {
+			ctx = resp.Request.Context()
+		}
+		opt := metric.WithAttributes(TransportKey.String(name), ResourceKey.String(resource.String()))
+		limit.Record(ctx, int64(rate.Limit), opt)
+		used.Record(ctx, int64(rate.Used), opt)
+		remaining.Record(ctx, int64(rate.Remaining), opt)
+		reset.Record(ctx, int64(rate.Reset), opt)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package ghratelimitotel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestInstrument(t *testing.T) {
+	transport := &ghratelimit.Transport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"X-Ratelimit-Limit":     []string{"5000"},
+					"X-Ratelimit-Used":      []string{"1"},
+					"X-Ratelimit-Remaining": []string{"4999"},
+					"X-Ratelimit-Reset":     []string{"1745121612"},
+					"X-Ratelimit-Resource":  []string{"core"},
+				},
+				Body:    io.NopCloser(strings.NewReader("")),
+				Request: req,
+			}, nil
+		}),
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	Instrument("app", transport, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	reader := sdkmetric.NewManualReader()
+	assert.NoError(t, RecordMetrics("app", &transport.Limits, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "(*ghratelimit.Transport).RoundTrip failed")
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Contains(t, spans[0].Attributes(), TransportKey.String("app"))
+	assert.Contains(t, spans[0].Attributes(), ResourceKey.String("core"))
+	assert.Contains(t, spans[0].Attributes(), RemainingAfterKey.Int64(4999))
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+	assert.Len(t, rm.ScopeMetrics, 1)
+	assert.Len(t, rm.ScopeMetrics[0].Metrics, 4)
+}
@@ -0,0 +1,100 @@
+// Package ghratelimitprom exports the rate limits observed by ghratelimit as Prometheus metrics.
+// It is a separate module so that the core package does not depend on the Prometheus client.
+package ghratelimitprom
+
+import (
+	"strconv"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	labels = []string{"transport", "resource"}
+
+	limitDesc = prometheus.NewDesc(
+		"github_rate_limit_limit",
+		"The maximum number of requests that can be made in the current rate limit window",
+		labels, nil,
+	)
+	usedDesc = prometheus.NewDesc(
+		"github_rate_limit_used",
+		"Number of requests made in the current rate limit window",
+		labels, nil,
+	)
+	remainingDesc = prometheus.NewDesc(
+		"github_rate_limit_remaining",
+		"Number of requests remaining in the current rate limit window",
+		labels, nil,
+	)
+	resetDesc = prometheus.NewDesc(
+		"github_rate_limit_reset",
+		"Unix timestamp when the current rate limit window resets",
+		labels, nil,
+	)
+
+	deprecationLabels = []string{"transport", "resource", "method", "route"}
+
+	deprecatedDesc = prometheus.NewDesc(
+		"github_deprecated_responses_total",
+		"Number of responses signalling their endpoint is deprecated via the Deprecation or Sunset headers",
+		deprecationLabels, nil,
+	)
+	sunsetDesc = prometheus.NewDesc(
+		"github_deprecated_sunset",
+		"Unix timestamp when a deprecated endpoint will stop responding, per its Sunset header",
+		deprecationLabels, nil,
+	)
+)
+
+// Collector implements prometheus.Collector, exporting gauges for the limit, used, remaining and reset of every resource.
+type Collector struct {
+	// Limits maps the identity of each transport (used as the "transport" label) to its rate limits.
+	Limits map[string]*ghratelimit.Limits
+}
+
+// ForTransport returns a Collector for a single transport with the given identity.
+func ForTransport(name string, transport *ghratelimit.Transport) *Collector {
+	return &Collector{Limits: map[string]*ghratelimit.Limits{name: &transport.Limits}}
+}
+
+// ForBalancer returns a Collector for every transport in the pool, identified by their Name or index.
+func ForBalancer(bt *ghratelimit.Balancer) *Collector {
+	c := &Collector{Limits: make(map[string]*ghratelimit.Limits, len(bt.Transports))}
+	for idx, transport := range bt.Transports {
+		name := transport.Name
+		if name == "" {
+			name = strconv.Itoa(idx)
+		}
+		c.Limits[name] = &transport.Limits
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- limitDesc
+	ch <- usedDesc
+	ch <- remainingDesc
+	ch <- resetDesc
+	ch <- deprecatedDesc
+	ch <- sunsetDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for name, limits := range c.Limits {
+		for resource, rate := range limits.Iter() {
+			ch <- prometheus.MustNewConstMetric(limitDesc, prometheus.GaugeValue, float64(rate.Limit), name, resource.String())
+			ch <- prometheus.MustNewConstMetric(usedDesc, prometheus.GaugeValue, float64(rate.Used), name, resource.String())
+			ch <- prometheus.MustNewConstMetric(remainingDesc, prometheus.GaugeValue, float64(rate.Remaining), name, resource.String())
+			ch <- prometheus.MustNewConstMetric(resetDesc, prometheus.GaugeValue, float64(rate.Reset), name, resource.String())
+		}
+		for _, d := range limits.Deprecations() {
+			ch <- prometheus.MustNewConstMetric(deprecatedDesc, prometheus.CounterValue, float64(d.Count), name, d.Resource.String(), d.Method, d.Route)
+			if !d.Sunset.IsZero() {
+				ch <- prometheus.MustNewConstMetric(sunsetDesc, prometheus.GaugeValue, float64(d.Sunset.Unix()), name, d.Resource.String(), d.Method, d.Route)
+			}
+		}
+	}
+}
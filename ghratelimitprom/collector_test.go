@@ -0,0 +1,42 @@
+package ghratelimitprom
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	transport := &ghratelimit.Transport{}
+	transport.Limits.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: 1745121612})
+	c := ForTransport("app", transport)
+	err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP github_rate_limit_remaining Number of requests remaining in the current rate limit window
+# TYPE github_rate_limit_remaining gauge
+github_rate_limit_remaining{resource="core",transport="app"} 4999
+`), "github_rate_limit_remaining")
+	assert.NoError(t, err, "testutil.CollectAndCompare failed")
+}
+
+func TestCollector_Deprecations(t *testing.T) {
+	transport := &ghratelimit.Transport{}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r/legacy", nil)
+	_ = transport.Limits.Parse(&http.Response{
+		Header:  http.Header{"Sunset": []string{"Wed, 11 Nov 2026 23:59:59 GMT"}},
+		Request: req,
+	})
+	c := ForTransport("app", transport)
+	err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP github_deprecated_responses_total Number of responses signalling their endpoint is deprecated via the Deprecation or Sunset headers
+# TYPE github_deprecated_responses_total counter
+github_deprecated_responses_total{method="GET",resource="",route="/repos/{owner}/{repo}/legacy",transport="app"} 1
+# HELP github_deprecated_sunset Unix timestamp when a deprecated endpoint will stop responding, per its Sunset header
+# TYPE github_deprecated_sunset gauge
+github_deprecated_sunset{method="GET",resource="",route="/repos/{owner}/{repo}/legacy",transport="app"} 1.794441599e+09
+`), "github_deprecated_responses_total", "github_deprecated_sunset")
+	assert.NoError(t, err, "testutil.CollectAndCompare failed")
+}
@@ -0,0 +1,75 @@
+// Package ghratelimitredis implements ghratelimit.SharedStore using Redis,
+// so that every replica using the same credential balances on the collective remaining count.
+// It is a separate module so that the core package does not depend on the Redis client.
+package ghratelimitredis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is a ghratelimit.SharedStore backed by Redis.
+type Store struct {
+	// Client is the Redis client (ex: *redis.Client or *redis.ClusterClient).
+	Client redis.Cmdable
+	// Prefix is prepended to every key, if set.
+	Prefix string
+}
+
+var (
+	_ ghratelimit.SharedStore      = (*Store)(nil)
+	_ ghratelimit.SharedNegotiator = (*Store)(nil)
+	_ ghratelimit.SharedMerger     = (*Store)(nil)
+)
+
+// Get implements ghratelimit.SharedStore
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := s.Client.Get(ctx, s.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set implements ghratelimit.SharedStore
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.Client.Set(ctx, s.Prefix+key, value, ttl).Err()
+}
+
+// Negotiate implements ghratelimit.SharedNegotiator
+func (s *Store) Negotiate(ctx context.Context, offer ghratelimit.SharedProtocol) (ghratelimit.SharedProtocol, error) {
+	protocol := ghratelimit.SharedProtocol{Version: min(offer.Version, ghratelimit.SharedProtocolVersion)}
+	if offer.Has(ghratelimit.SharedCapabilityMerge) {
+		protocol.Capabilities = append(protocol.Capabilities, ghratelimit.SharedCapabilityMerge)
+	}
+	return protocol, nil
+}
+
+// mergeScript sets KEYS[1] to ARGV[1] with a TTL of ARGV[2] milliseconds, unless the stored rate limit is fresher.
+var mergeScript = redis.NewScript(`
+local stored = redis.call("GET", KEYS[1])
+if stored then
+	local ok, old = pcall(cjson.decode, stored)
+	local new = cjson.decode(ARGV[1])
+	if ok and (old.reset > new.reset or (old.reset == new.reset and old.remaining < new.remaining)) then
+		return 0
+	end
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// Merge implements ghratelimit.SharedMerger
+func (s *Store) Merge(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := mergeScript.Run(ctx, s.Client, []string{s.Prefix + key}, value, max(ttl.Milliseconds(), 1)).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
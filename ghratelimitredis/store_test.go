@@ -0,0 +1,48 @@
+package ghratelimitredis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &Store{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()}), Prefix: "ghratelimit:"}
+
+	replicaA := &ghratelimit.Limits{Shared: store, SharedKey: "app"}
+	replicaB := &ghratelimit.Limits{Shared: store, SharedKey: "app"}
+	replicaA.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+
+	require.NoError(t, replicaB.SyncShared(t.Context()))
+	rate := replicaB.Load(ghratelimit.ResourceCore)
+	if assert.NotNil(t, rate, "replica should see the shared rate limit") {
+		assert.Equal(t, uint64(4900), rate.Remaining)
+	}
+	assert.True(t, mr.Exists("ghratelimit:app:core"), "key should be prefixed")
+	assert.Greater(t, mr.TTL("ghratelimit:app:core"), time.Duration(0), "key should expire")
+}
+
+func TestStore_Merge(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := &Store{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+
+	replicaA := &ghratelimit.Limits{Shared: store, SharedKey: "app"}
+	replicaB := &ghratelimit.Limits{Shared: store, SharedKey: "app"}
+	replicaA.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+	assert.Equal(t, &ghratelimit.SharedProtocol{Version: ghratelimit.SharedProtocolVersion, Capabilities: []ghratelimit.SharedCapability{ghratelimit.SharedCapabilityMerge}}, replicaA.SharedProtocol())
+
+	replicaB.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 50, Remaining: 4950, Reset: 1745121612})
+	require.NoError(t, replicaB.SyncShared(t.Context()))
+	assert.Equal(t, uint64(4900), replicaB.Load(ghratelimit.ResourceCore).Remaining, "stale update should not win")
+
+	replicaB.Store(nil, ghratelimit.ResourceCore, &ghratelimit.Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: 1745125212})
+	require.NoError(t, replicaA.SyncShared(t.Context()))
+	assert.Equal(t, uint64(4999), replicaA.Load(ghratelimit.ResourceCore).Remaining, "new window should win")
+	assert.Greater(t, mr.TTL("app:core"), time.Duration(0), "key should expire")
+}
@@ -0,0 +1,126 @@
+package ghratelimittest
+
+import (
+	"sync"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// Clock is a virtual ghratelimit.Clock that only moves when advanced, set it as the Server's Now and the Limits' Clock
+// to simulate window resets without sleeping. The zero value starts at the real time when first used.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending After or Ticker of the Clock.
+type waiter struct {
+	at     time.Time
+	period time.Duration // zero for After
+	ch     chan time.Time
+}
+
+var _ ghratelimit.Clock = (*Clock)(nil)
+
+// init starts the clock at the real time if it has not been used yet, c.mu must be held.
+func (c *Clock) init() {
+	if c.now.IsZero() {
+		c.now = time.Now()
+	}
+}
+
+// Now implements ghratelimit.Clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	return c.now
+}
+
+// schedule adds a waiter firing after d (and every period after, if non-zero).
+func (c *Clock) schedule(d, period time.Duration) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	w := &waiter{at: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.ch <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// After implements ghratelimit.Clock
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.schedule(d, 0).ch
+}
+
+// NewTicker implements ghratelimit.Clock
+func (c *Clock) NewTicker(d time.Duration) ghratelimit.Ticker {
+	return &ticker{clock: c, waiter: c.schedule(d, d)}
+}
+
+// Advance moves the virtual time forward by d, firing any After channels and tickers that are due.
+// Like time.Ticker, a ticker that is not read drops ticks.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		for !w.at.After(c.now) {
+			select {
+			case w.ch <- c.now:
+			default:
+			}
+			if w.period == 0 {
+				break
+			}
+			w.at = w.at.Add(w.period)
+		}
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+		}
+	}
+	c.waiters = pending
+}
+
+// Waiters returns the number of pending After channels and tickers,
+// ex: to advance the clock only once a goroutine is blocked waiting on it.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// stop removes the waiter.
+func (c *Clock) stop(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for idx, pending := range c.waiters {
+		if pending == w {
+			c.waiters = append(c.waiters[:idx], c.waiters[idx+1:]...)
+			return
+		}
+	}
+}
+
+// ticker implements ghratelimit.Ticker for a Clock.
+type ticker struct {
+	clock  *Clock
+	waiter *waiter
+}
+
+// C implements ghratelimit.Ticker
+func (t *ticker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+// Stop implements ghratelimit.Ticker
+func (t *ticker) Stop() {
+	t.clock.stop(t.waiter)
+}
@@ -0,0 +1,27 @@
+package ghratelimittest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	var clock Clock
+	start := clock.Now()
+	after := clock.After(time.Minute)
+	ticker := clock.NewTicker(time.Second)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), clock.Now())
+	assert.Len(t, after, 0, "After should not fire early")
+	assert.Equal(t, start.Add(30*time.Second), <-ticker.C(), "ticks should be delivered")
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-after)
+	ticker.Stop()
+	assert.Equal(t, 0, clock.Waiters())
+	assert.Len(t, clock.After(0), 1, "elapsed durations should fire immediately")
+}
@@ -0,0 +1,216 @@
+// Package ghratelimittest provides a fake GitHub API for testing code built on ghratelimit,
+// emitting realistic X-RateLimit-* headers, window resets, secondary rate limits and /rate_limit responses.
+package ghratelimittest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+)
+
+// DefaultLimits are the rate limits per window used for resource types missing from Server.Limits,
+// matching GitHub's defaults for an authenticated user.
+var DefaultLimits = map[ghratelimit.Resource]uint64{
+	ghratelimit.ResourceCore:       5000,
+	ghratelimit.ResourceSearch:     30,
+	ghratelimit.ResourceCodeSearch: 10,
+	ghratelimit.ResourceGraphQL:    5000,
+}
+
+// DefaultWindows are the lengths of the rate-limit windows used for resource types missing from Server.Windows,
+// resource types missing from both use an hour.
+var DefaultWindows = map[ghratelimit.Resource]time.Duration{
+	ghratelimit.ResourceSearch:     time.Minute,
+	ghratelimit.ResourceCodeSearch: time.Minute,
+}
+
+// window is the state of a resource type's current rate-limit window.
+type window struct {
+	used  uint64
+	reset time.Time
+}
+
+// Server is a fake GitHub API tracking the rate limit of each resource type (see ghratelimit.InferResource).
+// It is a http.Handler (ex: for httptest.NewServer) and a http.RoundTripper (ex: as a ghratelimit.Transport's Base).
+// It is safe for concurrent use, the zero value is ready to use.
+type Server struct {
+	// Limits is the number of requests allowed per window for each resource type, see DefaultLimits.
+	Limits map[ghratelimit.Resource]uint64
+	// Windows is the length of the rate-limit window for each resource type, see DefaultWindows.
+	Windows map[ghratelimit.Resource]time.Duration
+	// Now returns the current time, if nil time.Now is used. Use a (*Clock).Now to simulate time passing.
+	Now func() time.Time
+	// Secondary, if set, is called for every request, a non-zero duration responds with a secondary rate limit
+	// with that Retry-After instead. The request does not consume the primary rate limit.
+	Secondary func(*http.Request) time.Duration
+	// Handler, if set, serves every request that is not rate limited, otherwise 200 OK with an empty JSON object is returned.
+	Handler http.Handler
+
+	mu      sync.Mutex
+	windows map[ghratelimit.Resource]*window
+}
+
+// now returns the current (possibly virtual) time.
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// limit returns the number of requests allowed per window for the resource type.
+func (s *Server) limit(resource ghratelimit.Resource) uint64 {
+	if limit, ok := s.Limits[resource]; ok {
+		return limit
+	}
+	if limit, ok := DefaultLimits[resource]; ok {
+		return limit
+	}
+	return DefaultLimits[ghratelimit.ResourceCore]
+}
+
+// window returns the current window of the resource type, starting a new one if it has reset, s.mu must be held.
+func (s *Server) window(resource ghratelimit.Resource, now time.Time) *window {
+	if s.windows == nil {
+		s.windows = make(map[ghratelimit.Resource]*window)
+	}
+	w, ok := s.windows[resource]
+	if !ok || !now.Before(w.reset) {
+		length, ok := s.Windows[resource]
+		if !ok {
+			if length, ok = DefaultWindows[resource]; !ok {
+				length = time.Hour
+			}
+		}
+		w = &window{reset: now.Add(length).Truncate(time.Second)}
+		s.windows[resource] = w
+	}
+	return w
+}
+
+// rate returns the rate limit of the window, s.mu must be held.
+func (s *Server) rate(resource ghratelimit.Resource, w *window) *ghratelimit.Rate {
+	limit := s.limit(resource)
+	return &ghratelimit.Rate{
+		Limit:     limit,
+		Used:      min(w.used, limit),
+		Remaining: limit - min(w.used, limit),
+		Reset:     uint64(w.reset.Unix()),
+	}
+}
+
+// Rate returns the current rate limit of the resource type.
+func (s *Server) Rate(resource ghratelimit.Resource) *ghratelimit.Rate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate(resource, s.window(resource, s.now()))
+}
+
+// Use consumes n requests of the resource type's current window, ex: to simulate another process sharing the credential.
+func (s *Server) Use(resource ghratelimit.Resource, n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window(resource, s.now()).used += n
+}
+
+// Exhaust consumes every remaining request of the resource type's current window.
+func (s *Server) Exhaust(resource ghratelimit.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window(resource, s.now()).used = s.limit(resource)
+}
+
+// writeRate sets the X-RateLimit-* headers of the response.
+func writeRate(header http.Header, resource ghratelimit.Resource, rate *ghratelimit.Rate) {
+	header.Set("X-Ratelimit-Limit", strconv.FormatUint(rate.Limit, 10))
+	header.Set("X-Ratelimit-Used", strconv.FormatUint(rate.Used, 10))
+	header.Set("X-Ratelimit-Remaining", strconv.FormatUint(rate.Remaining, 10))
+	header.Set("X-Ratelimit-Reset", strconv.FormatUint(rate.Reset, 10))
+	header.Set("X-Ratelimit-Resource", resource.String())
+}
+
+// writeJSON writes the value as a JSON response with the status code.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serveRateLimit handles the /rate_limit endpoint, which does not consume any rate limit.
+func (s *Server) serveRateLimit(w http.ResponseWriter, now time.Time) {
+	s.mu.Lock()
+	resources := make(map[ghratelimit.Resource]*ghratelimit.Rate)
+	for _, resource := range ghratelimit.ValidResources {
+		resources[resource] = s.rate(resource, s.window(resource, now))
+	}
+	for resource := range s.windows {
+		resources[resource] = s.rate(resource, s.window(resource, now))
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"resources": resources,
+		"rate":      resources[ghratelimit.ResourceCore],
+	})
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := s.now()
+	if strings.TrimPrefix(r.URL.Path, "/api/v3") == "/rate_limit" {
+		s.serveRateLimit(w, now)
+		return
+	}
+	resource := ghratelimit.InferResource(r)
+	if resource == "" {
+		resource = ghratelimit.ResourceCore
+	}
+	if resource != ghratelimit.ResourceUntracked {
+		if s.Secondary != nil {
+			if retryAfter := s.Secondary(r); retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second)/time.Second)))
+				writeJSON(w, http.StatusForbidden, map[string]string{
+					"message":           "You have exceeded a secondary rate limit. Please wait a few minutes before you try again.",
+					"documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits",
+				})
+				return
+			}
+		}
+		s.mu.Lock()
+		win := s.window(resource, now)
+		exceeded := win.used >= s.limit(resource)
+		if !exceeded {
+			win.used++
+		}
+		rate := s.rate(resource, win)
+		s.mu.Unlock()
+		writeRate(w.Header(), resource, rate)
+		if exceeded {
+			writeJSON(w, http.StatusForbidden, map[string]string{
+				"message":           fmt.Sprintf("API rate limit exceeded for %s.", resource),
+				"documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-primary-rate-limits",
+			})
+			return
+		}
+	}
+	if s.Handler != nil {
+		s.Handler.ServeHTTP(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// RoundTrip implements http.RoundTripper, serving the request in-process without any network access.
+func (s *Server) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+package ghratelimittest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	ghratelimit "github.com/bored-engineer/github-rate-limit-http-transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	var clock Clock
+	server := &Server{Now: clock.Now, Limits: map[ghratelimit.Resource]uint64{ghratelimit.ResourceSearch: 2}}
+	transport := &ghratelimit.Transport{Base: server}
+
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/search/repositories?q=go", nil)
+		resp, err := transport.RoundTrip(req)
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	}
+	rate := transport.Limits.Load(ghratelimit.ResourceSearch)
+	if assert.NotNil(t, rate) {
+		assert.Equal(t, ghratelimit.Rate{Limit: 2, Used: 2, Remaining: 0, Reset: uint64(clock.Now().Add(time.Minute).Unix())}, *rate)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/search/repositories?q=go", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "exhausted resource should be rejected")
+		assert.Equal(t, "0", resp.Header.Get("X-Ratelimit-Remaining"))
+	}
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, uint64(2), server.Rate(ghratelimit.ResourceSearch).Remaining, "window should reset")
+	assert.Equal(t, uint64(5000), server.Rate(ghratelimit.ResourceCore).Remaining, "resources should be independent")
+}
+
+func TestServer_Secondary(t *testing.T) {
+	server := &Server{Secondary: func(*http.Request) time.Duration { return time.Minute }}
+	transport := &ghratelimit.Transport{Base: server, ClassifyForbidden: true}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	var secondary *ghratelimit.SecondaryRateLimitError
+	if assert.True(t, errors.As(err, &secondary)) {
+		assert.Equal(t, time.Minute, secondary.RetryAfter)
+	}
+	assert.Equal(t, uint64(0), server.Rate(ghratelimit.ResourceCore).Used, "secondary rate limits should not consume the primary")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	server := &Server{}
+	server.Use(ghratelimit.ResourceCore, 10)
+	server.Exhaust(ghratelimit.ResourceGraphQL)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	base, _ := url.Parse(srv.URL)
+	var limits ghratelimit.Limits
+	assert.NoError(t, limits.Fetch(context.Background(), srv.Client().Transport, ghratelimit.RateLimitURL(base)))
+	assert.Equal(t, uint64(4990), limits.Load(ghratelimit.ResourceCore).Remaining)
+	assert.Equal(t, uint64(0), limits.Load(ghratelimit.ResourceGraphQL).Remaining)
+	assert.Equal(t, uint64(10), server.Rate(ghratelimit.ResourceCore).Used, "/rate_limit should not consume any rate limit")
+
+	resp, err := srv.Client().Get(srv.URL + "/api/v3/rate_limit")
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		var body struct {
+			Rate ghratelimit.Rate `json:"rate"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, uint64(5000), body.Rate.Limit)
+	}
+}
@@ -0,0 +1,85 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// graphQLRateLimit is the rateLimit object that can be requested in a GraphQL query:
+//
+//	rateLimit { cost limit used remaining resetAt }
+type graphQLRateLimit struct {
+	Cost      *uint64   `json:"cost"`
+	Limit     *uint64   `json:"limit"`
+	Used      *uint64   `json:"used"`
+	Remaining *uint64   `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+// maxGraphQLBody is the largest response body ParseGraphQL reads into memory, larger bodies are not inspected.
+const maxGraphQLBody = 8 << 20
+
+// ParseGraphQL updates the graphql rate limit from the rateLimit object in a GraphQL response body, if present.
+// The response body (up to 8 MiB, larger bodies are passed through uninspected) is read into memory and replaced,
+// so it can still be read by the caller.
+// If only the cost was queried, it is deducted from the last known remaining rate limit, unless the response's
+// rate-limit headers already accounted for it.
+func (l *Limits) ParseGraphQL(resp *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGraphQLBody+1))
+	if err != nil {
+		return fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	if len(body) > maxGraphQLBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Data struct {
+			RateLimit *graphQLRateLimit `json:"rateLimit"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil // not a JSON response, nothing to inspect
+	}
+	rl := payload.Data.RateLimit
+	if rl == nil {
+		return nil
+	}
+
+	var rate Rate
+	if last := l.Load(ResourceGraphQL); last != nil {
+		rate = *last
+	}
+	switch {
+	case rl.Remaining != nil:
+		rate.Remaining = *rl.Remaining
+	case rl.Cost != nil && resp.Header.Get("X-RateLimit-Remaining") == "":
+		rate.Remaining -= min(*rl.Cost, rate.Remaining)
+		rate.Used += *rl.Cost
+	default:
+		return nil
+	}
+	if rl.Limit != nil {
+		rate.Limit = *rl.Limit
+	}
+	if rl.Used != nil {
+		rate.Used = *rl.Used
+	}
+	if !rl.ResetAt.IsZero() {
+		rate.Reset = uint64(rl.ResetAt.Unix())
+	}
+	l.Store(resp, ResourceGraphQL, &rate)
+	return nil
+}
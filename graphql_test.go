@@ -0,0 +1,40 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_ParseGraphQL(t *testing.T) {
+	var limits Limits
+	limits.Store(nil, ResourceGraphQL, &Rate{Limit: 5000, Used: 10, Remaining: 4990, Reset: 1745121612})
+
+	body := `{"data":{"viewer":{"login":"x"},"rateLimit":{"cost":5}}}`
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+	assert.NoError(t, limits.ParseGraphQL(resp))
+	assert.Equal(t, &Rate{Limit: 5000, Used: 15, Remaining: 4985, Reset: 1745121612}, limits.Load(ResourceGraphQL), "cost should be deducted")
+	b, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, body, string(b), "body should be re-readable")
+
+	resp = &http.Response{Body: io.NopCloser(strings.NewReader(`{"data":{"rateLimit":{"cost":1,"limit":5000,"used":20,"remaining":4980,"resetAt":"2025-04-20T04:00:12Z"}}}`))}
+	assert.NoError(t, limits.ParseGraphQL(resp))
+	assert.Equal(t, &Rate{Limit: 5000, Used: 20, Remaining: 4980, Reset: 1745121612}, limits.Load(ResourceGraphQL))
+
+	resp = &http.Response{Header: http.Header{"X-Ratelimit-Remaining": {"4979"}}, Body: io.NopCloser(strings.NewReader(`{"data":{"rateLimit":{"cost":1}}}`))}
+	assert.NoError(t, limits.ParseGraphQL(resp))
+	assert.Equal(t, uint64(4980), limits.Load(ResourceGraphQL).Remaining, "cost should not be deducted twice when the headers reported it")
+
+	large := `{"data":{"rateLimit":{"cost":1}},"padding":"` + strings.Repeat("x", maxGraphQLBody) + `"}`
+	resp = &http.Response{Body: io.NopCloser(strings.NewReader(large))}
+	assert.NoError(t, limits.ParseGraphQL(resp))
+	assert.Equal(t, uint64(4980), limits.Load(ResourceGraphQL).Remaining, "large bodies should not be inspected")
+	b, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, large, string(b), "large bodies should be passed through")
+
+	resp = &http.Response{Body: io.NopCloser(strings.NewReader(`not json`))}
+	assert.NoError(t, limits.ParseGraphQL(resp), "non-JSON bodies should be ignored")
+}
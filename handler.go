@@ -0,0 +1,57 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"time"
+)
+
+// resourceSnapshot is the live state of a resource type served by the debug handlers.
+type resourceSnapshot struct {
+	*Rate
+	// ResetTime is Reset as a timestamp, for humans.
+	ResetTime time.Time `json:"reset_time"`
+	// BurnRate is the number of requests per second consumed in the current window, see (*Limits).BurnRate.
+	BurnRate float64 `json:"burn_rate"`
+}
+
+// snapshot returns the live state of every resource type.
+func (l *Limits) snapshot() map[Resource]resourceSnapshot {
+	resources := make(map[Resource]resourceSnapshot)
+	for resource, rate := range l.Iter() {
+		resources[resource] = resourceSnapshot{Rate: rate, ResetTime: rate.ResetTime(), BurnRate: l.BurnRate(resource)}
+	}
+	return resources
+}
+
+// Handler returns a http.Handler serving a JSON snapshot of every resource type's rate limit,
+// suitable for mounting under /debug/ghratelimit to diagnose slow or throttled requests in production.
+func (l *Limits) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"resources": l.snapshot()})
+	})
+}
+
+// Handler returns a http.Handler serving a JSON snapshot of the status and rate limits of every transport,
+// suitable for mounting under /debug/ghratelimit. Unlike AdminHandler it is read-only.
+func (bt *Balancer) Handler() http.Handler {
+	type transportSnapshot struct {
+		TransportStatus
+		Resources map[Resource]resourceSnapshot `json:"resources"`
+		Usage     map[Resource]MethodUsage      `json:"usage"`
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		transports := bt.transports()
+		snapshots := make([]transportSnapshot, 0, len(transports))
+		for idx, transport := range transports {
+			snapshots = append(snapshots, transportSnapshot{
+				TransportStatus: bt.status(transport, idx),
+				Resources:       transport.Limits.snapshot(),
+				Usage:           transport.MethodUsage(),
+			})
+		}
+		writeJSON(w, map[string]any{
+			"standby_threshold": bt.getStandbyThreshold(),
+			"transports":        snapshots,
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_Handler(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	var limits Limits
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: uint64(reset.Unix())})
+
+	rec := httptest.NewRecorder()
+	limits.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/ghratelimit", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body struct {
+		SchemaVersion int                         `json:"schema_version"`
+		Resources     map[Resource]map[string]any `json:"resources"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, SchemaVersion, body.SchemaVersion)
+	assert.Equal(t, 4999.0, body.Resources[ResourceCore]["remaining"])
+	assert.Equal(t, reset.Format(time.RFC3339), body.Resources[ResourceCore]["reset_time"])
+}
+
+func TestBalancer_Handler(t *testing.T) {
+	named := withRemaining(100)
+	named.Name = "primary"
+	bt := &Balancer{Transports: []*Transport{named, withRemaining(50)}}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", nil)
+	resp, err := named.RoundTrip(req)
+	assert.NoError(t, err)
+	discard(resp)
+
+	rec := httptest.NewRecorder()
+	bt.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/ghratelimit", nil))
+	var body struct {
+		Transports []struct {
+			Name      string                      `json:"name"`
+			Healthy   bool                        `json:"healthy"`
+			Resources map[Resource]map[string]any `json:"resources"`
+			Usage     map[Resource]MethodUsage    `json:"usage"`
+		} `json:"transports"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	if assert.Len(t, body.Transports, 2) {
+		assert.Equal(t, "primary", body.Transports[0].Name)
+		assert.True(t, body.Transports[0].Healthy)
+		assert.Equal(t, 100.0, body.Transports[0].Resources[ResourceCore]["remaining"])
+		assert.Equal(t, MethodUsage{Writes: 1}, body.Transports[0].Usage[ResourceCore])
+		assert.Equal(t, 50.0, body.Transports[1].Resources[ResourceCore]["remaining"])
+	}
+}
@@ -0,0 +1,96 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrHardCapReached is returned when a request would exceed the transport's HardCap for the resource type.
+var ErrHardCapReached = errors.New("hard cap reached")
+
+// spend counts the requests sent by a transport for a resource type in the current rate-limit window.
+type spend struct {
+	mu    sync.Mutex
+	known bool   // false until the first window is known
+	reset uint64 // Reset of the window being counted
+	n     uint64
+}
+
+// roll moves the spend to the window with the given Reset, zeroing the count if it is a new window.
+// Once the counted window has reset the count is zeroed even if no response reported the next window yet (ex: as the
+// capped transport sent no requests), a Reset that already passed is then ignored. Requests counted before a window
+// was known are carried over to it.
+func (s *spend) roll(reset uint64, known bool, now time.Time) {
+	if s.known && !now.Before((&Rate{Reset: s.reset}).ResetTime()) {
+		s.known, s.reset, s.n = false, 0, 0
+	}
+	switch {
+	case !known || !now.Before((&Rate{Reset: reset}).ResetTime()):
+	case !s.known:
+		s.known, s.reset = true, reset
+	case s.reset != reset:
+		s.reset, s.n = reset, 0
+	}
+}
+
+// spent returns the spend of the resource type.
+func (t *Transport) spent(resource Resource) *spend {
+	val, _ := t.spends.LoadOrStore(resource, &spend{})
+	return val.(*spend)
+}
+
+// window returns the Reset of the current rate-limit window of the resource type, or false if unknown.
+func (t *Transport) window(resource Resource) (uint64, bool) {
+	if rate := t.Limits.Load(resource); rate != nil {
+		return rate.Reset, true
+	}
+	return 0, false
+}
+
+// capRemaining returns how many more requests HardCap allows for the resource type in the current window,
+// or false if the resource type is not capped.
+func (t *Transport) capRemaining(resource Resource) (uint64, bool) {
+	limit, ok := t.HardCap[resource]
+	if !ok {
+		return 0, false
+	}
+	reset, known := t.window(resource) // read before locking, Load must not be called under s.mu
+	now := t.clock().Now()
+	s := t.spent(resource)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(reset, known, now)
+	return limit - min(s.n, limit), true
+}
+
+// spend counts a request costing cost against the HardCap of the resource type, returning an error wrapping ErrHardCapReached if it would exceed it.
+func (t *Transport) spend(resource Resource, cost uint64) error {
+	limit, ok := t.HardCap[resource]
+	if !ok {
+		return nil
+	}
+	reset, known := t.window(resource)
+	now := t.clock().Now()
+	s := t.spent(resource)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(reset, known, now)
+	if s.n+cost > limit {
+		return fmt.Errorf("%w: %d of %d %s requests sent this window", ErrHardCapReached, s.n, limit, resource)
+	}
+	s.n += cost
+	return nil
+}
+
+// waitHardCap blocks until the rate-limit window of the resource type resets if a request costing cost would exceed its HardCap.
+func (t *Transport) waitHardCap(ctx context.Context, resource Resource, cost uint64) error {
+	rate := t.Limits.Load(resource)
+	if remaining, ok := t.capRemaining(resource); !ok || remaining >= cost || rate == nil {
+		return nil
+	}
+	debugf("waiting until %s for %s window reset, hard cap reached", rate.ResetTime(), resource)
+	return sleepUntil(ctx, t.clock(), rate.ResetTime(), "hard cap reset", resource)
+}
@@ -0,0 +1,71 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_HardCap(t *testing.T) {
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	capped := withRemaining(5000)
+	capped.HardCap = map[Resource]uint64{ResourceCore: 2}
+	capped.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000, Reset: reset})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	for range 2 {
+		_, err := capped.RoundTrip(req)
+		assert.NoError(t, err, "(*Transport).RoundTrip failed")
+	}
+	_, err := capped.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrHardCapReached, "requests over the cap should fail despite the remaining rate limit")
+
+	other := withRemaining(100)
+	bt := &Balancer{Transports: []*Transport{capped, other}}
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, other, selected, "capped transports should be avoided")
+
+	capped.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000, Reset: reset + 3600})
+	_, err = capped.RoundTrip(req)
+	assert.NoError(t, err, "the cap should reset with the window")
+}
+
+func TestTransport_HardCap_FirstWindow(t *testing.T) {
+	var sent int
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	transport := &Transport{HardCap: map[Resource]uint64{ResourceCore: 2}}
+	transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent++
+		resp, err := okResponse().RoundTrip(req)
+		resp.Header.Set("X-Ratelimit-Resource", "core")
+		resp.Header.Set("X-Ratelimit-Limit", "5000")
+		resp.Header.Set("X-Ratelimit-Remaining", strconv.Itoa(5000-sent))
+		resp.Header.Set("X-Ratelimit-Used", strconv.Itoa(sent))
+		resp.Header.Set("X-Ratelimit-Reset", reset)
+		return resp, err
+	})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	for range 3 {
+		_, _ = transport.RoundTrip(req)
+	}
+	assert.Equal(t, 2, sent, "the request sent before the window was known should count against it")
+}
+
+func TestTransport_HardCap_Reset(t *testing.T) {
+	transport := &Transport{Base: okResponse(), HardCap: map[Resource]uint64{ResourceCore: 1}}
+	transport.Limits.Clock = beforeReset
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000, Reset: 1745121612})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrHardCapReached)
+
+	transport.Limits.Clock = fixedClock(time.Unix(1745121615, 0))
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err, "the cap should reset with the window even if nothing reported the next one")
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrHardCapReached, "requests should be counted against the next window")
+}
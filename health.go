@@ -0,0 +1,147 @@
+package ghratelimit
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthPolicy configures the eviction of unhealthy transports from a Balancer,
+// ex: because the token was revoked or its credentials expired.
+type HealthPolicy struct {
+	// Failures is the number of consecutive failures (401, 403 or 5xx responses and errors, but not rate limits)
+	// after which a transport is evicted. If less than 1, transports are never evicted.
+	Failures int `json:"failures"`
+	// MinBackoff is how long a transport is first evicted for, after which it is probed with a single request.
+	// The eviction is doubled every time the probe fails, and reset once a request succeeds.
+	MinBackoff time.Duration `json:"min_backoff"`
+	// MaxBackoff, if non-zero, caps the exponential eviction.
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+}
+
+// health is the health state of a transport.
+type health struct {
+	mu           sync.Mutex
+	failures     int
+	evictions    int
+	evictedUntil time.Time
+}
+
+// unhealthy reports if the outcome of a request counts as a health failure of the transport.
+// Rate-limited responses, requests whose context is done and local limits are not failures.
+func unhealthy(resource Resource, resp *http.Response, err error) bool {
+	if DefaultRetryable(resource, resp, err) {
+		return false
+	}
+	if err != nil {
+		var waitErr *WaitError
+		return !errors.As(err, &waitErr) && !errors.Is(err, ErrSaturated) && !errors.Is(err, ErrHardCapReached) && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrHostNotAllowed)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return true
+	case resp.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+// record updates the health of the transport with the outcome of a request, evicting it per the policy.
+func (h *health) record(policy *HealthPolicy, failed bool, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !failed {
+		h.failures, h.evictions, h.evictedUntil = 0, 0, time.Time{}
+		return
+	}
+	h.failures++
+	if policy.Failures < 1 || h.failures < policy.Failures {
+		return
+	}
+	backoff := policy.MinBackoff << min(h.evictions, 32)
+	if backoff <= 0 || (policy.MaxBackoff > 0 && backoff > policy.MaxBackoff) {
+		backoff = policy.MaxBackoff
+	}
+	h.evictions++
+	h.evictedUntil = now.Add(backoff)
+}
+
+// Healthy reports if the transport is not currently evicted from selection by a Balancer's HealthPolicy,
+// and its Member (if any) is healthy.
+func (t *Transport) Healthy() bool {
+	if t.Member != nil && !t.Member.Healthy() {
+		return false
+	}
+	t.health.mu.Lock()
+	defer t.health.mu.Unlock()
+	return !t.clock().Now().Before(t.health.evictedUntil)
+}
+
+// TransportStatus is the state of a transport in a Balancer.
+type TransportStatus struct {
+	// Index is the position of the transport in Transports.
+	Index int `json:"index"`
+	// Name is the Name of the transport, if any.
+	Name string `json:"name,omitempty"`
+	// Fingerprint is the fingerprint of the transport's credential in the Balancer, if known, see Balancer.Fingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Paused reports if the transport was paused (or is draining).
+	Paused bool `json:"paused"`
+	// Standby reports if the transport is a standby.
+	Standby bool `json:"standby"`
+	// InFlight is the number of in-flight requests.
+	InFlight int `json:"in_flight"`
+	// Queued is the number of requests waiting to be sent, see QueueDepth.
+	Queued int `json:"queued"`
+	// Healthy reports if the transport is not evicted, see HealthPolicy.
+	Healthy bool `json:"healthy"`
+	// ConsecutiveFailures is the number of consecutive failed requests.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// EvictedUntil is when an evicted transport will next be probed.
+	EvictedUntil time.Time `json:"evicted_until,omitzero"`
+	// CircuitOpen reports if the transport's CircuitBreaker is open.
+	CircuitOpen bool `json:"circuit_open"`
+	// Shared is the protocol negotiated with the SharedStore of its Limits, if any, see (*Limits).SharedProtocol.
+	Shared *SharedProtocol `json:"shared,omitempty"`
+}
+
+// status returns the state of the transport.
+func (t *Transport) status(idx int) TransportStatus {
+	t.health.mu.Lock()
+	failures, evictedUntil := t.health.failures, t.health.evictedUntil
+	t.health.mu.Unlock()
+	if !t.clock().Now().Before(evictedUntil) {
+		evictedUntil = time.Time{}
+	}
+	return TransportStatus{
+		Index:               idx,
+		Name:                t.Name,
+		Paused:              t.Paused(),
+		Standby:             t.Standby,
+		InFlight:            t.InFlight(),
+		Queued:              t.QueueDepth(),
+		Healthy:             evictedUntil.IsZero(),
+		ConsecutiveFailures: failures,
+		EvictedUntil:        evictedUntil,
+		CircuitOpen:         t.CircuitOpen(),
+		Shared:              t.Limits.SharedProtocol(),
+	}
+}
+
+// Status returns the state of every transport.
+func (bt *Balancer) Status() []TransportStatus {
+	transports := bt.transports()
+	statuses := make([]TransportStatus, 0, len(transports))
+	for idx, transport := range transports {
+		statuses = append(statuses, bt.status(transport, idx))
+	}
+	return statuses
+}
+
+// status returns the state of the transport at the index, including its fingerprint.
+func (bt *Balancer) status(transport *Transport, idx int) TransportStatus {
+	status := transport.status(idx)
+	status.Fingerprint = bt.fingerprint(transport)
+	return status
+}
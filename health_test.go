@@ -0,0 +1,49 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withStatus returns a transport that always responds with the status code.
+func withStatus(code int) *Transport {
+	return &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: code, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})}
+}
+
+func TestBalancer_Health(t *testing.T) {
+	revoked, healthy := withStatus(http.StatusUnauthorized), withRemaining(100)
+	revoked.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000})
+	bt := &Balancer{
+		Transports: []*Transport{revoked, healthy},
+		Health:     &HealthPolicy{Failures: 2, MinBackoff: time.Hour},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	for range 2 {
+		resp, err := bt.RoundTrip(req)
+		assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	assert.False(t, revoked.Healthy(), "transport should be evicted after consecutive failures")
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "evicted transport should not be selected")
+
+	statuses := bt.Status()
+	assert.False(t, statuses[0].Healthy)
+	assert.Equal(t, 2, statuses[0].ConsecutiveFailures)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), statuses[0].EvictedUntil, time.Minute)
+	assert.True(t, statuses[1].Healthy)
+
+	// Once the eviction expires, a failed probe doubles the eviction
+	revoked.health.evictedUntil = time.Now()
+	assert.True(t, revoked.Healthy(), "transport should be probed once the eviction expires")
+	_, _ = bt.RoundTrip(req)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), bt.Status()[0].EvictedUntil, time.Minute)
+}
@@ -0,0 +1,64 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"time"
+)
+
+// HookInfo describes a request at a point in its lifecycle, see Hooks.
+type HookInfo struct {
+	// Request is the request being sent.
+	Request *http.Request
+	// Transport is the transport selected for (or sending) the request.
+	Transport *Transport
+	// Resource is the inferred rate-limit resource of the request.
+	Resource Resource
+	// Rate is a snapshot of the transport's rate limit for the resource, if known.
+	Rate *Rate
+	// Start is when the request was dispatched, it is zero for OnSelect.
+	Start time.Time
+	// Duration is how long the request took, only set for OnResponse.
+	Duration time.Duration
+	// Response is the response, only set for OnResponse.
+	Response *http.Response
+	// Err is the error of the request, only set for OnResponse.
+	Err error
+	// ParseErr is the error parsing the rate-limit headers of the response, only set for OnResponse.
+	// Unless the ParseErrorPolicy failed the request, the response is still returned, see ParseErrorPolicy.
+	ParseErr error
+}
+
+// Hooks are callbacks for each request, ex: for custom metrics, tracing or admission control.
+// Each callback is optional and must be safe for concurrent use.
+type Hooks struct {
+	// OnSelect is called when a Balancer selects a transport for a request (including failover attempts).
+	OnSelect func(HookInfo)
+	// OnRequest is called before a request is dispatched, if it returns an error the request is rejected with it.
+	OnRequest func(HookInfo) error
+	// OnResponse is called once the response of a dispatched request has been parsed, or the request failed.
+	OnResponse func(HookInfo)
+}
+
+// selected calls OnSelect, if set.
+func (h *Hooks) selected(info HookInfo) {
+	if h != nil && h.OnSelect != nil {
+		h.OnSelect(info)
+	}
+}
+
+// request calls OnRequest, if set.
+func (h *Hooks) request(info HookInfo) error {
+	if h != nil && h.OnRequest != nil {
+		return h.OnRequest(info)
+	}
+	return nil
+}
+
+// response calls OnResponse, if set.
+func (h *Hooks) response(info HookInfo, resp *http.Response, err error, now time.Time) {
+	if h != nil && h.OnResponse != nil {
+		info.Duration, info.Response, info.Err = now.Sub(info.Start), resp, err
+		info.Rate = info.Transport.Limits.Load(info.Resource)
+		h.OnResponse(info)
+	}
+}
@@ -0,0 +1,62 @@
+package ghratelimit
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) func(HookInfo) {
+		return func(info HookInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+			assert.Equal(t, ResourceCore, info.Resource)
+			assert.NotNil(t, info.Transport)
+		}
+	}
+	errDenied := errors.New("denied")
+	var deny bool
+	hooks := &Hooks{
+		OnSelect: record("select"),
+		OnRequest: func(info HookInfo) error {
+			record("request")(info)
+			assert.False(t, info.Start.IsZero())
+			if deny {
+				return errDenied
+			}
+			return nil
+		},
+		OnResponse: func(info HookInfo) {
+			record("response")(info)
+			assert.NoError(t, info.Err)
+			if assert.NotNil(t, info.Response) {
+				assert.Equal(t, http.StatusOK, info.Response.StatusCode)
+			}
+			assert.GreaterOrEqual(t, info.Duration, time.Duration(0))
+		},
+	}
+
+	transport := withRemaining(100)
+	transport.Hooks = hooks
+	bt := &Balancer{Transports: []*Transport{transport}, Hooks: hooks}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	assert.NoError(t, err)
+	discard(resp)
+	assert.Equal(t, []string{"select", "request", "request", "response", "response"}, calls)
+
+	calls = nil
+	deny = true
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, errDenied, "OnRequest should be able to reject requests")
+	assert.Equal(t, []string{"request"}, calls)
+	assert.Zero(t, transport.InFlight(), "rejected requests should release their slot")
+}
@@ -0,0 +1,31 @@
+package ghratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ContextWithIdempotencyKey returns a copy of ctx whose requests carry the caller-provided idempotency key, recorded in the
+// EventRetry of every retry or failover and in dead-letters, so higher layers can deduplicate retried creations
+// (ex: issues or comments) that GitHub may have accepted despite the error. Requests with a key are also considered
+// safe to send again, see RetryAborted. The key is not sent to GitHub.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key set by ContextWithIdempotencyKey, if any.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey).(string)
+	return key
+}
+
+// retried describes a request being sent again as an EventRetry, ex: "retrying POST /repos/o/r/issues: rate limited".
+func retried(req *http.Request, resource Resource, reason string) Event {
+	return Event{
+		Kind:           EventRetry,
+		Resource:       resource,
+		IdempotencyKey: IdempotencyKeyFromContext(req.Context()),
+		Message:        fmt.Sprintf("retrying %s %s: %s", req.Method, req.URL.Path, reason),
+	}
+}
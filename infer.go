@@ -2,11 +2,83 @@ package ghratelimit
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 )
 
+// Matcher reports if a HTTP request matches a resource added by RegisterResource.
+type Matcher func(*http.Request) bool
+
+// MatchAccept matches requests whose Accept header includes the media type (ex: "application/vnd.github.cloak-preview+json").
+// Media type parameters are ignored and the comparison is case-insensitive.
+func MatchAccept(mediaType string) Matcher {
+	return func(req *http.Request) bool {
+		for _, header := range req.Header.Values("Accept") {
+			for accept := range strings.SplitSeq(header, ",") {
+				accept, _, _ = strings.Cut(accept, ";")
+				if strings.EqualFold(strings.TrimSpace(accept), mediaType) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// MatchPathPrefix matches requests whose path (excluding any /api/v3 prefix) starts with prefix.
+func MatchPathPrefix(prefix string) Matcher {
+	return func(req *http.Request) bool {
+		return req.URL != nil && strings.HasPrefix(strings.TrimPrefix(req.URL.Path, "/api/v3"), prefix)
+	}
+}
+
+// MatchMethod matches requests with the given HTTP method.
+func MatchMethod(method string) Matcher {
+	return func(req *http.Request) bool {
+		return req.Method == method
+	}
+}
+
+// MatchAll matches requests that match all of the matchers.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req *http.Request) bool {
+		for _, match := range matchers {
+			if !match(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
 // InferResource guessed which rate-limit resource that will be consumed by the provided HTTP request.
+// A resource set by ContextWithResource takes precedence, then the resources added by RegisterResource are consulted,
+// then the built-in heuristics based on the path and method.
+// An empty Resource is returned for requests without a URL or path, which a Balancer handles per its UnknownResource.
 func InferResource(req *http.Request) Resource {
+	return registry.infer(req)
+}
+
+// infer implements InferResource using the resources added to r.
+func (r *resourceRegistry) infer(req *http.Request) Resource {
+	if req == nil {
+		return ""
+	}
+	if resource := ResourceFromContext(req.Context()); resource != "" {
+		return resource
+	}
+	if resource := r.match(req); resource != "" {
+		return resource
+	}
+	if req.URL == nil {
+		return ""
+	}
+	if slices.Contains(UntrackedHosts, req.URL.Hostname()) {
+		return ResourceUntracked
+	}
+	if req.URL.Path == "" {
+		return ""
+	}
 	path := strings.TrimPrefix(req.URL.Path, "/api/v3")
 	switch {
 	case strings.HasPrefix(path, "/search/"):
@@ -14,7 +86,7 @@ func InferResource(req *http.Request) Resource {
 			return ResourceCodeSearch
 		}
 		return ResourceSearch
-	case path == "/graphql":
+	case path == "/graphql", req.URL.Path == "/api/graphql":
 		return ResourceGraphQL
 	case strings.HasPrefix(path, "/app-manifests/"):
 		return ResourceIntegrationManifest
@@ -27,7 +99,7 @@ func InferResource(req *http.Request) Resource {
 		strings.HasSuffix(path, "/autofix") &&
 		req.Method == http.MethodPost:
 		return ResourceCodeScanningAutofix
-	case strings.HasPrefix(path, "/actions/runners/registration-token") &&
+	case strings.HasSuffix(path, "/actions/runners/registration-token") &&
 		req.Method == http.MethodPost:
 		return ResourceActionsRunnerRegistration
 	case strings.HasPrefix(path, "/scim/v2/"):
@@ -36,7 +108,8 @@ func InferResource(req *http.Request) Resource {
 		strings.Contains(path, "/dependency-graph/"):
 		return ResourceDependencySnapshots
 	case (strings.HasPrefix(path, "/enterprises/") ||
-		strings.HasPrefix(path, "/organizations/")) && strings.HasSuffix(path, "/audit-log"):
+		strings.HasPrefix(path, "/organizations/") ||
+		strings.HasPrefix(path, "/orgs/")) && strings.HasSuffix(path, "/audit-log"):
 		return ResourceAuditLog
 	case (strings.HasPrefix(path, "/enterprises/") ||
 		strings.HasPrefix(path, "/organizations/")) && strings.Contains(path, "/audit-log/streams"):
@@ -1,6 +1,7 @@
 package ghratelimit
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"testing"
@@ -26,3 +27,60 @@ func TestInferResource(t *testing.T) {
 		Method: http.MethodGet,
 	}), "mismatch  'core'")
 }
+
+func TestMatchAccept(t *testing.T) {
+	var r resourceRegistry
+	r.register(ResourceSearch, MatchAll(MatchPathPrefix("/repos/"), MatchAccept("application/vnd.github.cloak-preview+json")))
+
+	req := &http.Request{
+		URL:    &url.URL{Scheme: "https", Host: "api.github.com", Path: "/api/v3/repos/o/r/commits"},
+		Method: http.MethodGet,
+		Header: http.Header{"Accept": []string{"application/json, application/vnd.github.Cloak-Preview+json; q=0.9"}},
+	}
+	assert.Equal(t, ResourceSearch, r.infer(req), "matcher should match Accept header")
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	assert.Equal(t, ResourceCore, r.infer(req), "matcher should not match other media types")
+}
+
+func TestContextWithResource(t *testing.T) {
+	ctx := ContextWithResource(context.Background(), ResourceCodeScanningUpload)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/o/r/future-endpoint", nil)
+	assert.Equal(t, ResourceCodeScanningUpload, InferResource(req), "context should override inference")
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r/future-endpoint", nil)
+	assert.Equal(t, ResourceCore, InferResource(req), "inference should be the fallback")
+}
+
+func TestInferResource_Enterprise(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://github.example.com/api/graphql", nil)
+	assert.Equal(t, ResourceGraphQL, InferResource(req))
+	req, _ = http.NewRequest(http.MethodGet, "https://github.example.com/api/v3/search/issues", nil)
+	assert.Equal(t, ResourceSearch, InferResource(req))
+}
+
+func TestInferResource_RunnerRegistration(t *testing.T) {
+	for _, path := range []string{"/repos/o/r/actions/runners/registration-token", "/orgs/o/actions/runners/registration-token", "/api/v3/enterprises/e/actions/runners/registration-token"} {
+		req, _ := http.NewRequest(http.MethodPost, "https://api.github.com"+path, nil)
+		assert.Equal(t, ResourceActionsRunnerRegistration, InferResource(req), path)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/orgs/o/actions/runners", nil)
+	assert.Equal(t, ResourceCore, InferResource(req))
+}
+
+func TestInferResource_Unknown(t *testing.T) {
+	assert.Empty(t, InferResource(nil))
+	assert.Empty(t, InferResource(&http.Request{Method: http.MethodGet}), "requests without a URL should be unknown")
+	assert.Empty(t, InferResource(&http.Request{Method: http.MethodGet, URL: &url.URL{}}), "requests without a path should be unknown")
+
+	var r resourceRegistry
+	r.register(ResourceSearch, MatchPathPrefix("/search/"))
+	assert.Empty(t, r.infer(&http.Request{Method: http.MethodGet}), "matchers should handle requests without a URL")
+
+	bt := &Balancer{Transports: []*Transport{withRemaining(100)}}
+	_, err := bt.RoundTrip(&http.Request{Method: http.MethodGet, URL: &url.URL{}})
+	assert.ErrorIs(t, err, ErrUnknownResource)
+	bt.UnknownResource = ResourceCore
+	_, err = bt.RoundTrip(&http.Request{Method: http.MethodGet, URL: &url.URL{}})
+	assert.NoError(t, err, "UnknownResource should be used instead of rejecting")
+}
@@ -0,0 +1,46 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"path"
+)
+
+// DefaultInvalidate returns the paths made stale by a write to req: its own path and its parent collection,
+// ex: PATCH /repos/{owner}/{repo}/issues/1 invalidates /repos/{owner}/{repo}/issues/1 and /repos/{owner}/{repo}/issues.
+func DefaultInvalidate(req *http.Request) []string {
+	if req.URL == nil || req.URL.Path == "" {
+		return nil
+	}
+	p := path.Clean(req.URL.Path)
+	if parent := path.Dir(p); parent != p && parent != "/" {
+		return []string{p, parent}
+	}
+	return []string{p}
+}
+
+// index records that the response for the request is cached under the key, so it can be invalidated by path.
+func (ct *CachingTransport) index(req *http.Request, key string) {
+	val, _ := ct.paths.LoadOrStore(req.URL.Path, new(syncMap))
+	val.(*syncMap).Store(key, struct{}{})
+}
+
+// invalidate deletes the cached responses made stale by a successful write, see Invalidate.
+func (ct *CachingTransport) invalidate(req *http.Request, resp *http.Response) {
+	if !isWrite(req) || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return
+	}
+	invalidate := ct.Invalidate
+	if invalidate == nil {
+		invalidate = DefaultInvalidate
+	}
+	for _, p := range invalidate(req) {
+		val, ok := ct.paths.LoadAndDelete(p)
+		if !ok {
+			continue
+		}
+		val.(*syncMap).Range(func(key, _ any) bool {
+			ct.Store.Delete(key.(string))
+			return true
+		})
+	}
+}
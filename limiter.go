@@ -0,0 +1,120 @@
+package ghratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limiter adapts the live rate limit of a resource type to the method set of golang.org/x/time/rate's *Limiter,
+// so code written against it can adopt GitHub-aware limiting without restructuring. Unlike a token bucket the limiter
+// does not consume tokens itself: they are consumed by the requests as observed from GitHub's responses.
+// If the rate limit is unknown, every event is allowed.
+type Limiter struct {
+	limits   *Limits
+	resource Resource
+}
+
+// Limiter returns a Limiter for the resource type backed by the Limits.
+func (l *Limits) Limiter(resource Resource) *Limiter {
+	return &Limiter{limits: l, resource: resource}
+}
+
+// Burst returns the limit of the current rate-limit window, the maximum number of events that can be reserved at once.
+// It returns 0 if the rate limit is unknown.
+func (lim *Limiter) Burst() int {
+	if rate := lim.limits.Load(lim.resource); rate != nil {
+		return int(min(rate.Limit, MaxRateValue))
+	}
+	return 0
+}
+
+// Tokens returns the number of requests remaining, or 0 if the rate limit is unknown.
+func (lim *Limiter) Tokens() float64 {
+	return lim.TokensAt(lim.limits.clock().Now())
+}
+
+// TokensAt returns the number of requests remaining at time t, the full limit if the window has reset by then.
+func (lim *Limiter) TokensAt(t time.Time) float64 {
+	rate := lim.limits.Load(lim.resource)
+	if rate == nil {
+		return 0
+	}
+	return float64(forecastRate(rate, windowLength(lim.resource), t).avail)
+}
+
+// Allow reports whether a request may be made now.
+func (lim *Limiter) Allow() bool {
+	return lim.AllowN(lim.limits.clock().Now(), 1)
+}
+
+// AllowN reports whether n requests may be made at time t.
+func (lim *Limiter) AllowN(t time.Time, n int) bool {
+	return n <= 0 || lim.limits.estimateWaitAt(lim.resource, uint64(n), t) == 0
+}
+
+// Wait blocks until a request may be made, see WaitN.
+func (lim *Limiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n requests may be made. It returns an error if n exceeds the Burst,
+// or if ctx is done (or its deadline is before the requests would be available).
+func (lim *Limiter) WaitN(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if burst := lim.Burst(); burst > 0 && n > burst {
+		return fmt.Errorf("ghratelimit: Wait(n=%d) exceeds limiter's burst %d", n, burst)
+	}
+	return lim.limits.waitFor(ctx, lim.resource, uint64(n))
+}
+
+// Reserve returns a Reservation indicating how long to wait before a request may be made, see ReserveN.
+func (lim *Limiter) Reserve() *Reservation {
+	return lim.ReserveN(lim.limits.clock().Now(), 1)
+}
+
+// ReserveN returns a Reservation indicating how long to wait from time t before n requests may be made.
+// The reservation is not OK if n exceeds the Burst.
+func (lim *Limiter) ReserveN(t time.Time, n int) *Reservation {
+	if burst := lim.Burst(); burst > 0 && n > burst {
+		return &Reservation{}
+	}
+	return &Reservation{ok: true, timeToAct: t.Add(lim.limits.estimateWaitAt(lim.resource, uint64(max(n, 0)), t)), clock: lim.limits.clock()}
+}
+
+// Reservation is the result of (*Limiter).Reserve, mirroring golang.org/x/time/rate's *Reservation.
+type Reservation struct {
+	ok        bool
+	timeToAct time.Time
+	clock     Clock
+}
+
+// OK reports whether the limiter can provide the requested number of requests,
+// if false Delay returns an infinite duration.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before making the requests, see DelayFrom.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return Never
+	}
+	return r.DelayFrom(r.clock.Now())
+}
+
+// DelayFrom returns how long after t to wait before making the requests, zero means act immediately.
+func (r *Reservation) DelayFrom(t time.Time) time.Duration {
+	if !r.ok {
+		return Never
+	}
+	return max(r.timeToAct.Sub(t), 0)
+}
+
+// Cancel is a no-op, as the limiter does not consume tokens itself.
+func (r *Reservation) Cancel() {}
+
+// CancelAt is a no-op, as the limiter does not consume tokens itself.
+func (r *Reservation) CancelAt(time.Time) {}
@@ -0,0 +1,42 @@
+package ghratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	var limits Limits
+	lim := limits.Limiter(ResourceCore)
+	assert.True(t, lim.Allow(), "unknown rate limits should allow every event")
+	assert.NoError(t, lim.Wait(context.Background()))
+
+	now := time.Now()
+	reset := now.Add(time.Minute).Truncate(time.Second)
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Used: 98, Remaining: 2, Reset: uint64(reset.Unix())})
+	assert.Equal(t, 100, lim.Burst())
+	assert.Equal(t, 2.0, lim.Tokens())
+	assert.Equal(t, 100.0, lim.TokensAt(reset.Add(time.Second)), "the window should have reset")
+	assert.True(t, lim.Allow())
+	assert.True(t, lim.AllowN(now, 2))
+	assert.False(t, lim.AllowN(now, 3))
+	assert.True(t, lim.AllowN(reset, 3), "should be allowed once the window resets")
+
+	r := lim.ReserveN(now, 3)
+	if assert.True(t, r.OK()) {
+		assert.Equal(t, reset.Sub(now), r.DelayFrom(now))
+		assert.Zero(t, r.DelayFrom(reset.Add(time.Second)))
+		r.Cancel()
+	}
+	r = lim.ReserveN(now, 101)
+	assert.False(t, r.OK(), "more than the burst should never be satisfied")
+	assert.Equal(t, Never, r.Delay())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, lim.WaitN(ctx, 3), context.DeadlineExceeded, "should not wait past the deadline")
+	assert.Error(t, lim.WaitN(context.Background(), 101))
+}
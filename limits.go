@@ -3,13 +3,14 @@ package ghratelimit
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
+	"sync/atomic"
 )
 
 // DefaultURL is the default URL used to poll rate limits.
@@ -20,23 +21,112 @@ var DefaultURL = &url.URL{
 	Path:   "/rate_limit",
 }
 
+// RateLimitURL returns the /rate_limit endpoint of the REST API at the given base URL.
+// For GitHub Enterprise Server the base URL is either the instance (ex: https://github.example.com, implying /api/v3)
+// or its API host when subdomain isolation is enabled (ex: https://api.github.example.com).
+// If base is nil, DefaultURL is returned.
+func RateLimitURL(base *url.URL) *url.URL {
+	if base == nil {
+		return DefaultURL
+	}
+	return apiURL(base, "rate_limit")
+}
+
+// apiURL returns the URL of the REST API endpoint at the given base URL, see RateLimitURL.
+func apiURL(base *url.URL, path string) *url.URL {
+	if base == nil {
+		base = &url.URL{Scheme: DefaultURL.Scheme, Host: DefaultURL.Host}
+	}
+	if strings.Trim(base.Path, "/") == "" && !strings.HasPrefix(base.Host, "api.") {
+		return base.JoinPath("/api/v3", path)
+	}
+	return base.JoinPath(path)
+}
+
 // Limits represents the rate limits for all known resource types.
 type Limits struct {
-	m sync.Map
+	m       syncMap
+	windows syncMap // Resource -> *window
+	etags   syncMap // URL -> ETag of the last /rate_limit response
+	fetches syncMap // URL -> *fetchCall
+
+	optimistic syncMap // Resource -> *optimistic
+	received   syncMap // Resource -> time.Time the rate limit was last stored
+	aborted    syncMap // Resource -> true if a request was aborted since the rate limit was last stored
+
+	deprecations     syncMap // "METHOD route" -> *deprecation
+	sharedProtocol   atomic.Pointer[SharedProtocol]
+	negotiation      negotiation
+	sharedKeyMissing atomic.Bool
+	deprecationCount atomic.Int64
 	// Notify is called when a new rate limit is stored.
 	// It can be a useful hook to update metric gauges.
 	Notify func(*http.Response, Resource, *Rate)
+	// NotifyResponse, if set, is called with the information parsed from every response by Parse,
+	// including those without rate-limit headers, ex: to log the X-GitHub-Request-Id of rate-limited responses.
+	NotifyResponse func(*ResponseInfo)
+	// OnFetchBody, if set, is called with the raw body of every successful /rate_limit response fetched by Fetch before it is parsed,
+	// ex: to capture resource types this package doesn't model yet. The body must not be modified.
+	OnFetchBody func([]byte)
+	// OnReset, if set, is called when a new rate-limit window of a resource type is observed (its reset moved forward, or its
+	// remaining requests returned to the limit), with the new rate limit and the requests used in the previous window as last
+	// observed, ex: to resume paused background queues as soon as quota is available.
+	OnReset func(resource Resource, rate *Rate, consumed uint64)
+	// OnEvent is called for notable events, such as a rate limit being clamped to a sane range,
+	// crossing one of the Thresholds, being exhausted or its window resetting.
+	OnEvent func(Event)
+	// Thresholds are fractions (0.0 to 1.0) of the rate limit, an EventThreshold is emitted
+	// when the remaining requests of a resource drop below one, ex: 0.1 to alert when a token is nearly exhausted.
+	Thresholds []float64
+	// Shared, if set, shares the rate limits with other processes using the same credential,
+	// so decisions are made from the collective remaining count rather than only this process's responses.
+	// Every Store is merged into it, and its rate limits are merged back into this process by Store and SyncShared,
+	// keeping whichever is fresher.
+	Shared SharedStore
+	// SharedKey identifies the credential in the SharedStore (ex: the GitHub App installation ID), it prefixes every key.
+	// It is required, Shared is ignored without it.
+	SharedKey string
+	// AcceptStale, if true, stores every update. Otherwise an update from a response that appears older than the stored
+	// rate limit (an earlier reset, or the same reset with more remaining) is ignored, as responses can arrive out of order.
+	AcceptStale bool
+	// PartialFetch, if true, stores the valid entries of a /rate_limit response even if others are malformed,
+	// returning an error wrapping ErrPartialFetch for the rest. Otherwise a single malformed entry fails the whole Fetch.
+	PartialFetch bool
+	// Clock is the source of time for reset calculations and waits, including those of the Transport owning the Limits.
+	// If nil, SystemClock is used.
+	Clock Clock
 }
 
 // Store the rate limit for the given resource type.
+// Absurd values (ex: a reset decades in the future) are clamped to a sane range first, emitting an EventClamped.
 func (l *Limits) Store(resp *http.Response, resource Resource, rate *Rate) {
-	l.m.Store(resource, rate)
+	now := l.clock().Now()
+	if clamped, reason := rate.clamp(now); clamped != rate {
+		l.emit(Event{Kind: EventClamped, Resource: resource, Rate: clamped, Message: reason})
+		rate = clamped
+	}
+	rate, prevRate, ok := l.swap(resp, resource, rate, now)
+	if !ok {
+		return
+	}
+	l.received.Store(resource, now)
+	l.aborted.Delete(resource)
+	l.observe(resource, rate)
+	l.transitions(resource, prevRate, rate)
+	if l.sharing() {
+		ctx, cancel := sharedContext(resp)
+		if shared := l.storeShared(ctx, resource, rate); shared != nil && shared != rate {
+			l.mergeLocal(resource, shared)
+		}
+		cancel()
+	}
 	if l.Notify != nil {
 		l.Notify(resp, resource, rate)
 	}
 }
 
 // Load the rate-limit for the given resource type.
+// It never blocks on the SharedStore, rate limits from other processes are merged in by Store and SyncShared.
 func (l *Limits) Load(resource Resource) *Rate {
 	val, ok := l.m.Load(resource)
 	if !ok {
@@ -44,28 +134,37 @@ func (l *Limits) Load(resource Resource) *Rate {
 	}
 	r, ok := val.(*Rate)
 	if !ok {
+		l.corrupt(resource, val)
 		return nil
 	}
 	return r
 }
 
 // Iter loops over the resource types and yields each resource type and its rate limit.
+// Entries of an unexpected type are skipped, emitting an EventCorruptEntry.
 func (l *Limits) Iter() iter.Seq2[Resource, *Rate] {
 	return func(yield func(Resource, *Rate) bool) {
 		l.m.Range(func(key, value any) bool {
 			resource, ok := key.(Resource)
 			if !ok {
-				return false
+				l.corrupt(key, value)
+				return true
 			}
 			rate, ok := value.(*Rate)
-			if !ok {
-				return false
+			if !ok || rate == nil {
+				l.corrupt(key, value)
+				return true
 			}
 			return yield(resource, rate)
 		})
 	}
 }
 
+// corrupt reports an entry of an unexpected type, which should never happen.
+func (l *Limits) corrupt(key, value any) {
+	l.emit(Event{Kind: EventCorruptEntry, Message: fmt.Sprintf("skipped entry %v of unexpected type %T: %T", key, key, value)})
+}
+
 // String implements fmt.Stringer
 func (l *Limits) String() string {
 	var sb strings.Builder
@@ -86,30 +185,74 @@ func (l *Limits) String() string {
 
 // Parse updates the rate limits based on the provided HTTP response.
 func (l *Limits) Parse(resp *http.Response) error {
-	resource := ParseResource(resp.Header)
-	if resource == "" {
-		return nil // possibly a error or an endpoint without a rate-limit
+	info, err := ParseResponseInfo(resp)
+	if info.Deprecation != nil && info.Deprecation.Path != "" {
+		l.deprecated(*info.Deprecation)
 	}
-	rate, err := ParseRate(resp.Header)
-	if err != nil {
-		return err
+	if info.Rate != nil {
+		l.Store(resp, info.Resource, info.Rate) // otherwise possibly a error or an endpoint without a rate-limit
 	}
-	l.Store(resp, resource, &rate)
-	return nil
+	if l.NotifyResponse != nil {
+		l.NotifyResponse(info)
+	}
+	return err
 }
 
 // Fetch the latest rate limits from the GitHub API and update the Limits instance.
 // If the provided URL is nil, it defaults to DefaultURL (https://api.github.com/rate_limit).
+// The request is conditional on the ETag of the previous response, if the limits are unchanged (304) nothing is updated.
+// Concurrent calls for the same URL share a single request, returning its result,
+// unless its context was done first, in which case they retry with their own.
+// The response is parsed leniently: resource types unknown to this package are stored as-is,
+// null entries are skipped and missing fields are treated as zero.
 func (l *Limits) Fetch(ctx context.Context, transport http.RoundTripper, u *url.URL) error {
 	if u == nil {
 		u = DefaultURL
 	}
+	key := u.String()
+	for {
+		call := &fetchCall{done: make(chan struct{}), err: errFetchAborted}
+		if val, loaded := l.fetches.LoadOrStore(key, call); loaded {
+			inflight := val.(*fetchCall)
+			select {
+			case <-inflight.done:
+				if ctx.Err() == nil && (errors.Is(inflight.err, context.Canceled) || errors.Is(inflight.err, context.DeadlineExceeded)) {
+					continue // the leader gave up, fetch again with this context
+				}
+				return inflight.err
+			case <-ctx.Done():
+				return &WaitError{Op: "concurrent fetch of " + key, Err: context.Cause(ctx)}
+			}
+		}
+		defer func() {
+			l.fetches.Delete(key)
+			close(call.done)
+		}()
+		call.err = l.fetch(ctx, transport, u)
+		return call.err
+	}
+}
+
+// errFetchAborted is returned to the calls waiting on a Fetch that panicked.
+var errFetchAborted = errors.New("concurrent fetch aborted")
+
+// fetchCall is an in-flight Fetch that concurrent calls for the same URL wait on.
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// fetch requests the /rate_limit endpoint at the URL, see Fetch.
+func (l *Limits) fetch(ctx context.Context, transport http.RoundTripper, u *url.URL) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
 	}
 	req.Header.Set("User-Agent", "github.com/bored-engineer/github-rate-limit-http-transport")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("X-GitHub-Api-Version", DefaultAPIVersion)
+	if etag, ok := l.etags.Load(u.String()); ok {
+		req.Header.Set("If-None-Match", etag.(string))
+	}
 
 	resp, err := transport.RoundTrip(req)
 	if err != nil {
@@ -125,21 +268,80 @@ func (l *Limits) Fetch(ctx context.Context, transport http.RoundTripper, u *url.
 		return fmt.Errorf("(*http.Response).Body.Close for %q failed: %w", u, err)
 	}
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("(*http.Response).StatusCode(%d) != 200 for %q: %s", resp.StatusCode, u, string(body))
 	}
+	if l.OnFetchBody != nil {
+		l.OnFetchBody(body)
+	}
 
 	var limits struct {
-		Resources map[Resource]Rate `json:"resources"`
+		Resources map[Resource]json.RawMessage `json:"resources"`
 	}
 
 	if err := json.Unmarshal(body, &limits); err != nil {
 		return fmt.Errorf("json.Unmarshal for %q failed: %w", u, err)
 	}
 
-	for resource, rate := range limits.Resources {
-		l.Store(resp, resource, &rate)
+	rates := make(map[Resource]*Rate, len(limits.Resources))
+	var errs []error
+	for resource, raw := range limits.Resources {
+		var rate *Rate
+		if err := json.Unmarshal(raw, &rate); err != nil {
+			errs = append(errs, fmt.Errorf("resource %q: %w", resource, err))
+			continue
+		}
+		if resource == "" || rate == nil {
+			continue
+		}
+		rates[resource] = rate
+	}
+	if len(errs) > 0 && !l.PartialFetch {
+		return fmt.Errorf("json.Unmarshal for %q failed: %w", u, errors.Join(errs...))
+	}
+
+	for resource, rate := range rates {
+		l.Store(resp, resource, rate)
 	}
+	if len(errs) > 0 {
+		// the ETag is not stored so the malformed entries are fetched again
+		return fmt.Errorf("%w for %q: %w", ErrPartialFetch, u, errors.Join(errs...))
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		l.etags.Store(u.String(), etag)
+	}
+
+	return nil
+}
+
+// ErrPartialFetch is wrapped by the error returned by (*Limits).Fetch when PartialFetch is set
+// and some entries of the /rate_limit response were malformed, the valid entries were stored.
+var ErrPartialFetch = errors.New("partial fetch")
 
+// Wait blocks until the given resource type has requests remaining, or until its rate-limit window resets.
+// It returns immediately if the rate limit for the resource type is unknown.
+// If ctx is done before then (or its deadline is before the reset), a *RateLimitError wrapping a *WaitError is returned.
+func (l *Limits) Wait(ctx context.Context, resource Resource) error {
+	return l.waitFor(ctx, resource, 1)
+}
+
+// waitFor blocks until the given resource type has at least cost requests remaining, or until its rate-limit window resets, see Wait.
+func (l *Limits) waitFor(ctx context.Context, resource Resource, cost uint64) error {
+	rate := l.Load(resource)
+	if rate == nil || rate.Remaining >= cost {
+		return nil
+	}
+	reset := rate.ResetTime()
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(reset) && reset.After(l.clock().Now()) {
+		return &RateLimitError{Resource: resource, Remaining: rate.Remaining, Reset: reset, Err: &WaitError{
+			Op: "rate limit reset", Resource: resource, Until: reset, Err: context.DeadlineExceeded,
+		}}
+	}
+	if err := sleepUntil(ctx, l.clock(), reset, "rate limit reset", resource); err != nil {
+		return &RateLimitError{Resource: resource, Remaining: rate.Remaining, Reset: reset, Err: err}
+	}
 	return nil
 }
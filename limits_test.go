@@ -1,10 +1,18 @@
 package ghratelimit
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"maps"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -156,3 +164,313 @@ func TestLimits_Parse(t *testing.T) {
 	})
 	assert.Error(t, err, "expected error, got nil")
 }
+
+func TestLimits_Wait(t *testing.T) {
+	var limits Limits
+	assert.NoError(t, limits.Wait(context.Background(), ResourceCore), "unknown resource should not block")
+
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 5000, Remaining: 0, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := limits.Wait(ctx, ResourceCore)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "exhausted resource should block until ctx is done")
+
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 5000, Remaining: 0, Reset: uint64(time.Now().Add(-time.Second).Unix())})
+	assert.NoError(t, limits.Wait(context.Background(), ResourceCore), "elapsed reset should not block")
+}
+
+func TestLimits_Fetch_ETag(t *testing.T) {
+	var requests []string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req.Header.Get("If-None-Match"))
+		if req.Header.Get("If-None-Match") == `"abc"` {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{`"abc"`}},
+			Body:       io.NopCloser(strings.NewReader(limitsResponse)),
+		}, nil
+	})
+
+	var limits Limits
+	assert.NoError(t, limits.Fetch(context.Background(), transport, nil), "(*Limits).Fetch failed")
+	assert.NotNil(t, limits.Load(ResourceCore))
+	assert.NoError(t, limits.Fetch(context.Background(), transport, nil), "(*Limits).Fetch should accept 304")
+	assert.Equal(t, []string{"", `"abc"`}, requests, "second fetch should be conditional")
+}
+
+func TestLimits_Thresholds(t *testing.T) {
+	var events []Event
+	limits := Limits{
+		Thresholds: []float64{0.5, 0.1},
+		OnEvent:    func(event Event) { events = append(events, event) },
+	}
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 60, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 40, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 30, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 0, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 0, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 99, Reset: 1745125212})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Remaining: 45, Reset: 1745125212})
+
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, fmt.Sprintf("%s %v", event.Kind, event.Threshold))
+	}
+	assert.Equal(t, []string{
+		"threshold 0.5",
+		"threshold 0.1",
+		"exhausted 0",
+		"reset 0",
+		"threshold 0.5",
+	}, kinds)
+}
+
+func TestLimits_OnReset(t *testing.T) {
+	type reset struct {
+		resource Resource
+		rate     Rate
+		consumed uint64
+	}
+	var resets []reset
+	limits := Limits{OnReset: func(resource Resource, rate *Rate, consumed uint64) {
+		resets = append(resets, reset{resource, *rate, consumed})
+	}}
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Used: 10, Remaining: 90, Reset: 1745121612})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Used: 100, Remaining: 0, Reset: 1745121612})
+	assert.Empty(t, resets, "the first observation is not a reset")
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Used: 1, Remaining: 99, Reset: 1745125212})
+	limits.Store(nil, ResourceCore, &Rate{Limit: 100, Used: 2, Remaining: 98, Reset: 1745125212})
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Used: 30, Remaining: 0, Reset: 1745121612})
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Used: 0, Remaining: 30, Reset: 1745121612})
+	assert.Equal(t, []reset{
+		{ResourceCore, Rate{Limit: 100, Used: 1, Remaining: 99, Reset: 1745125212}, 100},
+		{ResourceSearch, Rate{Limit: 30, Used: 0, Remaining: 30, Reset: 1745121612}, 30},
+	}, resets, "should detect the reset moving forward and the remaining returning to the limit")
+}
+
+func TestRateLimitURL(t *testing.T) {
+	for base, expected := range map[string]string{
+		"":                                   "https://api.github.com/rate_limit",
+		"https://github.example.com":         "https://github.example.com/api/v3/rate_limit",
+		"https://github.example.com/api/v3/": "https://github.example.com/api/v3/rate_limit",
+		"https://api.github.example.com":     "https://api.github.example.com/rate_limit",
+	} {
+		var u *url.URL
+		if base != "" {
+			u, _ = url.Parse(base)
+		}
+		assert.Equal(t, expected, RateLimitURL(u).String(), base)
+	}
+}
+
+func BenchmarkLimits_Parse(b *testing.B) {
+	var limits Limits
+	resp := &http.Response{Header: http.Header{
+		"X-Ratelimit-Resource":  []string{"core"},
+		"X-Ratelimit-Limit":     []string{"5000"},
+		"X-Ratelimit-Used":      []string{"1000"},
+		"X-Ratelimit-Remaining": []string{"4000"},
+		"X-Ratelimit-Reset":     []string{"1633036800"},
+	}}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := limits.Parse(resp); err != nil {
+			b.Fatalf("(*Limits).Parse failed: %v", err)
+		}
+	}
+}
+
+func TestLimits_Store_Stale(t *testing.T) {
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	resp := &http.Response{}
+	var limits Limits
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 10, Remaining: 4990, Reset: reset})
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 5, Remaining: 4995, Reset: reset})
+	assert.Equal(t, uint64(4990), limits.Load(ResourceCore).Remaining, "older response in the same window should be ignored")
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 4000, Remaining: 1000, Reset: reset - 3600})
+	assert.Equal(t, reset, limits.Load(ResourceCore).Reset, "response from an earlier window should be ignored")
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: reset + 3600})
+	assert.Equal(t, uint64(4999), limits.Load(ResourceCore).Remaining, "response from a later window should be stored")
+
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 0, Remaining: 5000, Reset: reset + 3600})
+	assert.Equal(t, uint64(5000), limits.Load(ResourceCore).Remaining, "updates without a response should always be stored")
+
+	limits.AcceptStale = true
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 4000, Remaining: 1000, Reset: reset})
+	assert.Equal(t, uint64(1000), limits.Load(ResourceCore).Remaining, "AcceptStale should store every update")
+}
+
+func TestLimits_Fetch_SingleFlight(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(limitsResponse))}, nil
+	})
+
+	var limits Limits
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, limits.Fetch(context.Background(), transport, nil))
+		}()
+	}
+	assert.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), requests.Load(), "concurrent fetches should share a single request")
+	assert.NotNil(t, limits.Load(ResourceCore))
+}
+
+func TestLimits_Fetch_LeaderCancelled(t *testing.T) {
+	var requests atomic.Int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if requests.Add(1) == 1 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(limitsResponse))}, nil
+	})
+
+	var limits Limits
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() { leader <- limits.Fetch(ctx, transport, nil) }()
+	assert.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+	waiter := make(chan error, 1)
+	go func() { waiter <- limits.Fetch(context.Background(), transport, nil) }()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-leader, context.Canceled)
+	assert.NoError(t, <-waiter, "the waiter should not fail with the leader's context error")
+	assert.Equal(t, int32(2), requests.Load())
+
+	panicking := roundTripperFunc(func(req *http.Request) (*http.Response, error) { panic("boom") })
+	assert.Panics(t, func() { _ = limits.Fetch(context.Background(), panicking, nil) })
+	assert.NoError(t, limits.Fetch(context.Background(), transport, nil), "a panic should not wedge the URL")
+}
+
+func TestTransport_Poll_Concurrent(t *testing.T) {
+	var requests atomic.Int32
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(limitsResponse))}, nil
+	})}
+
+	first, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.Poll(first, time.Hour, nil)
+	}()
+	assert.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	go transport.Poll(second, time.Hour, nil)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, int32(1), requests.Load(), "concurrent Poll should not start a second loop")
+
+	cancel()
+	<-done
+	assert.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second, time.Millisecond, "waiting Poll should take over")
+}
+
+func TestLimits_Iter_Corrupt(t *testing.T) {
+	var events []Event
+	limits := &Limits{OnEvent: func(e Event) { events = append(events, e) }}
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 4999})
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 29})
+	events = nil
+	limits.m.Store("graphql", &Rate{Limit: 5000})
+	limits.m.Store(ResourceCodeSearch, Rate{Limit: 10})
+	limits.m.Store(ResourceGraphQL, (*Rate)(nil))
+
+	seen := make(map[Resource]uint64)
+	for resource, rate := range limits.Iter() {
+		seen[resource] = rate.Remaining
+	}
+	assert.Equal(t, map[Resource]uint64{ResourceCore: 4999, ResourceSearch: 29}, seen, "corrupt entries should be skipped, not stop iteration")
+	assert.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, EventCorruptEntry, event.Kind)
+	}
+
+	events = nil
+	assert.Nil(t, limits.Load(ResourceCodeSearch))
+	assert.Len(t, events, 1, "corrupt entries should be reported by Load")
+}
+
+// fetchBody returns a RoundTripper responding to every request with the body.
+func fetchBody(body []byte) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+	})
+}
+
+func TestLimits_Fetch_Lenient(t *testing.T) {
+	var limits Limits
+	body := `{"resources":{"core":null,"future_resource":{"limit":10,"remaining":5,"reset":1700000000},"search":{"limit":30}},"rate":null}`
+	assert.NoError(t, limits.Fetch(context.Background(), fetchBody([]byte(body)), nil))
+	assert.Nil(t, limits.Load(ResourceCore), "null entries should be skipped")
+	assert.Equal(t, &Rate{Limit: 10, Remaining: 5, Reset: 1700000000}, limits.Load("future_resource"), "unknown resources should be stored")
+	assert.Equal(t, &Rate{Limit: 30}, limits.Load(ResourceSearch), "missing fields should be zero")
+
+	assert.NoError(t, limits.Fetch(context.Background(), fetchBody([]byte(`{"resources":null}`)), nil))
+	assert.NoError(t, limits.Fetch(context.Background(), fetchBody([]byte(`{}`)), nil))
+}
+
+func FuzzLimits_Fetch(f *testing.F) {
+	f.Add([]byte(limitsResponse))
+	f.Add([]byte(`{"resources":{"core":null}}`))
+	f.Add([]byte(`{"resources":{"core":{"limit":"5000","remaining":"4999","reset":"1700000000"}}}`))
+	f.Add([]byte(`{"resources":{"unknown":{"limit":1,"used":2,"remaining":3,"reset":4}}}`))
+	f.Add([]byte(`{"resources":{"core":{}}}`))
+	f.Add([]byte(`{"resources":{"":{"limit":1}}}`))
+	f.Add([]byte(`{"resources":[]}`))
+	f.Add([]byte(`null`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var limits Limits
+		if err := limits.Fetch(context.Background(), fetchBody(body), nil); err != nil {
+			return
+		}
+		for resource, rate := range limits.Iter() {
+			if resource == "" || rate == nil {
+				t.Fatalf("stored invalid entry %q: %v", resource, rate)
+			}
+			if rate.Remaining > rate.Limit || rate.Limit > MaxRateValue {
+				t.Fatalf("stored unclamped rate for %q: %v", resource, rate)
+			}
+		}
+	})
+}
+
+func TestLimits_Fetch_Partial(t *testing.T) {
+	body := []byte(`{"resources":{"core":{"limit":5000,"remaining":4000},"search":{"limit":"lots"}}}`)
+	var limits Limits
+	err := limits.Fetch(context.Background(), fetchBody(body), nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrPartialFetch)
+	assert.Nil(t, limits.Load(ResourceCore), "without PartialFetch nothing should be stored")
+
+	limits.PartialFetch = true
+	err = limits.Fetch(context.Background(), fetchBody(body), nil)
+	assert.ErrorIs(t, err, ErrPartialFetch)
+	assert.ErrorContains(t, err, `resource "search"`)
+	assert.Equal(t, &Rate{Limit: 5000, Remaining: 4000}, limits.Load(ResourceCore), "valid entries should be stored")
+	assert.Nil(t, limits.Load(ResourceSearch))
+}
+
+func TestLimits_OnFetchBody(t *testing.T) {
+	var bodies []string
+	limits := Limits{OnFetchBody: func(body []byte) { bodies = append(bodies, string(body)) }}
+	assert.NoError(t, limits.Fetch(context.Background(), fetchBody([]byte(limitsResponse)), nil))
+	assert.Error(t, limits.Fetch(context.Background(), fetchBody([]byte(`not json`)), nil))
+	assert.Equal(t, []string{limitsResponse, `not json`}, bodies, "every body should be passed before parsing")
+}
@@ -0,0 +1,35 @@
+package ghratelimit
+
+import "net/http"
+
+// RateAwareTransport is the minimal contract a member of a Balancer must fulfil: sending requests and reporting
+// its rate limits and health. *Transport implements it. Other implementations (ex: backed by a remote quota broker) join
+// a pool as the Member of a Transport, which keeps applying the pool's local policies (pacing, concurrency, etc.) to them.
+type RateAwareTransport interface {
+	http.RoundTripper
+	// RateLimit returns the current rate limit of the resource type, or nil if it is unknown.
+	RateLimit(resource Resource) *Rate
+	// Healthy reports if the member may be selected.
+	Healthy() bool
+}
+
+// RateLimit implements RateAwareTransport, see (*Limits).Load.
+// If the Member is set, its current rate limit is observed first.
+func (t *Transport) RateLimit(resource Resource) *Rate {
+	t.observeMember(resource)
+	return t.Limits.Load(resource)
+}
+
+// observeMember copies the rate limit of the resource type reported by the Member (if any) into the Limits.
+func (t *Transport) observeMember(resource Resource) {
+	if t.Member == nil {
+		return
+	}
+	rate := t.Member.RateLimit(resource)
+	if rate == nil {
+		return
+	}
+	if current := t.Limits.Load(resource); current == nil || *current != *rate {
+		t.Limits.Store(nil, resource, rate)
+	}
+}
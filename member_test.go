@@ -0,0 +1,65 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// broker is a RateAwareTransport backed by a (simulated) remote quota broker.
+type broker struct {
+	remaining atomic.Uint64
+	unhealthy atomic.Bool
+	sent      atomic.Int64
+}
+
+func (b *broker) RoundTrip(req *http.Request) (*http.Response, error) {
+	b.sent.Add(1)
+	return okResponse().RoundTrip(req)
+}
+
+func (b *broker) RateLimit(resource Resource) *Rate {
+	remaining := b.remaining.Load()
+	return &Rate{Limit: 5000, Remaining: remaining, Used: 5000 - remaining, Reset: uint64(time.Now().Add(time.Hour).Unix())}
+}
+
+func (b *broker) Healthy() bool {
+	return !b.unhealthy.Load()
+}
+
+func TestTransport_Member(t *testing.T) {
+	var _ RateAwareTransport = (*Transport)(nil)
+	low, high := &broker{}, &broker{}
+	low.remaining.Store(10)
+	high.remaining.Store(1000)
+	a, b := &Transport{Member: low}, &Transport{Member: high}
+	bt := &Balancer{Transports: []*Transport{a, b}}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := bt.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(1), high.sent.Load(), "the member with the most remaining should be selected")
+	assert.Equal(t, uint64(1000), b.RateLimit(ResourceCore).Remaining, "the member's rate limit should be observed")
+
+	high.unhealthy.Store(true)
+	assert.False(t, b.Healthy())
+	resp, err = bt.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(1), low.sent.Load(), "unhealthy members should not be selected")
+
+	high.unhealthy.Store(false)
+	high.remaining.Store(0)
+	low.remaining.Store(500)
+	resp, err = bt.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(2), low.sent.Load(), "changes reported by the members should be observed")
+}
@@ -0,0 +1,61 @@
+package ghratelimit
+
+import (
+	"net/http"
+)
+
+// FromResponse parses the rate-limit resource and rate from the headers of a GitHub response,
+// ex: from the *github.Response of go-github. If the response has no rate limit, the resource is empty and the rate is nil.
+func FromResponse(resp *http.Response) (Resource, *Rate, error) {
+	resource := ParseResource(resp.Header)
+	if resource == "" {
+		return "", nil, nil
+	}
+	rate, err := ParseRate(resp.Header)
+	if err != nil {
+		return resource, nil, err
+	}
+	return resource, &rate, nil
+}
+
+// Wrap returns a http.Handler that updates the limits from the rate-limit headers written by next,
+// ex: a httputil.ReverseProxy in front of the GitHub API. Headers that cannot be parsed are ignored.
+func (l *Limits) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&observingWriter{ResponseWriter: w, limits: l, req: r}, r)
+	})
+}
+
+// observingWriter parses the rate-limit headers when the wrapped handler writes the response header.
+type observingWriter struct {
+	http.ResponseWriter
+	limits   *Limits
+	req      *http.Request
+	observed bool
+}
+
+// observe updates the limits from the response headers, once.
+func (ow *observingWriter) observe(code int) {
+	if ow.observed {
+		return
+	}
+	ow.observed = true
+	_ = ow.limits.Parse(&http.Response{StatusCode: code, Header: ow.Header(), Request: ow.req})
+}
+
+// WriteHeader implements http.ResponseWriter
+func (ow *observingWriter) WriteHeader(code int) {
+	ow.observe(code)
+	ow.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (ow *observingWriter) Write(b []byte) (int, error) {
+	ow.observe(http.StatusOK)
+	return ow.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to access the underlying http.ResponseWriter, ex: to flush.
+func (ow *observingWriter) Unwrap() http.ResponseWriter {
+	return ow.ResponseWriter
+}
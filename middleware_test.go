@@ -0,0 +1,43 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromResponse(t *testing.T) {
+	resource, rate, err := FromResponse(&http.Response{Header: http.Header{
+		"X-Ratelimit-Resource":  []string{"core"},
+		"X-Ratelimit-Limit":     []string{"5000"},
+		"X-Ratelimit-Used":      []string{"1"},
+		"X-Ratelimit-Remaining": []string{"4999"},
+		"X-Ratelimit-Reset":     []string{"1745121612"},
+	}})
+	assert.NoError(t, err, "FromResponse failed")
+	assert.Equal(t, ResourceCore, resource)
+	assert.Equal(t, &Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: 1745121612}, rate)
+
+	resource, rate, err = FromResponse(&http.Response{Header: http.Header{}})
+	assert.NoError(t, err, "responses without a rate limit should not fail")
+	assert.Empty(t, resource)
+	assert.Nil(t, rate)
+}
+
+func TestLimits_Wrap(t *testing.T) {
+	var limits Limits
+	h := limits.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Resource", "search")
+		w.Header().Set("X-Ratelimit-Limit", "30")
+		w.Header().Set("X-Ratelimit-Used", "2")
+		w.Header().Set("X-Ratelimit-Remaining", "28")
+		w.Header().Set("X-Ratelimit-Reset", "1745118072")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/issues?q=foo", nil))
+	assert.Equal(t, "{}", rec.Body.String())
+	assert.Equal(t, &Rate{Limit: 30, Used: 2, Remaining: 28, Reset: 1745118072}, limits.Load(ResourceSearch))
+}
@@ -0,0 +1,100 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMirrorInFlight is the default maximum number of requests a MirrorTransport mirrors at once.
+const DefaultMirrorInFlight = 16
+
+// mirrorTimeout bounds each mirrored request, as it does not inherit the cancellation of the original request.
+const mirrorTimeout = time.Minute
+
+// ErrMirrorSaturated is passed to OnMirror for sampled requests that were not mirrored because MaxInFlight was reached.
+var ErrMirrorSaturated = errors.New("too many mirrored requests in flight")
+
+// MirrorTransport serves every request using Base and additionally mirrors a sample of read requests to Mirror.
+// Responses from Mirror are discarded, it is intended to validate a canary credential's scopes and rate-limits
+// (typically via its own *Transport) before promoting it into a Balancer.
+// Requests are mirrored in the background, except in ghratelimit_minimal builds where they are mirrored
+// after the original request is served, before RoundTrip returns.
+type MirrorTransport struct {
+	// Base is the RoundTripper used to serve requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Mirror is the RoundTripper that sampled read requests are copied to.
+	// If nil, no requests are mirrored.
+	Mirror http.RoundTripper
+	// Sample is the fraction (0.0 to 1.0) of read requests that are mirrored.
+	Sample float64
+	// MaxInFlight is the maximum number of requests mirrored at once, sampled requests beyond it are skipped.
+	// If zero, DefaultMirrorInFlight is used.
+	MaxInFlight int
+	// OnMirror is called with the outcome of each mirrored request, if set.
+	// The response body has already been consumed and closed when it is called.
+	OnMirror func(*http.Request, *http.Response, error)
+
+	inflight atomic.Int64
+}
+
+// mirrorable reports if the request is a read request that is safe to send twice.
+func mirrorable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// mirror sends a copy of the request to the Mirror RoundTripper, discarding the response.
+// The copy keeps the values of the request's context but not its cancellation, it is bounded by mirrorTimeout instead.
+func (mt *MirrorTransport) mirror(req *http.Request) {
+	defer mt.inflight.Add(-1)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), mirrorTimeout)
+	defer cancel()
+	req = req.Clone(ctx)
+	resp, err := mt.Mirror.RoundTrip(req)
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	if mt.OnMirror != nil {
+		mt.OnMirror(req, resp, err)
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (mt *MirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sampled := mt.Mirror != nil && mt.Sample > 0 && mirrorable(req) && rand.Float64() < mt.Sample
+	if sampled {
+		limit := mt.MaxInFlight
+		if limit <= 0 {
+			limit = DefaultMirrorInFlight
+		}
+		if mt.inflight.Add(1) > int64(limit) {
+			mt.inflight.Add(-1)
+			sampled = false
+			if mt.OnMirror != nil {
+				mt.OnMirror(req, nil, ErrMirrorSaturated)
+			}
+		} else if !minimal {
+			// the mirrored request outlives the original request
+			go mt.mirror(req)
+			sampled = false
+		}
+	}
+	base := mt.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if sampled {
+		mt.mirror(req) // no background goroutines in minimal builds, mirror once the request is served
+	}
+	return resp, err
+}
@@ -0,0 +1,100 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripperFunc adapts a function into a http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// okResponse returns a RoundTripper that always responds with an empty 200 OK.
+func okResponse() http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestMirrorTransport(t *testing.T) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var mirrored []string
+	mt := &MirrorTransport{
+		Base:   okResponse(),
+		Mirror: okResponse(),
+		Sample: 1,
+		OnMirror: func(req *http.Request, resp *http.Response, err error) {
+			defer wg.Done()
+			assert.NoError(t, err, "mirror failed")
+			mu.Lock()
+			mirrored = append(mirrored, req.Method+" "+req.URL.Path)
+			mu.Unlock()
+		},
+	}
+
+	wg.Add(1)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := mt.RoundTrip(req)
+	assert.NoError(t, err, "(*MirrorTransport).RoundTrip failed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/user/repos", strings.NewReader("{}"))
+	_, err = mt.RoundTrip(req)
+	assert.NoError(t, err, "(*MirrorTransport).RoundTrip failed")
+
+	wg.Wait()
+	assert.Equal(t, []string{"GET /users/bored-engineer"}, mirrored, "only reads should be mirrored")
+}
+
+func TestMirrorTransport_MaxInFlight(t *testing.T) {
+	if minimal {
+		t.Skip("requests are mirrored synchronously in minimal builds")
+	}
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var errs []error
+	mt := &MirrorTransport{
+		Base: okResponse(),
+		Mirror: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			<-release
+			return okResponse().RoundTrip(req)
+		}),
+		Sample:      1,
+		MaxInFlight: 1,
+		OnMirror: func(req *http.Request, resp *http.Response, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	}
+	for range 2 {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		_, err := mt.RoundTrip(req)
+		assert.NoError(t, err)
+	}
+	mu.Lock()
+	assert.Equal(t, []error{ErrMirrorSaturated}, errs, "requests beyond MaxInFlight should not be mirrored")
+	mu.Unlock()
+	close(release)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) == 2
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return mt.inflight.Load() == 0 }, time.Second, time.Millisecond)
+}
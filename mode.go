@@ -0,0 +1,11 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import "sync"
+
+// minimal reports if the package was built with the ghratelimit_minimal tag, see mode_minimal.go.
+const minimal = false
+
+// syncMap is the concurrent map used for the package's state, a sync.Map unless built with the ghratelimit_minimal tag.
+type syncMap = sync.Map
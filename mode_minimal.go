@@ -0,0 +1,110 @@
+//go:build ghratelimit_minimal
+
+// Building with the ghratelimit_minimal tag (ex: for WASM or TinyGo) reduces the package to what constrained environments
+// support, while keeping header parsing, Rate math and the fail-fast logic of Transport and Balancer:
+//   - nothing depends on the os package: DiskCacheStore, FileCursorStore and ToggleDebugOnSignal are omitted,
+//     (*Limits).SaveFile and (*Limits).LoadFile (and so StateFile) fail with errors.ErrUnsupported
+//   - no goroutines outlive the call that started them: StaleWhileRevalidate revalidates before serving
+//     and MirrorTransport mirrors before returning instead (calls such as (*Balancer).Fetch and Replay still
+//     use goroutines internally, but wait for them)
+//   - state is kept in mutex guarded maps instead of sync.Map
+//   - ProfilerLabels is ignored, as runtime/pprof is unavailable
+
+package ghratelimit
+
+import "sync"
+
+// minimal reports if the package was built with the ghratelimit_minimal tag.
+const minimal = true
+
+// syncMap is a mutex guarded map with the methods of sync.Map used by the package,
+// for environments where sync.Map is unavailable or expensive (ex: TinyGo).
+type syncMap struct {
+	mu sync.Mutex
+	m  map[any]any
+}
+
+// Load mirrors (*sync.Map).Load
+func (m *syncMap) Load(key any) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.m[key]
+	return val, ok
+}
+
+// Store mirrors (*sync.Map).Store
+func (m *syncMap) Store(key, val any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.m == nil {
+		m.m = make(map[any]any)
+	}
+	m.m[key] = val
+}
+
+// LoadOrStore mirrors (*sync.Map).LoadOrStore
+func (m *syncMap) LoadOrStore(key, val any) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if actual, ok := m.m[key]; ok {
+		return actual, true
+	}
+	if m.m == nil {
+		m.m = make(map[any]any)
+	}
+	m.m[key] = val
+	return val, false
+}
+
+// LoadAndDelete mirrors (*sync.Map).LoadAndDelete
+func (m *syncMap) LoadAndDelete(key any) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	val, ok := m.m[key]
+	delete(m.m, key)
+	return val, ok
+}
+
+// Delete mirrors (*sync.Map).Delete
+func (m *syncMap) Delete(key any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, key)
+}
+
+// CompareAndSwap mirrors (*sync.Map).CompareAndSwap
+func (m *syncMap) CompareAndSwap(key, old, new any) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if val, ok := m.m[key]; !ok || val != old {
+		return false
+	}
+	m.m[key] = new
+	return true
+}
+
+// CompareAndDelete mirrors (*sync.Map).CompareAndDelete
+func (m *syncMap) CompareAndDelete(key, old any) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if val, ok := m.m[key]; !ok || val != old {
+		return false
+	}
+	delete(m.m, key)
+	return true
+}
+
+// Range mirrors (*sync.Map).Range, f is called without the lock held so it may modify the map.
+func (m *syncMap) Range(f func(key, val any) bool) {
+	m.mu.Lock()
+	keys := make([]any, 0, len(m.m))
+	for key := range m.m {
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+	for _, key := range keys {
+		if val, ok := m.Load(key); ok && !f(key, val) {
+			return
+		}
+	}
+}
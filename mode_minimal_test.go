@@ -0,0 +1,55 @@
+//go:build ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimal(t *testing.T) {
+	var limits Limits
+	assert.ErrorIs(t, limits.SaveFile("state.json"), errors.ErrUnsupported)
+	assert.ErrorIs(t, limits.LoadFile("state.json"), errors.ErrUnsupported)
+
+	var m syncMap
+	val, loaded := m.LoadOrStore("a", 1)
+	assert.False(t, loaded)
+	assert.Equal(t, 1, val)
+	assert.True(t, m.CompareAndSwap("a", 1, 2))
+	m.Store("b", 3)
+	var sum int
+	m.Range(func(key, val any) bool {
+		m.Delete(key) // the map may be modified while ranging
+		sum += val.(int)
+		return true
+	})
+	assert.Equal(t, 5, sum)
+	_, ok := m.Load("a")
+	assert.False(t, ok)
+
+	transport := withRemaining(0)
+	transport.ProfilerLabels = true
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := (&Balancer{Transports: []*Transport{transport}}).RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+}
+
+func TestMinimal_MirrorTransport(t *testing.T) {
+	var mirrored int
+	mt := &MirrorTransport{
+		Base:     okResponse(),
+		Mirror:   okResponse(),
+		Sample:   1,
+		OnMirror: func(*http.Request, *http.Response, error) { mirrored++ },
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := mt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mirrored, "the request should be mirrored before RoundTrip returns")
+}
@@ -0,0 +1,49 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// host returns the lower-cased host the transport is bound to, the host of its BaseURL (api.github.com by default).
+func (t *Transport) host() string {
+	base := DefaultURL
+	if t.BaseURL != nil {
+		base = t.BaseURL
+	}
+	return strings.ToLower(base.Hostname())
+}
+
+// serves reports if the transport is bound to the host of the request, see MultiHost.
+// Requests without a host can be served by every transport, requests to the UntrackedHosts only by github.com transports.
+func (t *Transport) serves(req *http.Request) bool {
+	if req.URL == nil {
+		return true
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	switch {
+	case host == "":
+		return true
+	case slices.Contains(UntrackedHosts, host):
+		return t.host() == DefaultURL.Hostname()
+	default:
+		return host == t.host()
+	}
+}
+
+// rebase returns a copy of a host-agnostic request (one without a host, ex: "/repos/o/r") with its URL resolved against
+// the transport's BaseURL, see MultiHost. Requests with a host are returned as-is.
+func (t *Transport) rebase(req *http.Request) *http.Request {
+	if req.URL == nil || req.URL.Host != "" {
+		return req
+	}
+	u := apiURL(t.BaseURL, req.URL.Path)
+	if prefix, ok := strings.CutSuffix(u.Path, "api/v3/graphql"); ok && req.URL.Path == "/graphql" {
+		u.Path = prefix + "api/graphql" // GitHub Enterprise Server serves GraphQL outside of the REST API prefix
+	}
+	u.RawQuery = req.URL.RawQuery
+	req = req.Clone(req.Context())
+	req.URL, req.Host = u, ""
+	return req
+}
@@ -0,0 +1,54 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_MultiHost(t *testing.T) {
+	bound := func(base string, remaining uint64) (*Transport, *[]string) {
+		var urls []string
+		transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			urls = append(urls, req.URL.String())
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+		})}
+		if base != "" {
+			transport.BaseURL, _ = url.Parse(base)
+		}
+		transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: remaining})
+		transport.Limits.Store(nil, ResourceGraphQL, &Rate{Limit: 5000, Remaining: remaining})
+		return transport, &urls
+	}
+	dotcom, dotcomURLs := bound("", 100)
+	ghes, ghesURLs := bound("https://github.example.com", 4000)
+	bt := &Balancer{Transports: []*Transport{dotcom, ghes}, MultiHost: true}
+
+	send := func(method, rawURL string) {
+		req, _ := http.NewRequest(method, rawURL, nil)
+		resp, err := bt.RoundTrip(req)
+		if assert.NoError(t, err) {
+			discard(resp)
+		}
+	}
+	send(http.MethodGet, "https://api.github.com/repos/o/r")
+	assert.Equal(t, []string{"https://api.github.com/repos/o/r"}, *dotcomURLs, "should be routed to the transport bound to the host despite fewer remaining")
+	send(http.MethodGet, "https://raw.githubusercontent.com/o/r/main/README.md")
+	assert.Len(t, *dotcomURLs, 2, "untracked github.com hosts should be routed to github.com transports")
+	send(http.MethodGet, "https://github.example.com/api/v3/repos/o/r")
+	assert.Equal(t, []string{"https://github.example.com/api/v3/repos/o/r"}, *ghesURLs)
+
+	send(http.MethodGet, "/repos/o/r?per_page=1")
+	send(http.MethodPost, "/graphql")
+	assert.Equal(t, []string{
+		"https://github.example.com/api/v3/repos/o/r",
+		"https://github.example.com/api/v3/repos/o/r?per_page=1",
+		"https://github.example.com/api/graphql",
+	}, *ghesURLs, "host-agnostic requests should be rewritten to the selected transport's host")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://github.other.com/api/v3/repos/o/r", nil)
+	_, err := bt.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrNoTransports, "requests to hosts without a transport should be rejected")
+}
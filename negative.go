@@ -0,0 +1,49 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"time"
+)
+
+// NegativeRule allows a CachingTransport to cache 404 Not Found responses to matching requests, see NegativeCache.
+type NegativeRule struct {
+	// Match reports if the rule applies to the request, ex: MatchPathPrefix("/users/").
+	Match Matcher
+	// TTL is how long a 404 Not Found response is served from the cache, it should be short (ex: a minute)
+	// as GitHub cannot report when the resource is created.
+	TTL time.Duration
+}
+
+// negativeTTL returns how long a 404 Not Found response to the request may be cached, zero if it may not.
+func (ct *CachingTransport) negativeTTL(req *http.Request) time.Duration {
+	for _, rule := range ct.NegativeCache {
+		if rule.Match(req) {
+			return rule.TTL
+		}
+	}
+	return 0
+}
+
+// serveNegative reports if the cached 404 Not Found response may be served without asking GitHub.
+func (ct *CachingTransport) serveNegative(req *http.Request, cached *CachedResponse) bool {
+	if CacheBypassFromContext(req.Context()) {
+		return false
+	}
+	return time.Since(cached.Stored) < ct.negativeTTL(req)
+}
+
+// storeNegative caches the 404 Not Found response, replacing its body with a copy.
+func (ct *CachingTransport) storeNegative(req *http.Request, key string, resp *http.Response) error {
+	body, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+	ct.index(req, key)
+	ct.Store.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Stored:     time.Now(),
+	})
+	return nil
+}
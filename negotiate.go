@@ -0,0 +1,121 @@
+package ghratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SharedProtocolVersion is the version of the protocol between Limits and its SharedStore implemented by this package.
+const SharedProtocolVersion = 1
+
+// SharedCapability is an optional feature of the protocol between Limits and its SharedStore.
+// Capabilities are negotiated (see SharedNegotiator), so processes and stores of different versions interoperate,
+// ex: while a fleet is mid-deploy, with each side only using the features both support.
+type SharedCapability string
+
+const (
+	// SharedCapabilityMerge is the ability of the store to merge a rate limit atomically, see SharedMerger.
+	SharedCapabilityMerge SharedCapability = "merge"
+)
+
+// sharedCapabilities are the capabilities offered by this package.
+var sharedCapabilities = []SharedCapability{SharedCapabilityMerge}
+
+// SharedProtocol is a protocol version and set of capabilities, offered by Limits or negotiated with its SharedStore.
+type SharedProtocol struct {
+	// Version is the protocol version.
+	Version int `json:"version"`
+	// Capabilities are the optional features.
+	Capabilities []SharedCapability `json:"capabilities,omitempty"`
+}
+
+// Has reports if the protocol includes the capability.
+func (p *SharedProtocol) Has(capability SharedCapability) bool {
+	return p != nil && slices.Contains(p.Capabilities, capability)
+}
+
+// SharedNegotiator is implemented by a SharedStore supporting version negotiation (ex: a quota broker).
+// Negotiate is called with the protocol offered by Limits before it first uses the store, and returns the version and
+// the capabilities both sides support. Stores that do not implement it are used with SharedProtocolVersion 1 and no capabilities.
+type SharedNegotiator interface {
+	Negotiate(ctx context.Context, offer SharedProtocol) (SharedProtocol, error)
+}
+
+// SharedMerger is implemented by a SharedStore that negotiates SharedCapabilityMerge: Merge stores the JSON encoded Rate
+// for the key unless the stored one is fresher (a later Reset, or the same Reset with fewer Remaining), atomically,
+// so concurrent writers never overwrite a fresher value. Without it Limits reads, merges then writes the value.
+type SharedMerger interface {
+	Merge(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// maxNegotiateBackoff is the longest a failed negotiation backs off before it is retried.
+const maxNegotiateBackoff = 5 * time.Minute
+
+// negotiation is the state of the negotiation with the SharedStore of a Limits.
+type negotiation struct {
+	mu       sync.Mutex
+	failures int
+	retryAt  time.Time
+}
+
+// negotiate returns the protocol negotiated with the SharedStore, negotiating it the first time it is used.
+// Negotiate gets its own SharedTimeout, detached from the caller's cancellation. Until it succeeds, version 1 without
+// capabilities is used: a failure is reported once and retried after a backoff, doubling up to maxNegotiateBackoff,
+// and callers do not wait on a negotiation already in progress.
+func (l *Limits) negotiate(ctx context.Context) *SharedProtocol {
+	if protocol := l.sharedProtocol.Load(); protocol != nil {
+		return protocol
+	}
+	baseline := &SharedProtocol{Version: 1}
+	negotiator, ok := l.Shared.(SharedNegotiator)
+	if !ok {
+		l.sharedProtocol.CompareAndSwap(nil, baseline)
+		return l.sharedProtocol.Load()
+	}
+	n := &l.negotiation
+	if !n.mu.TryLock() {
+		return baseline
+	}
+	defer n.mu.Unlock()
+	if protocol := l.sharedProtocol.Load(); protocol != nil {
+		return protocol
+	}
+	now := l.clock().Now()
+	if now.Before(n.retryAt) {
+		return baseline
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SharedTimeout)
+	defer cancel()
+	offer := SharedProtocol{Version: SharedProtocolVersion, Capabilities: slices.Clone(sharedCapabilities)}
+	reply, err := negotiator.Negotiate(ctx, offer)
+	if err != nil {
+		n.failures++
+		n.retryAt = now.Add(min(time.Second<<min(n.failures-1, 30), maxNegotiateBackoff))
+		if n.failures == 1 {
+			l.emit(Event{Kind: EventSharedError, Message: "negotiation failed: " + err.Error()})
+		}
+		return baseline
+	}
+	n.failures, n.retryAt = 0, time.Time{}
+	// never use more than was offered, even if the store replies with more
+	protocol := &SharedProtocol{Version: min(reply.Version, SharedProtocolVersion)}
+	for _, capability := range reply.Capabilities {
+		if offer.Has(capability) && !protocol.Has(capability) {
+			protocol.Capabilities = append(protocol.Capabilities, capability)
+		}
+	}
+	l.sharedProtocol.Store(protocol)
+	return protocol
+}
+
+// SharedProtocol returns the protocol negotiated with the SharedStore, ex: to debug a fleet running mixed versions.
+// It returns nil if Shared is not set or has not been used yet.
+func (l *Limits) SharedProtocol() *SharedProtocol {
+	protocol := l.sharedProtocol.Load()
+	if protocol == nil {
+		return nil
+	}
+	return &SharedProtocol{Version: protocol.Version, Capabilities: slices.Clone(protocol.Capabilities)}
+}
@@ -0,0 +1,109 @@
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// negotiatingSharedStore is a memorySharedStore supporting negotiation and atomic merges.
+type negotiatingSharedStore struct {
+	memorySharedStore
+	reply  SharedProtocol
+	err    error
+	offers []SharedProtocol
+	merges int
+}
+
+func (s *negotiatingSharedStore) Negotiate(ctx context.Context, offer SharedProtocol) (SharedProtocol, error) {
+	s.offers = append(s.offers, offer)
+	if err := ctx.Err(); err != nil {
+		return SharedProtocol{}, err
+	}
+	return s.reply, s.err
+}
+
+func (s *negotiatingSharedStore) Merge(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.merges++
+	var rate Rate
+	if err := json.Unmarshal(value, &rate); err != nil {
+		return err
+	}
+	if b, ok, _ := s.Get(ctx, key); ok {
+		var stored Rate
+		if err := json.Unmarshal(b, &stored); err == nil && fresher(&rate, &stored) == &stored {
+			return nil
+		}
+	}
+	return s.Set(ctx, key, value, ttl)
+}
+
+func TestLimits_SharedProtocol(t *testing.T) {
+	t.Run("Negotiated", func(t *testing.T) {
+		// a newer store offering a later version and unknown capabilities
+		store := &negotiatingSharedStore{reply: SharedProtocol{Version: SharedProtocolVersion + 1, Capabilities: []SharedCapability{"future", SharedCapabilityMerge}}}
+		transport := &Transport{Limits: Limits{Shared: store, SharedKey: "app"}}
+		limits := &transport.Limits
+		assert.Nil(t, limits.SharedProtocol(), "should not negotiate before use")
+		assert.Nil(t, transport.status(0).Shared)
+
+		limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+		limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 50, Remaining: 4950, Reset: 1745121612})
+		assert.Equal(t, &SharedProtocol{Version: SharedProtocolVersion, Capabilities: []SharedCapability{SharedCapabilityMerge}}, limits.SharedProtocol())
+		assert.Len(t, store.offers, 1, "should negotiate once")
+		assert.Equal(t, SharedProtocol{Version: SharedProtocolVersion, Capabilities: sharedCapabilities}, store.offers[0])
+		assert.Equal(t, 2, store.merges, "should merge atomically")
+		assert.NoError(t, limits.SyncShared(t.Context()))
+		assert.Equal(t, uint64(4900), limits.Load(ResourceCore).Remaining, "stale update should not win")
+
+		assert.Equal(t, limits.SharedProtocol(), transport.status(0).Shared)
+	})
+	t.Run("Older", func(t *testing.T) {
+		// an older store without capabilities
+		store := &negotiatingSharedStore{reply: SharedProtocol{Version: 1}}
+		limits := &Limits{Shared: store, SharedKey: "app"}
+		limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+		assert.Equal(t, &SharedProtocol{Version: 1}, limits.SharedProtocol())
+		assert.Zero(t, store.merges, "should not merge without the capability")
+		assert.Equal(t, uint64(4900), limits.Load(ResourceCore).Remaining)
+	})
+	t.Run("Unsupported", func(t *testing.T) {
+		limits := &Limits{Shared: &memorySharedStore{}, SharedKey: "app"}
+		assert.NoError(t, limits.SyncShared(t.Context()))
+		assert.Equal(t, &SharedProtocol{Version: 1}, limits.SharedProtocol())
+	})
+	t.Run("Failed", func(t *testing.T) {
+		store := &negotiatingSharedStore{err: errors.New("broker unavailable")}
+		var events []Event
+		limits := &Limits{Shared: store, SharedKey: "app", OnEvent: func(e Event) { events = append(events, e) }}
+		for range 3 {
+			limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+			assert.NoError(t, limits.SyncShared(t.Context()))
+		}
+		assert.Nil(t, limits.SharedProtocol(), "failed negotiation should not be kept")
+		assert.Len(t, store.offers, 1, "failed negotiation should back off")
+		assert.Zero(t, store.merges)
+		if assert.Len(t, events, 1, "failed negotiation should be reported once") {
+			assert.Equal(t, EventSharedError, events[0].Kind)
+		}
+
+		store.err = nil
+		store.reply = SharedProtocol{Version: 1, Capabilities: []SharedCapability{SharedCapabilityMerge}}
+		limits.negotiation.retryAt = time.Time{} // the backoff elapsed
+		limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 101, Remaining: 4899, Reset: 1745121612})
+		assert.True(t, limits.SharedProtocol().Has(SharedCapabilityMerge), "negotiation should be retried")
+		assert.Equal(t, 1, store.merges)
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		store := &negotiatingSharedStore{reply: SharedProtocol{Version: 1}}
+		limits := &Limits{Shared: store, SharedKey: "app"}
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		limits.negotiate(ctx)
+		assert.Equal(t, &SharedProtocol{Version: 1}, limits.SharedProtocol(), "negotiation should not inherit the caller's cancellation")
+	})
+}
@@ -0,0 +1,43 @@
+package ghratelimit
+
+// available reports if the transport may currently be selected: it is not paused, evicted or behind an open circuit.
+func (t *Transport) available() bool {
+	return !t.Paused() && t.Healthy() && !t.CircuitOpen()
+}
+
+// RateLimit implements RateAwareTransport, so the pool can be the Member of a Transport in an outer Balancer
+// (ex: balancing across GHES instances whose members each balance across tokens). The rate limit of the resource type is
+// aggregated across the transports that may currently be selected: their Limit, Remaining and Used are summed, and the
+// Reset is the soonest, as that is when capacity is next added. It returns nil if the rate limit is unknown for all of them.
+func (bt *Balancer) RateLimit(resource Resource) *Rate {
+	var total *Rate
+	for _, transport := range bt.transports() {
+		transport.observeMember(resource)
+		if !transport.available() {
+			continue
+		}
+		rate := transport.Limits.Load(resource)
+		if rate == nil {
+			continue
+		}
+		if total == nil {
+			total = &Rate{Reset: rate.Reset}
+		}
+		total.Limit += rate.Limit
+		total.Remaining += rate.Remaining
+		total.Used += rate.Used
+		total.Reset = min(total.Reset, rate.Reset)
+	}
+	return total
+}
+
+// Healthy implements RateAwareTransport, reporting if any transport of the pool may currently be selected,
+// so an outer Balancer stops selecting a pool once every transport in it is paused, evicted or tripped.
+func (bt *Balancer) Healthy() bool {
+	for _, transport := range bt.transports() {
+		if transport.available() {
+			return true
+		}
+	}
+	return false
+}
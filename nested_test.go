@@ -0,0 +1,54 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_Nested(t *testing.T) {
+	var _ RateAwareTransport = (*Balancer)(nil)
+	var sent [4]atomic.Int64
+	a1, a2 := withRemaining(100), withRemaining(200)
+	b1, b2 := withRemaining(1000), withRemaining(0)
+	for idx, transport := range []*Transport{a1, a2, b1, b2} {
+		transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent[idx].Add(1)
+			return okResponse().RoundTrip(req)
+		})
+	}
+	inner1 := &Balancer{Transports: []*Transport{a1, a2}}
+	inner2 := &Balancer{Transports: []*Transport{b1, b2}}
+
+	rate := inner1.RateLimit(ResourceCore)
+	if assert.NotNil(t, rate) {
+		assert.Equal(t, uint64(10000), rate.Limit)
+		assert.Equal(t, uint64(300), rate.Remaining)
+		assert.Equal(t, uint64(9700), rate.Used)
+	}
+	assert.Nil(t, inner1.RateLimit(ResourceSearch), "unknown rate limits should not be aggregated")
+
+	x, y := &Transport{Member: inner1}, &Transport{Member: inner2}
+	outer := &Balancer{Transports: []*Transport{x, y}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := outer.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(1), sent[2].Load(), "the pool with the most remaining should be selected")
+	assert.Equal(t, uint64(1000), y.RateLimit(ResourceCore).Remaining, "the aggregate should bubble up")
+
+	inner2.Pause(b1)
+	assert.True(t, inner2.Healthy())
+	assert.Equal(t, uint64(0), y.RateLimit(ResourceCore).Remaining, "paused transports should not be aggregated")
+	inner2.Pause(b2)
+	assert.False(t, inner2.Healthy(), "a pool without selectable transports should be unhealthy")
+	resp, err = outer.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, int64(1), sent[1].Load(), "unhealthy pools should not be selected")
+	assert.Nil(t, inner2.RateLimit(ResourceCore))
+}
@@ -0,0 +1,71 @@
+package ghratelimit
+
+import "sync"
+
+// optimistic tracks requests that were optimistically decremented from a resource's rate limit at dispatch.
+type optimistic struct {
+	mu      sync.Mutex
+	pending uint64 // cost dispatched that is not yet reflected by a response
+	reset   uint64 // Reset of the last stored rate limit
+	used    uint64 // Used of the last stored rate limit, as reported by GitHub
+}
+
+// optimisticFor returns the optimistic state of the resource type, if any request has been dispatched optimistically.
+func (l *Limits) optimisticFor(resource Resource, create bool) *optimistic {
+	if !create {
+		val, ok := l.optimistic.Load(resource)
+		if !ok {
+			return nil
+		}
+		return val.(*optimistic)
+	}
+	val, _ := l.optimistic.LoadOrStore(resource, new(optimistic))
+	return val.(*optimistic)
+}
+
+// dispatch optimistically decrements the remaining requests of the resource type by cost,
+// so concurrent requests see the reduced budget before the first response arrives.
+func (l *Limits) dispatch(resource Resource, cost uint64) {
+	if cost == 0 {
+		return
+	}
+	o := l.optimisticFor(resource, true)
+	o.mu.Lock()
+	o.pending += cost
+	o.mu.Unlock()
+	for {
+		val, ok := l.m.Load(resource)
+		if !ok {
+			return
+		}
+		rate := *val.(*Rate)
+		rate.Remaining -= min(cost, rate.Remaining)
+		rate.Used += cost
+		if l.m.CompareAndSwap(resource, val, &rate) {
+			return
+		}
+	}
+}
+
+// settle removes a dispatched request from the pending cost once its response (or error) has been received.
+func (l *Limits) settle(resource Resource, cost uint64) {
+	o := l.optimisticFor(resource, false)
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	o.pending -= min(cost, o.pending)
+	o.mu.Unlock()
+}
+
+// adjust returns the rate limit from GitHub adjusted for requests still pending, o may be nil.
+// The caller must hold o.mu.
+func (o *optimistic) adjust(rate *Rate) *Rate {
+	if o == nil || o.pending == 0 {
+		return rate
+	}
+	adjusted := *rate
+	adjusted.Remaining -= min(o.pending, adjusted.Remaining)
+	adjusted.Used += o.pending
+	return &adjusted
+}
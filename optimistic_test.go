@@ -0,0 +1,58 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Optimistic(t *testing.T) {
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	block := make(chan struct{})
+	transport := &Transport{Optimistic: true, Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-block
+		return nil, http.ErrHandlerTimeout
+	})}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 10, Used: 4990, Reset: reset})
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+			_, _ = transport.RoundTrip(req)
+		}()
+	}
+	assert.Eventually(t, func() bool { return transport.InFlight() == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(7), transport.Limits.Load(ResourceCore).Remaining, "dispatched requests should be decremented before their responses")
+
+	transport.Limits.settle(ResourceCore, 1)
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 9, Used: 4991, Reset: reset})
+	assert.Equal(t, uint64(7), transport.Limits.Load(ResourceCore).Remaining, "headers should be reconciled with pending requests")
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 10, Used: 4990, Reset: reset})
+	assert.Equal(t, uint64(7), transport.Limits.Load(ResourceCore).Remaining, "stale headers should be ignored")
+	transport.Limits.settle(ResourceCore, 1)
+
+	close(block)
+	wg.Wait()
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 8, Used: 4992, Reset: reset})
+	assert.Equal(t, uint64(8), transport.Limits.Load(ResourceCore).Remaining, "settled requests should no longer be pending")
+}
+
+func TestLimits_Store_StaleOptimistic(t *testing.T) {
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	resp := &http.Response{}
+	var limits Limits
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 10, Remaining: 4990, Reset: reset})
+	limits.dispatch(ResourceCore, 1)
+	limits.settle(ResourceCore, 1)
+
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 30, Remaining: 4995, Reset: reset})
+	assert.Equal(t, uint64(4989), limits.Load(ResourceCore).Remaining, "stale update should be ignored")
+	limits.Store(resp, ResourceCore, &Rate{Limit: 5000, Used: 20, Remaining: 4980, Reset: reset})
+	assert.Equal(t, uint64(4980), limits.Load(ResourceCore).Remaining, "a rejected update should not advance the optimistic state")
+}
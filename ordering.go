@@ -0,0 +1,123 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ContextWithOrderingKey returns a copy of ctx whose requests are serialized with every other request sharing the key,
+// in submission order. A Balancer also sends them through the same transport (while it remains in the pool and is
+// not paused), ex: the calls of a job where later requests depend on earlier writes. Each request is sent once the previous
+// one returned, and is never failed over to another transport, so neither failover nor retries can reorder them.
+// See also the OrderingKey of Transport and Balancer.
+func ContextWithOrderingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, orderingKey, key)
+}
+
+// OrderingKeyFromContext returns the ordering key set by ContextWithOrderingKey, if any.
+func OrderingKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(orderingKey).(string)
+	return key
+}
+
+// sequence serializes the requests sharing an ordering key.
+type sequence struct {
+	running   bool            // if a request is at the head, guarded by sequencer.mu
+	waiting   []chan struct{} // closed when it is the turn of each waiting request, in submission order, guarded by sequencer.mu
+	transport *Transport      // the transport of the previous request, only accessed by the request at the head
+	refs      int             // the number of submitted requests that have not returned, guarded by sequencer.mu
+}
+
+// sequencer serializes requests by their ordering key.
+type sequencer struct {
+	mu        sync.Mutex
+	sequences map[string]*sequence // ordering key -> sequence, guarded by mu
+}
+
+// queued returns the number of submitted requests with the ordering key that have not returned.
+func (s *sequencer) queued(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq, ok := s.sequences[key]; ok {
+		return seq.refs
+	}
+	return 0
+}
+
+// enqueue blocks until every request submitted before with the same ordering key has returned, returning the sequence
+// and a func to call once the request has returned. If ctx is done first, a *WaitError is returned.
+func (s *sequencer) enqueue(ctx context.Context, key string) (*sequence, func(), error) {
+	s.mu.Lock()
+	if s.sequences == nil {
+		s.sequences = make(map[string]*sequence)
+	}
+	seq, ok := s.sequences[key]
+	if !ok {
+		seq = &sequence{}
+		s.sequences[key] = seq
+	}
+	seq.refs++
+	done := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if seq.refs--; seq.refs == 0 {
+			delete(s.sequences, key)
+		}
+		if len(seq.waiting) == 0 {
+			seq.running = false
+			return
+		}
+		close(seq.waiting[0])
+		seq.waiting = seq.waiting[1:]
+	}
+	if !seq.running {
+		seq.running = true
+		s.mu.Unlock()
+		return seq, done, nil
+	}
+	turn := make(chan struct{})
+	seq.waiting = append(seq.waiting, turn)
+	s.mu.Unlock()
+
+	select {
+	case <-turn:
+		return seq, done, nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	if idx := slices.Index(seq.waiting, turn); idx >= 0 {
+		seq.waiting = slices.Delete(seq.waiting, idx, idx+1)
+		seq.refs--
+		s.mu.Unlock()
+	} else {
+		s.mu.Unlock()
+		done() // it became the turn of this request as ctx was done, pass it on
+	}
+	return nil, nil, &WaitError{Op: "ordered request", Err: context.Cause(ctx)}
+}
+
+// requestOrderingKey returns the ordering key of the request set by ContextWithOrderingKey, or returned by fn (if not nil).
+func requestOrderingKey(req *http.Request, fn func(*http.Request) string) string {
+	if key := OrderingKeyFromContext(req.Context()); key != "" || fn == nil {
+		return key
+	}
+	return fn(req)
+}
+
+// selectOrdered selects the transport of the previous request of the sequence if it can still be used,
+// otherwise a transport is selected as usual (and used by the following requests).
+func (bt *Balancer) selectOrdered(seq *sequence, req *http.Request, resource Resource, tried map[*Transport]bool) (*Transport, error) {
+	if seq == nil {
+		return bt.selectTransport(req, resource, tried)
+	}
+	if transport := seq.transport; transport != nil && !tried[transport] && !transport.Paused() && slices.Contains(bt.transports(), transport) {
+		return transport, nil
+	}
+	selected, err := bt.selectTransport(req, resource, tried)
+	if selected != nil {
+		seq.transport = selected
+	}
+	return selected, err
+}
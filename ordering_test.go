@@ -0,0 +1,79 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_OrderingKey(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	recording := func(name string) *Transport {
+		transport := &Transport{Name: name, Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			order = append(order, name+" "+req.URL.Query().Get("n"))
+			mu.Unlock()
+			if req.URL.Query().Get("n") == "0" {
+				<-release
+			}
+			return okResponse().RoundTrip(req)
+		})}
+		transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 100, Used: 4900})
+		return transport
+	}
+	a, b := recording("a"), recording("b")
+	b.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 4000, Used: 1000})
+	bt := &Balancer{Transports: []*Transport{a, b}, Failover: 1}
+
+	queued := func() int { return bt.sequencer.queued("job") }
+	ctx := ContextWithOrderingKey(context.Background(), "job")
+	cancelled, cancel := context.WithCancel(ctx)
+	const n = 4
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		reqCtx := ctx
+		if i == 2 {
+			reqCtx = cancelled
+		}
+		req, _ := http.NewRequestWithContext(reqCtx, http.MethodPost, "https://api.github.com/repos/o/r/issues?n="+strconv.Itoa(i), nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := bt.RoundTrip(req)
+			if err == nil {
+				discard(resp)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			errs[i] = err
+		}()
+		assert.Eventually(t, func() bool { return queued() == i+1 }, time.Second, time.Millisecond, "request %d should be queued", i)
+		if i == 0 {
+			a.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000})
+		}
+	}
+	cancel()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs[2] != nil
+	}, time.Second, time.Millisecond, "cancelled request should stop waiting")
+	var waitErr *WaitError
+	assert.ErrorAs(t, errs[2], &waitErr)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"b 0", "b 1", "b 3"}, order, "requests should be sent in order through the same transport")
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.NoError(t, errs[3])
+	assert.Zero(t, queued(), "the sequence should be released once every request returned")
+}
@@ -0,0 +1,81 @@
+package ghratelimit
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// paceInterval returns the delay between requests that spreads the remaining requests evenly until the reset.
+// It returns zero if the rate limit is unknown, exhausted or already reset.
+func paceInterval(rate *Rate, now time.Time) time.Duration {
+	if rate == nil || rate.Remaining == 0 {
+		return 0
+	}
+	until := rate.ResetTime().Sub(now)
+	if until <= 0 {
+		return 0
+	}
+	return until / time.Duration(rate.Remaining)
+}
+
+// reservePace reserves the next paced slot for a request costing cost, returning when the request may be sent.
+// If ctx has a deadline before the slot, no slot is reserved and a *WaitError is returned immediately.
+func (t *Transport) reservePace(ctx context.Context, resource Resource, now time.Time, cost uint64) (time.Time, error) {
+	interval := paceInterval(t.Limits.Load(resource), now)
+	if interval <= 0 {
+		return now, nil
+	}
+	val, _ := t.pace.LoadOrStore(resource, new(atomic.Int64))
+	next := val.(*atomic.Int64)
+	for {
+		prev := next.Load()
+		slot := time.Unix(0, max(prev, now.UnixNano()))
+		if deadline, ok := ctx.Deadline(); ok && slot.After(deadline) {
+			return slot, &WaitError{Op: "paced slot", Resource: resource, Until: slot, Err: context.DeadlineExceeded}
+		}
+		if next.CompareAndSwap(prev, slot.Add(interval*time.Duration(cost)).UnixNano()) {
+			return slot, nil
+		}
+	}
+}
+
+// wait blocks until a request costing cost may be sent if the resource type is paced (see Pace).
+func (t *Transport) wait(ctx context.Context, resource Resource, cost uint64) error {
+	if !slices.Contains(t.Pace, resource) {
+		return nil
+	}
+	now := t.clock().Now()
+	slot, err := t.reservePace(ctx, resource, now, cost)
+	if err != nil {
+		return err
+	}
+	if delay := slot.Sub(now); delay > 0 {
+		debugf("pacing %s request for %s", resource, delay)
+	}
+	return sleepUntil(ctx, t.clock(), slot, "paced slot", resource)
+}
+
+// PaceAll calls fn until it reports there is no more work (ex: once per page of search results) or returns an error,
+// waiting between calls so the remaining requests of the resource type last until its window resets,
+// and waiting for the reset if it is exhausted. It suits the tiny windows of ResourceSearch and ResourceCodeSearch,
+// where paginating as fast as possible trips the rate limit. fn should send one request using a Transport with these Limits.
+func (l *Limits) PaceAll(ctx context.Context, resource Resource, fn func(ctx context.Context) (more bool, err error)) error {
+	for {
+		start := l.clock().Now()
+		more, err := fn(ctx)
+		if err != nil || !more {
+			return err
+		}
+		if err := l.waitFor(ctx, resource, 1); err != nil {
+			return err
+		}
+		if interval := paceInterval(l.Load(resource), start); interval > 0 {
+			debugf("pacing %s request for %s", resource, interval)
+			if err := sleepUntil(ctx, l.clock(), start.Add(interval), "paced slot", resource); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Pace(t *testing.T) {
+	now := time.Now()
+	transport := &Transport{Base: okResponse(), Pace: []Resource{ResourceSearch}}
+	transport.Limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 10, Reset: uint64(now.Add(10 * time.Second).Unix())})
+
+	first, err := transport.reservePace(context.Background(), ResourceSearch, now, 1)
+	assert.NoError(t, err)
+	second, err := transport.reservePace(context.Background(), ResourceSearch, now, 1)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Second, second.Sub(first), float64(100*time.Millisecond), "requests should be spread across the window")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/search/repositories?q=foo", nil)
+	_, err = transport.RoundTrip(req)
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "should fail fast when the slot is after the deadline")
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err, "unpaced resources should not wait")
+}
+
+func TestLimits_PaceAll(t *testing.T) {
+	var limits Limits
+	reset := time.Now().Add(2 * time.Second).Truncate(time.Second).Add(time.Second)
+	var starts []time.Time
+	err := limits.PaceAll(context.Background(), ResourceSearch, func(ctx context.Context) (bool, error) {
+		starts = append(starts, time.Now())
+		remaining := uint64(40 - len(starts))
+		limits.Store(nil, ResourceSearch, &Rate{Limit: 40, Remaining: remaining, Reset: uint64(reset.Unix())})
+		return len(starts) < 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, starts, 3)
+	interval := time.Until(reset) / 38
+	assert.Greater(t, starts[2].Sub(starts[1]), interval/2, "calls should be spread across the window")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = limits.PaceAll(ctx, ResourceSearch, func(ctx context.Context) (bool, error) { return true, nil })
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "cancelled context should stop pacing")
+}
@@ -0,0 +1,31 @@
+package ghratelimit
+
+import "net/http"
+
+// ParseErrorPolicy decides how a Transport handles a response whose rate-limit headers cannot be parsed,
+// ex: a malformed X-RateLimit-Reset injected by a proxy. It is called with the response and the parse error,
+// if it returns nil the response is returned to the caller, otherwise the response is discarded and the error returned.
+// The parse error is reported to Hooks.OnResponse (see HookInfo.ParseErr) regardless of the policy.
+type ParseErrorPolicy func(resp *http.Response, err error) error
+
+// ParseErrorFail discards the response and returns the parse error, the behavior prior to ParseErrorPolicy.
+func ParseErrorFail(_ *http.Response, err error) error {
+	return err
+}
+
+// ParseErrorIgnore returns the response, ignoring the parse error. It is the default.
+func ParseErrorIgnore(*http.Response, error) error {
+	return nil
+}
+
+// parseError applies the ParseErrorPolicy (defaulting to ParseErrorIgnore) to the parse error of a response.
+func (t *Transport) parseError(resp *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	policy := t.ParseErrorPolicy
+	if policy == nil {
+		policy = ParseErrorIgnore
+	}
+	return policy(resp, err)
+}
@@ -0,0 +1,67 @@
+package ghratelimit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_ParseErrorPolicy(t *testing.T) {
+	malformed := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"X-Ratelimit-Resource":  []string{"core"},
+				"X-Ratelimit-Limit":     []string{"5000"},
+				"X-Ratelimit-Remaining": []string{"4999"},
+				"X-Ratelimit-Used":      []string{"1"},
+				"X-Ratelimit-Reset":     []string{"soon"},
+			},
+			Body:    io.NopCloser(strings.NewReader("ok")),
+			Request: req,
+		}, nil
+	})
+	var parseErr error
+	transport := &Transport{
+		Base:  malformed,
+		Hooks: &Hooks{OnResponse: func(info HookInfo) { parseErr = info.ParseErr }},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err, "the response should be surfaced by default") {
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "ok", string(body))
+		discard(resp)
+	}
+	assert.Error(t, parseErr, "the parse error should be reported to the hook")
+
+	transport.ParseErrorPolicy = ParseErrorFail
+	parseErr = nil
+	_, err = transport.RoundTrip(req)
+	assert.Error(t, err)
+	assert.Equal(t, parseErr, err)
+
+	errMalformed := errors.New("malformed")
+	var called *http.Response
+	transport.ParseErrorPolicy = func(resp *http.Response, err error) error {
+		called = resp
+		return errors.Join(errMalformed, err)
+	}
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, errMalformed)
+	assert.NotNil(t, called, "the policy should be called with the response")
+
+	transport.ParseErrorPolicy = ParseErrorIgnore
+	transport.Base = okResponse()
+	parseErr = errMalformed
+	resp, err = transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.NoError(t, parseErr, "responses without rate-limit headers should not be reported")
+}
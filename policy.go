@@ -0,0 +1,230 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+)
+
+// ErrUnknownStrategy is returned by (*Balancer).ApplyPolicy for a PolicyStrategy with an unknown name.
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// Policy describes the decision pipeline of a Balancer as data: the filters that exclude transports from selection,
+// the strategy that selects among the remaining candidates, the fallbacks and failover once they are exhausted and the waits.
+// It is constructed in code or decoded from configuration and applied with (*Balancer).ApplyPolicy,
+// while (*Balancer).Policy describes the current configuration so it can be inspected, diffed (see Diff) and logged.
+type Policy struct {
+	// SchemaVersion is the SchemaVersion the Policy was encoded with.
+	SchemaVersion int `json:"schema_version"`
+	// UnknownResource is the resource type of requests whose resource cannot be inferred, see Balancer.UnknownResource.
+	UnknownResource Resource `json:"unknown_resource,omitempty"`
+	// Filters exclude transports from selection.
+	Filters PolicyFilters `json:"filters"`
+	// Strategy selects among the candidates, see Balancer.Strategy.
+	Strategy PolicyStrategy `json:"strategy"`
+	// ResourceStrategies overrides the Strategy for specific resource types, see Balancer.ResourceStrategies.
+	ResourceStrategies map[Resource]PolicyStrategy `json:"resource_strategies,omitempty"`
+	// Fallbacks maps a resource type exhausted on every transport to the resource type the request is attributed to instead,
+	// see Balancer.Fallback. A Fallback set directly (rather than by ApplyPolicy) is not described.
+	Fallbacks map[Resource]Resource `json:"fallbacks,omitempty"`
+	// Failover is the number of additional transports a request is retried on, see Balancer.Failover.
+	Failover int `json:"failover"`
+	// Waits decide if requests wait for quota.
+	Waits PolicyWaits `json:"waits"`
+}
+
+// PolicyFilters are the filters of a Policy.
+type PolicyFilters struct {
+	// StandbyThreshold is the Balancer.StandbyThreshold.
+	StandbyThreshold float64 `json:"standby_threshold"`
+	// Floors is the Balancer.Floors.
+	Floors map[Resource]uint64 `json:"floors,omitempty"`
+	// FailOnSaturation is the Balancer.FailOnSaturation.
+	FailOnSaturation bool `json:"fail_on_saturation"`
+	// Health is the Balancer.Health.
+	Health *HealthPolicy `json:"health,omitempty"`
+}
+
+// PolicyStrategy describes a Strategy of a Policy.
+type PolicyStrategy struct {
+	// Name is one of "highest_remaining" (the default if empty), "round_robin", "weighted_random", "least_recently_used" or
+	// "first_above_threshold". Other strategies are described by their type, ex: "*ghratelimit.Experiment", and can only be
+	// applied to a Balancer already using that strategy, which keeps it.
+	Name string `json:"name"`
+	// Threshold is the FirstAboveThreshold.Threshold.
+	Threshold uint64 `json:"threshold,omitempty"`
+}
+
+// PolicyWaits are the waits of a Policy.
+type PolicyWaits struct {
+	// WaitOnExhaustion is the Transport.WaitOnExhaustion of every transport in the pool.
+	WaitOnExhaustion bool `json:"wait_on_exhaustion"`
+}
+
+// describeStrategy describes the strategy as a PolicyStrategy.
+func describeStrategy(strategy Strategy) PolicyStrategy {
+	switch s := strategy.(type) {
+	case nil, HighestRemaining, *HighestRemaining:
+		return PolicyStrategy{Name: "highest_remaining"}
+	case *RoundRobin:
+		return PolicyStrategy{Name: "round_robin"}
+	case WeightedRandom, *WeightedRandom:
+		return PolicyStrategy{Name: "weighted_random"}
+	case LeastRecentlyUsed, *LeastRecentlyUsed:
+		return PolicyStrategy{Name: "least_recently_used"}
+	case FirstAboveThreshold:
+		return PolicyStrategy{Name: "first_above_threshold", Threshold: s.Threshold}
+	case *FirstAboveThreshold:
+		return PolicyStrategy{Name: "first_above_threshold", Threshold: s.Threshold}
+	default:
+		return PolicyStrategy{Name: strategyName(strategy)}
+	}
+}
+
+// strategy returns the Strategy described by the PolicyStrategy, keeping current if it is described the same way.
+func (ps PolicyStrategy) strategy(current Strategy) (Strategy, error) {
+	if ps == describeStrategy(current) {
+		return current, nil
+	}
+	switch ps.Name {
+	case "", "highest_remaining":
+		return HighestRemaining{}, nil
+	case "round_robin":
+		return &RoundRobin{}, nil
+	case "weighted_random":
+		return WeightedRandom{}, nil
+	case "least_recently_used":
+		return LeastRecentlyUsed{}, nil
+	case "first_above_threshold":
+		return FirstAboveThreshold{Threshold: ps.Threshold}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, ps.Name)
+	}
+}
+
+// Policy describes the current decision pipeline of the Balancer.
+func (bt *Balancer) Policy() Policy {
+	strategy := bt.Strategy
+	if current := bt.strategy.Load(); current != nil && *current != nil {
+		strategy = *current
+	}
+	p := Policy{
+		SchemaVersion:   SchemaVersion,
+		UnknownResource: bt.UnknownResource,
+		Filters: PolicyFilters{
+			StandbyThreshold: bt.getStandbyThreshold(),
+			Floors:           maps.Clone(bt.Floors),
+			FailOnSaturation: bt.FailOnSaturation,
+			Health:           bt.Health,
+		},
+		Strategy:  describeStrategy(strategy),
+		Fallbacks: maps.Clone(bt.fallbacks),
+		Failover:  bt.Failover,
+	}
+	if len(bt.ResourceStrategies) > 0 {
+		p.ResourceStrategies = make(map[Resource]PolicyStrategy, len(bt.ResourceStrategies))
+		for resource, strategy := range bt.ResourceStrategies {
+			p.ResourceStrategies[resource] = describeStrategy(strategy)
+		}
+	}
+	transports := bt.transports()
+	p.Waits.WaitOnExhaustion = len(transports) > 0
+	for _, transport := range transports {
+		p.Waits.WaitOnExhaustion = p.Waits.WaitOnExhaustion && transport.WaitOnExhaustion
+	}
+	return p
+}
+
+// ApplyPolicy configures the Balancer (and the WaitOnExhaustion of its transports) from the Policy,
+// replacing the fields it describes. Like setting those fields directly, it must not be called once the Balancer
+// is in use. Nothing is changed if the Policy is invalid, ex: it has an unknown strategy.
+func (bt *Balancer) ApplyPolicy(p Policy) error {
+	if err := checkSchemaVersion(p.SchemaVersion); err != nil {
+		return err
+	}
+	strategy, err := p.Strategy.strategy(bt.Strategy)
+	if err != nil {
+		return err
+	}
+	var strategies map[Resource]Strategy
+	if len(p.ResourceStrategies) > 0 {
+		strategies = make(map[Resource]Strategy, len(p.ResourceStrategies))
+		for resource, ps := range p.ResourceStrategies {
+			if strategies[resource], err = ps.strategy(bt.ResourceStrategies[resource]); err != nil {
+				return fmt.Errorf("strategy for %s: %w", resource, err)
+			}
+		}
+	}
+	bt.UnknownResource = p.UnknownResource
+	bt.StandbyThreshold, bt.Floors = p.Filters.StandbyThreshold, maps.Clone(p.Filters.Floors)
+	bt.standbyThreshold.Store(nil)
+	bt.FailOnSaturation, bt.Health = p.Filters.FailOnSaturation, p.Filters.Health
+	bt.Strategy, bt.ResourceStrategies = strategy, strategies
+	bt.strategy.Store(nil)
+	bt.Failover = p.Failover
+	bt.fallbacks, bt.Fallback = maps.Clone(p.Fallbacks), nil
+	if len(bt.fallbacks) > 0 {
+		fallbacks := bt.fallbacks
+		bt.Fallback = func(req *http.Request) (*http.Request, Resource, error) {
+			return req, fallbacks[InferResource(req)], nil
+		}
+	}
+	for _, transport := range bt.transports() {
+		transport.WaitOnExhaustion = p.Waits.WaitOnExhaustion
+	}
+	return nil
+}
+
+// String implements fmt.Stringer, describing the Policy as JSON for logging.
+func (p Policy) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// Diff describes each difference from the Policy to other, ex: "failover: 0 -> 2" or "filters.floors.core: 50 -> <nil>".
+// It is empty if the policies are equivalent.
+func (p Policy) Diff(other Policy) []string {
+	from, to := p.flatten(), other.flatten()
+	keys := slices.Collect(maps.Keys(from))
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	var diff []string
+	for _, key := range keys {
+		if !reflect.DeepEqual(from[key], to[key]) {
+			diff = append(diff, fmt.Sprintf("%s: %v -> %v", key, from[key], to[key]))
+		}
+	}
+	return diff
+}
+
+// flatten returns every leaf of the JSON encoding of the Policy, keyed by its dotted path, ex: "filters.standby_threshold".
+func (p Policy) flatten() map[string]any {
+	var v any
+	b, _ := json.Marshal(p)
+	_ = json.Unmarshal(b, &v)
+	out := make(map[string]any)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			out[prefix] = v
+			return
+		}
+		for key, value := range obj {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, value)
+		}
+	}
+	walk("", v)
+	return out
+}
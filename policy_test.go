@@ -0,0 +1,81 @@
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_Policy(t *testing.T) {
+	a, b := withRemaining(100), withRemaining(200)
+	bt := &Balancer{Transports: []*Transport{a, b}}
+	p := bt.Policy()
+	assert.Equal(t, SchemaVersion, p.SchemaVersion)
+	assert.Equal(t, PolicyStrategy{Name: "highest_remaining"}, p.Strategy)
+	assert.False(t, p.Waits.WaitOnExhaustion)
+
+	var decoded Policy
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"schema_version": 1,
+		"filters": {"standby_threshold": 0.2, "floors": {"core": 50}, "health": {"failures": 3, "min_backoff": 1000000000}},
+		"strategy": {"name": "round_robin"},
+		"resource_strategies": {"search": {"name": "first_above_threshold", "threshold": 10}},
+		"fallbacks": {"core": "graphql"},
+		"failover": 2,
+		"waits": {"wait_on_exhaustion": true}
+	}`), &decoded))
+	assert.NoError(t, bt.ApplyPolicy(decoded))
+	assert.IsType(t, &RoundRobin{}, bt.Strategy)
+	assert.Equal(t, FirstAboveThreshold{Threshold: 10}, bt.ResourceStrategies[ResourceSearch])
+	assert.Equal(t, 2, bt.Failover)
+	assert.Equal(t, 0.2, bt.getStandbyThreshold())
+	assert.Equal(t, 3, bt.Health.Failures)
+	assert.True(t, a.WaitOnExhaustion && b.WaitOnExhaustion)
+	assert.Equal(t, decoded, bt.Policy(), "the applied policy should be described as-is")
+	assert.Empty(t, decoded.Diff(bt.Policy()))
+
+	assert.Equal(t, []string{
+		"failover: 0 -> 2",
+		"fallbacks.core: <nil> -> graphql",
+		"filters.floors.core: <nil> -> 50",
+		"filters.health.failures: <nil> -> 3",
+		"filters.health.min_backoff: <nil> -> 1e+09",
+		"filters.standby_threshold: 0 -> 0.2",
+		"resource_strategies.search.name: <nil> -> first_above_threshold",
+		"resource_strategies.search.threshold: <nil> -> 10",
+		"strategy.name: highest_remaining -> round_robin",
+		"waits.wait_on_exhaustion: false -> true",
+	}, p.Diff(decoded))
+	assert.Contains(t, decoded.String(), `"failover":2`)
+
+	a.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 5000})
+	b.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 5000})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, resource, err := bt.fallback(req, ResourceCore)
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceGraphQL, resource, "fallbacks should be applied")
+	req, _ = http.NewRequestWithContext(ContextWithResource(context.Background(), ResourceSearch), http.MethodGet, "https://api.github.com/search/issues", nil)
+	_, resource, _ = bt.fallback(req, ResourceSearch)
+	assert.Equal(t, ResourceSearch, resource, "resource types without a fallback should be unchanged")
+}
+
+func TestBalancer_ApplyPolicy_Invalid(t *testing.T) {
+	experiment := &Experiment{}
+	bt := &Balancer{Strategy: experiment, Failover: 1}
+	p := bt.Policy()
+	assert.Equal(t, "*ghratelimit.Experiment", p.Strategy.Name)
+	p.Failover = 3
+	assert.NoError(t, bt.ApplyPolicy(p), "custom strategies should be kept when re-applied")
+	assert.Same(t, experiment, bt.Strategy)
+	assert.Equal(t, 3, bt.Failover)
+
+	p.Strategy.Name, p.Failover = "fastest", 4
+	assert.ErrorIs(t, bt.ApplyPolicy(p), ErrUnknownStrategy)
+	assert.Equal(t, 3, bt.Failover, "nothing should change if the policy is invalid")
+
+	p.Strategy.Name, p.SchemaVersion = "", SchemaVersion+1
+	assert.ErrorIs(t, bt.ApplyPolicy(p), ErrSchemaVersion)
+}
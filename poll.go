@@ -0,0 +1,66 @@
+package ghratelimit
+
+import (
+	"log"
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+// MaxPollBackoff is the longest interval Poll backs off to after consecutive failures.
+const MaxPollBackoff = time.Hour
+
+// PollPolicy adapts the interval of (*Transport).Poll to how close the transport is to its rate limits,
+// the interval passed to Poll is used when every resource type has all of its requests remaining.
+type PollPolicy struct {
+	// MinInterval is the shortest interval, approached as the remaining requests of any resource type approach zero.
+	MinInterval time.Duration
+	// IdleInterval, if non-zero, is the interval used while the transport sent no requests since the last poll.
+	IdleInterval time.Duration
+	// Jitter is the fraction (0.0 to 1.0) of the interval randomly added or removed,
+	// so a fleet of replicas started together does not poll in lockstep.
+	Jitter float64
+}
+
+// interval returns the delay before the next poll given the base interval and whether the transport has been idle.
+// A poll is also scheduled for just after the soonest reset, so exhausted limits are refreshed as soon as they reset.
+func (p *PollPolicy) interval(limits *Limits, base time.Duration, idle bool, now time.Time) time.Duration {
+	interval := base
+	if idle && p.IdleInterval > 0 {
+		interval = p.IdleInterval
+	} else {
+		for _, rate := range limits.Iter() {
+			if rate.Limit > 0 {
+				scaled := time.Duration(float64(base) * float64(rate.Remaining) / float64(rate.Limit))
+				interval = min(interval, scaled)
+			}
+			if until := rate.ResetTime().Sub(now); until > 0 {
+				interval = min(interval, until+time.Second)
+			}
+		}
+	}
+	if p.Jitter > 0 {
+		interval += time.Duration(p.Jitter * (2*rand.Float64() - 1) * float64(interval))
+	}
+	return max(interval, p.MinInterval, time.Second)
+}
+
+// pollBackoff returns the interval before retrying a poll after consecutive failures, doubling the interval for each.
+func pollBackoff(interval time.Duration, failures int) time.Duration {
+	if failures > 30 || interval<<failures <= 0 {
+		return MaxPollBackoff
+	}
+	return max(min(interval<<failures, MaxPollBackoff), interval)
+}
+
+// pollError reports a failed poll to OnPollError, otherwise it is logged on the first
+// and then every power of two consecutive failures to avoid flooding the logs.
+func (t *Transport) pollError(err error, failures int) {
+	if t.OnPollError != nil {
+		t.OnPollError(err)
+		return
+	}
+	if bits.OnesCount(uint(failures)) == 1 {
+		log.Printf("(*ghratelimit.Transport).Limits.Fetch failed (%d consecutive failures): %v\n", failures, err)
+	}
+}
@@ -0,0 +1,52 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollPolicy(t *testing.T) {
+	now := time.Now()
+	policy := &PollPolicy{MinInterval: 5 * time.Second, IdleInterval: 10 * time.Minute}
+	var limits Limits
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 5000, Reset: uint64(now.Add(time.Hour).Unix())})
+	assert.Equal(t, time.Minute, policy.interval(&limits, time.Minute, false, now), "full remaining should use the base interval")
+	assert.Equal(t, 10*time.Minute, policy.interval(&limits, time.Minute, true, now), "idle transports should back off")
+
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 15, Reset: uint64(now.Add(time.Hour).Unix())})
+	assert.Equal(t, 30*time.Second, policy.interval(&limits, time.Minute, false, now), "should poll more often as remaining drops")
+
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 0, Reset: uint64(now.Add(time.Hour).Unix())})
+	assert.Equal(t, 5*time.Second, policy.interval(&limits, time.Minute, false, now), "MinInterval should bound the interval")
+
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 30, Reset: uint64(now.Add(20 * time.Second).Unix())})
+	assert.InDelta(t, 21*time.Second, policy.interval(&limits, time.Minute, false, now), float64(time.Second), "should poll just after the reset")
+
+	policy.Jitter = 0.5
+	for range 100 {
+		interval := policy.interval(&limits, time.Hour, true, now)
+		assert.GreaterOrEqual(t, interval, 5*time.Minute)
+		assert.LessOrEqual(t, interval, 15*time.Minute)
+	}
+}
+
+func TestTransport_Poll_Backoff(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, pollBackoff(time.Minute, 1))
+	assert.Equal(t, 16*time.Minute, pollBackoff(time.Minute, 4))
+	assert.Equal(t, MaxPollBackoff, pollBackoff(time.Minute, 10))
+	assert.Equal(t, MaxPollBackoff, pollBackoff(time.Minute, 100))
+
+	var errs []error
+	transport := failing(io.ErrUnexpectedEOF)
+	transport.OnPollError = func(err error) { errs = append(errs, err) }
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	transport.Poll(ctx, time.Millisecond, nil)
+	assert.NotEmpty(t, errs, "OnPollError should be called")
+	assert.Less(t, len(errs), 10, "consecutive failures should back off")
+	assert.ErrorIs(t, errs[0], io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,66 @@
+package ghratelimit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Priority is the scheduling class of a request, set using ContextWithPriority.
+type Priority int
+
+const (
+	// PriorityLow is for background work such as batch jobs.
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of requests without one.
+	PriorityNormal Priority = 0
+	// PriorityHigh is for latency-sensitive work such as user-facing requests.
+	PriorityHigh Priority = 1
+)
+
+// String implements fmt.Stringer
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// reserved returns the fraction of the rate limit reserved for priorities above the given priority.
+func (t *Transport) reserved(priority Priority) float64 {
+	var fraction float64
+	for p, f := range t.Reserve {
+		if p > priority {
+			fraction += f
+		}
+	}
+	return fraction
+}
+
+// permits reports if a request with the given priority may use the remaining rate limit of the resource type,
+// without eating into the quota reserved for higher priorities.
+func (t *Transport) permits(priority Priority, resource Resource) bool {
+	if len(t.Reserve) == 0 {
+		return true
+	}
+	rate := t.Limits.Load(resource)
+	if rate == nil || rate.Limit == 0 {
+		return true
+	}
+	return float64(rate.Remaining) > t.reserved(priority)*float64(rate.Limit)
+}
+
+// waitReserved blocks a request until its priority is permitted (see Reserve), or until the rate-limit window resets.
+func (t *Transport) waitReserved(ctx context.Context, resource Resource) error {
+	priority := PriorityFromContext(ctx)
+	if t.permits(priority, resource) {
+		return nil
+	}
+	rate := t.Limits.Load(resource)
+	debugf("queueing %s priority %s request until %s, remaining quota is reserved", priority, resource, rate.ResetTime())
+	return sleepUntil(ctx, t.clock(), rate.ResetTime(), "reserved quota", resource)
+}
@@ -0,0 +1,48 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Reserve(t *testing.T) {
+	transport := &Transport{Base: okResponse(), Reserve: map[Priority]float64{PriorityHigh: 0.1}}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 400, Used: 4600, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+
+	req, _ := http.NewRequestWithContext(ContextWithPriority(context.Background(), PriorityHigh), http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "high priority requests should use the reserved quota")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err = transport.RoundTrip(req)
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "normal priority requests should be queued")
+
+	other := withRemaining(4000)
+	bt := &Balancer{Transports: []*Transport{transport, other}, Strategy: FirstAboveThreshold{}}
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, other, selected, "transports that would queue the request should be avoided")
+}
+
+func TestContextWithEmergencyOverride(t *testing.T) {
+	var events []Event
+	transport := withRemaining(100)
+	transport.Reserve = map[Priority]float64{PriorityHigh: 0.5}
+	transport.HardCap = map[Resource]uint64{ResourceCore: 0}
+	transport.Limits.OnEvent = func(event Event) { events = append(events, event) }
+
+	ctx := ContextWithEmergencyOverride(context.Background(), "INC-1234 rollback")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "local policies should be bypassed")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventEmergencyOverride, events[0].Kind)
+		assert.Contains(t, events[0].Message, "INC-1234 rollback")
+	}
+}
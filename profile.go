@@ -0,0 +1,39 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+)
+
+// label sets the profiler labels of the current goroutine (see runtime/pprof) to the labels of ctx
+// plus the resource type and whether the request is currently being throttled, if ProfilerLabels is set.
+func (t *Transport) label(ctx context.Context, resource Resource, throttled bool) {
+	if !t.ProfilerLabels {
+		return
+	}
+	pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(
+		"ghratelimit_resource", resource.String(),
+		"ghratelimit_throttled", strconv.FormatBool(throttled),
+	)))
+}
+
+// unlabel restores the profiler labels of the current goroutine to the labels of ctx, if ProfilerLabels is set.
+func (t *Transport) unlabel(ctx context.Context) {
+	if t.ProfilerLabels {
+		pprof.SetGoroutineLabels(ctx)
+	}
+}
+
+// labelTransport returns the request with the selected transport's Name (or fingerprint) added to its profiler labels,
+// if the transport has ProfilerLabels set.
+func (bt *Balancer) labelTransport(req *http.Request, transport *Transport) *http.Request {
+	if !transport.ProfilerLabels {
+		return req
+	}
+	ctx := pprof.WithLabels(req.Context(), pprof.Labels("ghratelimit_transport", bt.identify(transport)))
+	return req.WithContext(ctx)
+}
@@ -0,0 +1,19 @@
+//go:build ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+)
+
+// label is a no-op when built with the ghratelimit_minimal tag, as runtime/pprof is unavailable.
+func (t *Transport) label(ctx context.Context, resource Resource, throttled bool) {}
+
+// unlabel is a no-op when built with the ghratelimit_minimal tag.
+func (t *Transport) unlabel(ctx context.Context) {}
+
+// labelTransport returns the request as-is when built with the ghratelimit_minimal tag.
+func (bt *Balancer) labelTransport(req *http.Request, transport *Transport) *http.Request {
+	return req
+}
@@ -0,0 +1,32 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"bytes"
+	"net/http"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_ProfilerLabels(t *testing.T) {
+	var profile bytes.Buffer
+	transport := &Transport{
+		ProfilerLabels: true,
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			_ = pprof.Lookup("goroutine").WriteTo(&profile, 1)
+			return okResponse().RoundTrip(req)
+		}),
+	}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 100})
+	bt := &Balancer{
+		Transports:  []*Transport{withRemaining(0), transport},
+		Fingerprint: func(*Transport) string { return "sha256:6a1f4b0c9e2d7f38" },
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.Contains(t, profile.String(), `labels: {"ghratelimit_resource":"core", "ghratelimit_throttled":"false", "ghratelimit_transport":"sha256:6a1f4b0c9e2d7f38"}`)
+}
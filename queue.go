@@ -0,0 +1,68 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// QueueDepth returns the number of requests currently waiting to be sent by the transport: for a concurrency slot,
+// the rate limit to reset, the quota reserved for higher priorities or a paced slot.
+func (t *Transport) QueueDepth() int {
+	return int(t.queued.Load())
+}
+
+// QueueWait estimates how long a request of the resource type would wait locally before being sent by the transport:
+// for its rate limit to reset if it is exhausted (and WaitOnExhaustion is set), and for the next paced slot (see Pace).
+func (t *Transport) QueueWait(resource Resource) time.Duration {
+	now := t.clock().Now()
+	var wait time.Duration
+	if rate := t.Limits.Load(resource); t.WaitOnExhaustion && rate != nil && rate.Remaining == 0 {
+		wait = rate.ResetTime().Sub(now)
+	}
+	if slices.Contains(t.Pace, resource) {
+		if val, ok := t.pace.Load(resource); ok {
+			wait = max(wait, time.Unix(0, val.(*atomic.Int64).Load()).Sub(now))
+		}
+	}
+	return max(wait, 0)
+}
+
+// ShortestWait is a Strategy that minimizes end-to-end latency rather than just preserving quota: it only considers the
+// candidates whose QueueWait is within Slack of the shortest, then those with the fewest requests queued (see QueueDepth),
+// so a transport with slightly less remaining and an empty queue is preferred over one with a long local wait.
+type ShortestWait struct {
+	// Slack is how much longer than the shortest QueueWait a candidate may wait and still be considered.
+	Slack time.Duration
+	// Strategy selects among the considered candidates.
+	// If nil, HighestRemaining is used.
+	Strategy Strategy
+}
+
+// Select implements Strategy
+func (s ShortestWait) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	waits := make([]time.Duration, len(candidates))
+	for idx, transport := range candidates {
+		waits[idx] = transport.QueueWait(resource)
+	}
+	shortest := slices.Min(waits)
+	considered := make([]*Transport, 0, len(candidates))
+	depth := -1
+	for idx, transport := range candidates {
+		if waits[idx] > shortest+s.Slack {
+			continue
+		}
+		switch queued := transport.QueueDepth(); {
+		case depth == -1 || queued < depth:
+			considered, depth = append(considered[:0], transport), queued
+		case queued == depth:
+			considered = append(considered, transport)
+		}
+	}
+	strategy := s.Strategy
+	if strategy == nil {
+		strategy = HighestRemaining{}
+	}
+	return strategy.Select(req, resource, considered)
+}
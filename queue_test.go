@@ -0,0 +1,54 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortestWait(t *testing.T) {
+	now := time.Now()
+	reset := uint64(now.Add(time.Hour).Unix())
+	busy, idle := &Transport{Base: okResponse(), Pace: []Resource{ResourceCore}}, &Transport{Base: okResponse()}
+	busy.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 4000, Used: 1000, Reset: reset})
+	idle.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 3000, Used: 2000, Reset: reset})
+	_, err := busy.reservePace(context.Background(), ResourceCore, now, 100)
+	assert.NoError(t, err)
+	assert.InDelta(t, 90*time.Second, busy.QueueWait(ResourceCore), float64(2*time.Second), "the paced backlog should be estimated")
+	assert.Zero(t, idle.QueueWait(ResourceCore))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	candidates := []*Transport{busy, idle}
+	assert.Same(t, busy, HighestRemaining{}.Select(req, ResourceCore, candidates))
+	assert.Same(t, idle, ShortestWait{}.Select(req, ResourceCore, candidates), "the transport without a local wait should be preferred")
+	assert.Same(t, busy, ShortestWait{Slack: 2 * time.Minute}.Select(req, ResourceCore, candidates), "waits within the slack should be ignored")
+
+	idle.queued.Add(1)
+	assert.Same(t, busy, ShortestWait{Slack: 2 * time.Minute, Strategy: &RoundRobin{}}.Select(req, ResourceCore, candidates), "the shortest queue should be preferred")
+}
+
+func TestTransport_QueueDepth(t *testing.T) {
+	release := make(chan struct{})
+	transport := &Transport{MaxConcurrency: 1, Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return okResponse().RoundTrip(req)
+	})}
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+			if resp, err := transport.RoundTrip(req); err == nil {
+				discard(resp)
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool { return transport.QueueDepth() == 1 }, time.Second, time.Millisecond, "the request waiting for a slot should be queued")
+	close(release)
+	<-done
+	<-done
+	assert.Zero(t, transport.QueueDepth())
+}
@@ -1,11 +1,22 @@
 package ghratelimit
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// MaxRateValue is the largest Limit, Used or Remaining value that is trusted, larger values are clamped.
+const MaxRateValue = math.MaxInt32
+
+// MaxResetDelay is the furthest in the future that a Reset is trusted to be, later values are clamped.
+// GitHub's longest rate-limit window is an hour, so this leaves ample room for clock skew.
+const MaxResetDelay = 2 * time.Hour
+
 // Rate represents the rate limit information for a given resource type.
 type Rate struct {
 	// The maximum number of requests that you can make per hour.
@@ -23,27 +34,99 @@ func (r *Rate) String() string {
 	return fmt.Sprintf("Rate{Limit: %d, Used: %d, Remaining: %d, Reset: %d}", r.Limit, r.Used, r.Remaining, r.Reset)
 }
 
+// UnmarshalJSON implements json.Unmarshaler. It is lenient, accepting each value as either a JSON number or
+// a string-encoded number (ex: "5000"), as emitted by some proxies and GitHub Enterprise Server versions.
+// A null value is treated as zero.
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Limit     lenientUint `json:"limit"`
+		Used      lenientUint `json:"used"`
+		Remaining lenientUint `json:"remaining"`
+		Reset     lenientUint `json:"reset"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*r = Rate{Limit: uint64(raw.Limit), Used: uint64(raw.Used), Remaining: uint64(raw.Remaining), Reset: uint64(raw.Reset)}
+	return nil
+}
+
+// lenientUint is a uint64 that can be decoded from a JSON number or a string-encoded number.
+type lenientUint uint64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *lenientUint) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if len(s) > 0 && s[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		s = strings.TrimSpace(s)
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid rate value %s: %w", b, err)
+	}
+	*n = lenientUint(v)
+	return nil
+}
+
+// ResetTime returns Reset as a time.Time, it never overflows.
+func (r *Rate) ResetTime() time.Time {
+	return time.Unix(int64(min(r.Reset, math.MaxInt64/2)), 0)
+}
+
+// clamp returns a copy of the rate with absurd values clamped to a sane range, and the reason why.
+// If no clamping was necessary, the original rate is returned.
+func (r *Rate) clamp(now time.Time) (*Rate, string) {
+	c := *r
+	var reasons []string
+	if c.Limit > MaxRateValue {
+		reasons = append(reasons, fmt.Sprintf("limit %d > %d", c.Limit, MaxRateValue))
+		c.Limit = MaxRateValue
+	}
+	if c.Used > MaxRateValue {
+		reasons = append(reasons, fmt.Sprintf("used %d > %d", c.Used, MaxRateValue))
+		c.Used = MaxRateValue
+	}
+	if c.Remaining > c.Limit {
+		reasons = append(reasons, fmt.Sprintf("remaining %d > limit %d", c.Remaining, c.Limit))
+		c.Remaining = c.Limit
+	}
+	if latest := uint64(now.Add(MaxResetDelay).Unix()); c.Reset > latest {
+		reasons = append(reasons, fmt.Sprintf("reset %d > %d", c.Reset, latest))
+		c.Reset = latest
+	}
+	if len(reasons) == 0 {
+		return r, ""
+	}
+	return &c, "clamped " + strings.Join(reasons, ", ")
+}
+
+// rateHeaders are the canonical keys of the rate-limit headers, precomputed so that parsing avoids canonicalizing them.
+var rateHeaders = [...]string{
+	"X-Ratelimit-Limit",
+	"X-Ratelimit-Used",
+	"X-Ratelimit-Remaining",
+	"X-Ratelimit-Reset",
+}
+
 // Parse extracts the rate limit information from the HTTP response headers.
 func ParseRate(headers http.Header) (r Rate, _ error) {
-	if val, err := strconv.ParseUint(headers.Get("X-Ratelimit-Limit"), 10, 64); err != nil {
-		return r, fmt.Errorf("failed to parse X-Ratelimit-Limit header: %w", err)
-	} else {
-		r.Limit = val
-	}
-	if val, err := strconv.ParseUint(headers.Get("X-Ratelimit-Used"), 10, 64); err != nil {
-		return r, fmt.Errorf("failed to parse X-Ratelimit-Used header: %w", err)
-	} else {
-		r.Used = val
-	}
-	if val, err := strconv.ParseUint(headers.Get("X-Ratelimit-Remaining"), 10, 64); err != nil {
-		return r, fmt.Errorf("failed to parse X-Ratelimit-Remaining header: %w", err)
-	} else {
-		r.Remaining = val
-	}
-	if val, err := strconv.ParseUint(headers.Get("X-Ratelimit-Reset"), 10, 64); err != nil {
-		return r, fmt.Errorf("failed to parse X-Ratelimit-Reset header: %w", err)
-	} else {
-		r.Reset = val
+	fields := [...]*uint64{&r.Limit, &r.Used, &r.Remaining, &r.Reset}
+	for idx, key := range rateHeaders {
+		var val string
+		if vals := headers[key]; len(vals) > 0 {
+			val = vals[0]
+		}
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return r, fmt.Errorf("failed to parse %s header: %w", key, err)
+		}
+		*fields[idx] = n
 	}
 	return r, nil
 }
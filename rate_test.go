@@ -1,8 +1,11 @@
 package ghratelimit
 
 import (
+	"encoding/json"
+	"math"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,3 +33,50 @@ func TestRate_Parse(t *testing.T) {
 	})
 	assert.Error(t, err, "expected error, got nil")
 }
+
+func TestRate_UnmarshalJSON(t *testing.T) {
+	var rate Rate
+	assert.NoError(t, json.Unmarshal([]byte(`{"limit":5000,"used":"1","remaining":" 4999 ","reset":"1700000000","extra":true}`), &rate))
+	assert.Equal(t, Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: 1700000000}, rate, "string-encoded numbers should be accepted")
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"limit":null,"remaining":10}`), &rate))
+	assert.Equal(t, Rate{Remaining: 10}, rate, "null and missing values should be zero")
+
+	assert.Error(t, json.Unmarshal([]byte(`{"limit":"many"}`), &rate))
+	assert.Error(t, json.Unmarshal([]byte(`{"limit":-1}`), &rate))
+	assert.Error(t, json.Unmarshal([]byte(`{"limit":true}`), &rate))
+}
+
+func TestRate_clamp(t *testing.T) {
+	now := time.Now()
+	rate := &Rate{Limit: 5000, Used: 0, Remaining: 5000, Reset: uint64(now.Unix())}
+	clamped, _ := rate.clamp(now)
+	assert.Same(t, rate, clamped, "sane rate should not be clamped")
+
+	var events []Event
+	limits := Limits{OnEvent: func(event Event) { events = append(events, event) }}
+	limits.Store(nil, ResourceCore, &Rate{Limit: math.MaxUint64, Used: 0, Remaining: math.MaxUint64, Reset: math.MaxUint64})
+	rate = limits.Load(ResourceCore)
+	assert.Equal(t, uint64(MaxRateValue), rate.Limit)
+	assert.Equal(t, uint64(MaxRateValue), rate.Remaining)
+	assert.WithinDuration(t, now.Add(MaxResetDelay), rate.ResetTime(), time.Second)
+	assert.Len(t, events, 1, "expected an EventClamped")
+	assert.Equal(t, EventClamped, events[0].Kind)
+
+	assert.False(t, (&Rate{Reset: math.MaxUint64}).ResetTime().Before(now), "ResetTime should not overflow")
+}
+
+func BenchmarkParseRate(b *testing.B) {
+	headers := http.Header{
+		"X-Ratelimit-Limit":     []string{"5000"},
+		"X-Ratelimit-Used":      []string{"1000"},
+		"X-Ratelimit-Remaining": []string{"4000"},
+		"X-Ratelimit-Reset":     []string{"1633036800"},
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ParseRate(headers); err != nil {
+			b.Fatalf("ParseRate failed: %v", err)
+		}
+	}
+}
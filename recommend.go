@@ -0,0 +1,110 @@
+package ghratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// window is the first observation of a resource's current rate-limit window.
+type window struct {
+	start time.Time
+	reset uint64
+	used  uint64
+}
+
+// observe records the first observation of each rate-limit window, used to calculate the burn rate.
+func (l *Limits) observe(resource Resource, rate *Rate) {
+	if val, ok := l.windows.Load(resource); ok {
+		if w, ok := val.(*window); ok && w.reset == rate.Reset && w.used <= rate.Used {
+			return
+		}
+	}
+	l.windows.Store(resource, &window{start: l.clock().Now(), reset: rate.Reset, used: rate.Used})
+}
+
+// BurnRate returns the number of requests per second consumed for the given resource type,
+// measured since the first observation of the current rate-limit window.
+// It returns 0 if there are not yet enough observations to calculate a rate.
+func (l *Limits) BurnRate(resource Resource) float64 {
+	rate := l.Load(resource)
+	if rate == nil {
+		return 0
+	}
+	val, ok := l.windows.Load(resource)
+	if !ok {
+		return 0
+	}
+	w, ok := val.(*window)
+	if !ok || w.reset != rate.Reset || rate.Used < w.used {
+		return 0
+	}
+	elapsed := l.clock().Now().Sub(w.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(rate.Used-w.used) / elapsed
+}
+
+// Recommendation is a capacity planning recommendation for a resource type across a Balancer.
+type Recommendation struct {
+	// Resource is the resource type the recommendation applies to.
+	Resource Resource
+	// BurnRate is the number of requests per second being consumed across the pool.
+	BurnRate float64
+	// Remaining is the total number of requests remaining across the pool.
+	Remaining uint64
+	// Reset is the time at which the last rate-limit window in the pool resets.
+	Reset time.Time
+	// Exhaustion is the projected time the pool is exhausted at the current burn rate.
+	// It is the zero value if the pool is not projected to be exhausted before Reset.
+	Exhaustion time.Time
+	// AddTransports is the number of additional transports needed to avoid exhaustion.
+	AddTransports int
+	// ReduceRate is the fraction (0.0 to 1.0) the request rate must be reduced by to avoid exhaustion.
+	ReduceRate float64
+}
+
+// String implements fmt.Stringer
+func (r Recommendation) String() string {
+	if r.Exhaustion.IsZero() {
+		return fmt.Sprintf("%s: %d remaining is sufficient at %.2f req/s until %s", r.Resource, r.Remaining, r.BurnRate, r.Reset.Format("15:04"))
+	}
+	return fmt.Sprintf("%s: add %d tokens or reduce QPS by %.0f%% to avoid exhaustion by %s", r.Resource, r.AddTransports, r.ReduceRate*100, r.Exhaustion.Format("15:04"))
+}
+
+// Recommendation projects the burn rate of the given resource type against the pool's capacity.
+func (bt *Balancer) Recommendation(resource Resource) (rec Recommendation) {
+	rec.Resource = resource
+	now := time.Now()
+
+	var members int
+	var totalLimit uint64
+	for _, transport := range bt.transports() {
+		rate := transport.Limits.Load(resource)
+		if rate == nil {
+			continue
+		}
+		members++
+		totalLimit += rate.Limit
+		rec.Remaining += rate.Remaining
+		rec.BurnRate += transport.Limits.BurnRate(resource)
+		if reset := rate.ResetTime(); reset.After(rec.Reset) {
+			rec.Reset = reset
+		}
+	}
+	if members == 0 || rec.BurnRate <= 0 || !rec.Reset.After(now) {
+		return rec
+	}
+
+	// Number of requests that will be needed before the last window resets.
+	needed := rec.BurnRate * rec.Reset.Sub(now).Seconds()
+	if needed <= float64(rec.Remaining) {
+		return rec
+	}
+	rec.Exhaustion = now.Add(time.Duration(float64(rec.Remaining) / rec.BurnRate * float64(time.Second)))
+	deficit := needed - float64(rec.Remaining)
+	rec.AddTransports = int(math.Ceil(deficit / (float64(totalLimit) / float64(members))))
+	rec.ReduceRate = deficit / needed
+	return rec
+}
@@ -0,0 +1,26 @@
+package ghratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancer_Recommendation(t *testing.T) {
+	reset := uint64(time.Now().Add(time.Hour).Unix())
+	transport := &Transport{}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 0, Remaining: 5000, Reset: reset})
+	rec := (&Balancer{Transports: []*Transport{transport}}).Recommendation(ResourceCore)
+	assert.True(t, rec.Exhaustion.IsZero(), "no burn rate should not project exhaustion")
+
+	// Simulate a window that started ~10 seconds ago and has burned 1000 requests.
+	transport.Limits.windows.Store(ResourceCore, &window{start: time.Now().Add(-10 * time.Second), reset: reset, used: 0})
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 1000, Remaining: 4000, Reset: reset})
+	rec = (&Balancer{Transports: []*Transport{transport}}).Recommendation(ResourceCore)
+	assert.InDelta(t, 100, rec.BurnRate, 5, "burn rate mismatch")
+	assert.False(t, rec.Exhaustion.IsZero(), "should project exhaustion")
+	assert.Greater(t, rec.AddTransports, 1, "should recommend more transports")
+	assert.Greater(t, rec.ReduceRate, 0.9, "should recommend reducing rate")
+	assert.Contains(t, rec.String(), "add ")
+}
@@ -0,0 +1,122 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRedeliveryBatchSize is the number of webhook redeliveries sent concurrently if Redeliverer.BatchSize is not set.
+const DefaultRedeliveryBatchSize = 10
+
+// Redeliverer redelivers webhook deliveries (ex: after an outage of the receiving service), a burst-heavy operation
+// that easily exhausts a token. Redeliveries are sent in batches spread across a Balancer's pool,
+// each batch waiting until the pool has enough core requests remaining.
+type Redeliverer struct {
+	// Pool is the Balancer used to send the redeliveries.
+	Pool *Balancer
+	// BaseURL is the URL of the GitHub API, if nil the Pool's BaseURL is used (see RateLimitURL).
+	BaseURL *url.URL
+	// BatchSize is the number of redeliveries sent concurrently, if zero DefaultRedeliveryBatchSize is used.
+	BatchSize int
+	// Reserve is the number of core requests left remaining across the pool for other traffic,
+	// once a batch would consume them it waits for the soonest rate-limit window to reset.
+	Reserve uint64
+	// OnResult is called with the outcome of each redelivery, if set.
+	// The response body is closed after OnResult returns.
+	OnResult func(id int64, resp *http.Response, err error)
+}
+
+// budget returns the core requests remaining across the pool and the soonest reset, ok is false if none are known.
+func (r *Redeliverer) budget() (remaining uint64, reset time.Time, ok bool) {
+	for _, transport := range r.Pool.transports() {
+		rate := transport.Limits.Load(ResourceCore)
+		if rate == nil {
+			continue
+		}
+		remaining += rate.Remaining
+		if !ok || rate.ResetTime().Before(reset) {
+			reset = rate.ResetTime()
+		}
+		ok = true
+	}
+	return remaining, reset, ok
+}
+
+// waitBudget blocks until the pool has enough core requests remaining for a batch of size n.
+func (r *Redeliverer) waitBudget(ctx context.Context, n int) error {
+	for {
+		remaining, reset, ok := r.budget()
+		if !ok || remaining >= r.Reserve+uint64(n) || !reset.After(SystemClock.Now()) {
+			return nil
+		}
+		if err := sleepUntil(ctx, SystemClock, reset, "core budget for webhook redeliveries", ResourceCore); err != nil {
+			return err
+		}
+	}
+}
+
+// redeliver sends a single redelivery of the webhook delivery.
+func (r *Redeliverer) redeliver(ctx context.Context, hook string, id int64) error {
+	base := r.BaseURL
+	if base == nil {
+		base = r.Pool.BaseURL
+	}
+	u := apiURL(base, hook+"/deliveries/"+strconv.FormatInt(id, 10)+"/attempts")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := r.Pool.RoundTrip(req)
+	if r.OnResult != nil {
+		r.OnResult(id, resp, err)
+	}
+	if err != nil {
+		return fmt.Errorf("redelivery of %d: %w", id, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("redelivery of %d: unexpected status %s", id, resp.Status)
+	}
+	return nil
+}
+
+// Redeliver redelivers each webhook delivery of the hook, returning the joined errors of any that failed.
+// The hook is the API path of the webhook, ex: "/repos/{owner}/{repo}/hooks/{hook_id}", "/orgs/{org}/hooks/{hook_id}"
+// or "/app/hook" (which requires the Pool to authenticate as the GitHub App).
+func (r *Redeliverer) Redeliver(ctx context.Context, hook string, ids []int64) error {
+	size := r.BatchSize
+	if size <= 0 {
+		size = DefaultRedeliveryBatchSize
+	}
+	var mu sync.Mutex
+	var errs []error
+	for batch := range slices.Chunk(ids, size) {
+		if err := r.waitBudget(ctx, len(batch)); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		var wg sync.WaitGroup
+		for _, id := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := r.redeliver(ctx, hook, id); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	return errors.Join(errs...)
+}
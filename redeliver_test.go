@@ -0,0 +1,50 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedeliverer_Redeliver(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	pool := &Balancer{}
+	for range 2 {
+		transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			paths = append(paths, req.Method+" "+req.URL.Path)
+			mu.Unlock()
+			status := http.StatusAccepted
+			if strings.Contains(req.URL.Path, "/3/") {
+				status = http.StatusNotFound
+			}
+			return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+		})}
+		transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 100, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+		pool.Transports = append(pool.Transports, transport)
+	}
+
+	r := &Redeliverer{Pool: pool, BatchSize: 2}
+	err := r.Redeliver(context.Background(), "/repos/o/r/hooks/1", []int64{1, 2, 3})
+	assert.ErrorContains(t, err, "redelivery of 3: unexpected status")
+	assert.ElementsMatch(t, []string{
+		"POST /repos/o/r/hooks/1/deliveries/1/attempts",
+		"POST /repos/o/r/hooks/1/deliveries/2/attempts",
+		"POST /repos/o/r/hooks/1/deliveries/3/attempts",
+	}, paths)
+
+	r.Reserve = 199
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = r.Redeliver(ctx, "/repos/o/r/hooks/1", []int64{4, 5})
+	var waitErr *WaitError
+	assert.ErrorAs(t, err, &waitErr, "batches should wait when they would consume the reserve")
+	assert.Len(t, paths, 3)
+}
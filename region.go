@@ -0,0 +1,118 @@
+package ghratelimit
+
+import (
+	"maps"
+	"net/http"
+	"sync"
+)
+
+// SpillReason is why a RegionFailover selected a transport outside its Local region.
+type SpillReason string
+
+const (
+	// SpillThreshold is when the remaining fraction of the Local region dropped below the Threshold.
+	SpillThreshold SpillReason = "threshold"
+	// SpillUnavailable is when no transport of the Local region was a candidate, ex: paused, unhealthy or exhausted.
+	SpillUnavailable SpillReason = "unavailable"
+)
+
+// RegionFailover is a Strategy that prefers the transports of the Local region until their remaining fraction drops below
+// the Threshold, then spills to the other regions, ex: balancing across nested Balancers per region (see Member)
+// with "prefer local until remaining < 20%". It keeps stats of how much traffic spilled and why, see Stats.
+// It must not be copied after first use.
+type RegionFailover struct {
+	// Region returns the region of a transport.
+	// If nil, the transport's Name is used.
+	Region func(*Transport) string
+	// Local is the preferred region.
+	Local string
+	// Threshold is the fraction (0.0 to 1.0) of the Local region's rate limit remaining below which requests spill,
+	// as long as another region has a higher fraction remaining.
+	Threshold float64
+	// Strategy selects among the transports of the chosen region.
+	// If nil, HighestRemaining is used.
+	Strategy Strategy
+
+	mu    sync.Mutex
+	stats RegionStats
+}
+
+// RegionStats are the selection stats of a RegionFailover.
+type RegionStats struct {
+	// Local is the number of selections kept in the Local region.
+	Local uint64
+	// Spilled is the number of selections that spilled to another region, by reason.
+	Spilled map[SpillReason]uint64
+}
+
+// region returns the region of the transport.
+func (rf *RegionFailover) region(transport *Transport) string {
+	if rf.Region != nil {
+		return rf.Region(transport)
+	}
+	return transport.Name
+}
+
+// remainingFraction returns the fraction of the aggregate rate limit of the transports remaining, 1 if it is unknown.
+func remainingFraction(transports []*Transport, resource Resource) float64 {
+	var limit, remaining uint64
+	for _, transport := range transports {
+		if rate := transport.Limits.Load(resource); rate != nil {
+			limit += rate.Limit
+			remaining += rate.Remaining
+		}
+	}
+	if limit == 0 {
+		return 1
+	}
+	return float64(remaining) / float64(limit)
+}
+
+// Select implements Strategy
+func (rf *RegionFailover) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	var local, remote []*Transport
+	for _, transport := range candidates {
+		if rf.region(transport) == rf.Local {
+			local = append(local, transport)
+		} else {
+			remote = append(remote, transport)
+		}
+	}
+	var reason SpillReason
+	switch {
+	case len(remote) == 0:
+	case len(local) == 0:
+		reason = SpillUnavailable
+	case remainingFraction(local, resource) < rf.Threshold && remainingFraction(remote, resource) > remainingFraction(local, resource):
+		reason = SpillThreshold
+	}
+	strategy := rf.Strategy
+	if strategy == nil {
+		strategy = HighestRemaining{}
+	}
+
+	rf.mu.Lock()
+	if reason == "" {
+		rf.stats.Local++
+	} else {
+		if rf.stats.Spilled == nil {
+			rf.stats.Spilled = make(map[SpillReason]uint64)
+		}
+		rf.stats.Spilled[reason]++
+	}
+	rf.mu.Unlock()
+
+	if reason == "" {
+		return strategy.Select(req, resource, local)
+	}
+	return strategy.Select(req, resource, remote)
+}
+
+// Stats returns a snapshot of the selection stats.
+func (rf *RegionFailover) Stats() RegionStats {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	stats := rf.stats
+	stats.Spilled = maps.Clone(rf.stats.Spilled)
+	return stats
+}
@@ -0,0 +1,28 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionFailover(t *testing.T) {
+	local, remote := withRemaining(2000), withRemaining(4000)
+	local.Name, remote.Name = "us-east", "eu-west"
+	rf := &RegionFailover{Local: "us-east", Threshold: 0.2}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+
+	assert.Same(t, local, rf.Select(req, ResourceCore, []*Transport{local, remote}), "the local region should be preferred")
+
+	local.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 500, Used: 4500})
+	assert.Same(t, remote, rf.Select(req, ResourceCore, []*Transport{local, remote}), "requests should spill below the threshold")
+	assert.Same(t, remote, rf.Select(req, ResourceCore, []*Transport{remote}), "requests should spill if the local region is unavailable")
+
+	remote.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 100, Used: 4900})
+	assert.Same(t, local, rf.Select(req, ResourceCore, []*Transport{local, remote}), "requests should not spill to a region with less remaining")
+
+	stats := rf.Stats()
+	assert.Equal(t, uint64(2), stats.Local)
+	assert.Equal(t, map[SpillReason]uint64{SpillThreshold: 1, SpillUnavailable: 1}, stats.Spilled)
+}
@@ -0,0 +1,73 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// registered is a resource added by RegisterResource.
+type registered struct {
+	resource Resource
+	match    Matcher
+}
+
+// resourceRegistry holds the resources added by RegisterResource, it is safe for concurrent use.
+type resourceRegistry struct {
+	mu      sync.RWMutex
+	entries []registered
+}
+
+// registry is the package-wide resourceRegistry used by RegisterResource, InferResource and Resource.Valid.
+var registry resourceRegistry
+
+// RegisterResource teaches the package a rate-limit resource it does not know, ex: one GitHub added since this release
+// or a custom resource of a GitHub Enterprise Server instance. The resource is then Valid, and if match is not nil,
+// requests it matches are inferred as the resource by InferResource (before the built-in heuristics, the first match wins).
+// Registering a resource again replaces its matcher. It is safe to call at runtime.
+func RegisterResource(resource Resource, match Matcher) {
+	registry.register(resource, match)
+}
+
+// UnregisterResource removes a resource added by RegisterResource.
+func UnregisterResource(resource Resource) {
+	registry.unregister(resource)
+}
+
+// register adds the resource, replacing any previous matcher.
+func (r *resourceRegistry) register(resource Resource, match Matcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = slices.DeleteFunc(slices.Clone(r.entries), func(e registered) bool { return e.resource == resource })
+	r.entries = append(r.entries, registered{resource: resource, match: match})
+}
+
+// unregister removes the resource.
+func (r *resourceRegistry) unregister(resource Resource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = slices.DeleteFunc(slices.Clone(r.entries), func(e registered) bool { return e.resource == resource })
+}
+
+// snapshot returns the registered resources, the slice is never modified in place.
+func (r *resourceRegistry) snapshot() []registered {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.entries
+}
+
+// valid reports if the resource is one of the ValidResources or was registered.
+func (r *resourceRegistry) valid(resource Resource) bool {
+	return slices.Contains(ValidResources, resource) ||
+		slices.ContainsFunc(r.snapshot(), func(e registered) bool { return e.resource == resource })
+}
+
+// match returns the first registered resource whose matcher matches the request, if any.
+func (r *resourceRegistry) match(req *http.Request) Resource {
+	for _, e := range r.snapshot() {
+		if e.match != nil && e.match(req) {
+			return e.resource
+		}
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package ghratelimit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"sync"
+)
+
+// ReadDeadLetters decodes the JSON lines written by a JSONDeadLetterSink, yielding each DeadLetter in order.
+func ReadDeadLetters(r io.Reader) iter.Seq2[*DeadLetter, error] {
+	return func(yield func(*DeadLetter, error) bool) {
+		dec := json.NewDecoder(bufio.NewReader(r))
+		for {
+			var dl DeadLetter
+			if err := dec.Decode(&dl); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(nil, fmt.Errorf("(*json.Decoder).Decode failed: %w", err))
+				return
+			}
+			if err := checkSchemaVersion(dl.SchemaVersion); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&dl, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Request reconstructs the original request from the DeadLetter, labelled with its caller and idempotency key.
+// Credentials were stripped when the DeadLetter was created, so they must be added by the RoundTripper.
+func (dl *DeadLetter) Request(ctx context.Context) (*http.Request, error) {
+	if dl.Caller != "" {
+		ctx = ContextWithCaller(ctx, dl.Caller)
+	}
+	if dl.IdempotencyKey != "" {
+		ctx = ContextWithIdempotencyKey(ctx, dl.IdempotencyKey)
+	}
+	var body io.Reader
+	if dl.Body != nil {
+		body = bytes.NewReader(dl.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, dl.Method, dl.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext for %q failed: %w", dl.URL, err)
+	}
+	for key, values := range dl.Header {
+		req.Header[key] = values
+	}
+	return req, nil
+}
+
+// Waiter blocks until there is budget available for the given resource type.
+// It is implemented by *Limits and *Balancer.
+type Waiter interface {
+	Wait(ctx context.Context, resource Resource) error
+}
+
+// Replayer re-executes dead-lettered requests once budget is available.
+// Requests from the same caller are replayed sequentially in their original order,
+// requests from different callers are replayed concurrently.
+type Replayer struct {
+	// Transport is the RoundTripper used to replay requests, typically a *Balancer.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Waiter, if set, is waited on before each request is replayed.
+	Waiter Waiter
+	// OnResult is called with the outcome of each replayed request, if set.
+	// The response body is closed after OnResult returns.
+	OnResult func(*DeadLetter, *http.Response, error)
+}
+
+// replay re-executes a single DeadLetter
+func (r *Replayer) replay(ctx context.Context, dl *DeadLetter) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("replay of %s %q: %w", dl.Method, dl.URL, context.Cause(ctx))
+	}
+	req, err := dl.Request(ctx)
+	if err != nil {
+		return err
+	}
+	resource := dl.Resource
+	if resource == "" {
+		resource = InferResource(req)
+	}
+	if r.Waiter != nil {
+		if err := r.Waiter.Wait(ctx, resource); err != nil {
+			return fmt.Errorf("replay of %s %q: %w", dl.Method, dl.URL, err)
+		}
+	}
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if r.OnResult != nil {
+		r.OnResult(dl, resp, err)
+	}
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("replay of %s %q: %w", dl.Method, dl.URL, err)
+	}
+	return nil
+}
+
+// callerQueue is the DeadLetters of a caller waiting to be replayed, drained by at most one goroutine at a time.
+type callerQueue struct {
+	mu      sync.Mutex
+	pending []*DeadLetter
+	running bool
+}
+
+// Replay re-executes every DeadLetter, returning the joined errors of any that failed.
+// A request that fails does not prevent later requests from the same caller from being replayed,
+// and a caller whose requests are slow does not hold up the requests of other callers.
+func (r *Replayer) Replay(ctx context.Context, letters iter.Seq2[*DeadLetter, error]) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	queues := make(map[string]*callerQueue)
+	drain := func(q *callerQueue) {
+		defer wg.Done()
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.running = false
+				q.mu.Unlock()
+				return
+			}
+			dl := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+			if err := r.replay(ctx, dl); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}
+	}
+	var readErr error
+	for dl, err := range letters {
+		if err != nil {
+			readErr = err
+			break
+		}
+		q, ok := queues[dl.Caller]
+		if !ok {
+			q = new(callerQueue)
+			queues[dl.Caller] = q
+		}
+		q.mu.Lock()
+		q.pending = append(q.pending, dl)
+		if !q.running {
+			q.running = true
+			wg.Add(1)
+			go drain(q)
+		}
+		q.mu.Unlock()
+	}
+	wg.Wait()
+	return errors.Join(append(errs, readErr)...)
+}
@@ -0,0 +1,97 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayer_Replay(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &JSONDeadLetterSink{Writer: &buf}
+	for _, path := range []string{"/repos/o/r/issues/1", "/repos/o/r/issues/2", "/repos/o/r/issues/3"} {
+		req, _ := http.NewRequestWithContext(ContextWithCaller(context.Background(), "a"), http.MethodPatch, "https://api.github.com"+path, strings.NewReader(path))
+		assert.Error(t, reject(sink, req, "", io.EOF))
+	}
+	req, _ := http.NewRequestWithContext(ContextWithCaller(context.Background(), "b"), http.MethodGet, "https://api.github.com/users/b", nil)
+	assert.Error(t, reject(sink, req, "", io.EOF))
+
+	var mu sync.Mutex
+	order := make(map[string][]string)
+	r := &Replayer{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := ""
+			if req.Body != nil {
+				b, _ := io.ReadAll(req.Body)
+				body = string(b)
+			}
+			mu.Lock()
+			order[CallerFromContext(req.Context())] = append(order[CallerFromContext(req.Context())], req.URL.Path+"="+body)
+			mu.Unlock()
+			return okResponse().RoundTrip(req)
+		}),
+		Waiter: &Limits{},
+	}
+	assert.NoError(t, r.Replay(context.Background(), ReadDeadLetters(&buf)), "(*Replayer).Replay failed")
+	assert.Equal(t, map[string][]string{
+		"a": {"/repos/o/r/issues/1=/repos/o/r/issues/1", "/repos/o/r/issues/2=/repos/o/r/issues/2", "/repos/o/r/issues/3=/repos/o/r/issues/3"},
+		"b": {"/users/b="},
+	}, order, "requests should be replayed in order per caller")
+}
+
+func TestReplayer_Replay_SlowCaller(t *testing.T) {
+	release := make(chan struct{})
+	replayed := make(chan string, 1)
+	r := &Replayer{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if CallerFromContext(req.Context()) == "slow" {
+			<-release
+		} else {
+			replayed <- req.URL.Path
+		}
+		return okResponse().RoundTrip(req)
+	})}
+	letters := func(yield func(*DeadLetter, error) bool) {
+		for range 100 {
+			if !yield(&DeadLetter{Caller: "slow", Method: http.MethodGet, URL: "https://api.github.com/users/slow"}, nil) {
+				return
+			}
+		}
+		yield(&DeadLetter{Caller: "fast", Method: http.MethodGet, URL: "https://api.github.com/users/fast"}, nil)
+	}
+	done := make(chan error)
+	go func() { done <- r.Replay(context.Background(), letters) }()
+	select {
+	case path := <-replayed:
+		assert.Equal(t, "/users/fast", path)
+	case <-time.After(time.Second):
+		t.Fatal("a slow caller should not hold up other callers")
+	}
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func TestReplayer_Replay_ReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := &Replayer{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, io.ErrUnexpectedEOF
+	})}
+	letters := func(yield func(*DeadLetter, error) bool) {
+		for _, caller := range []string{"a", "b", "c"} {
+			if !yield(&DeadLetter{Caller: caller, Method: http.MethodGet, URL: "https://api.github.com/users/" + caller}, nil) {
+				return
+			}
+		}
+		yield(nil, errRead)
+	}
+	err := r.Replay(context.Background(), letters)
+	assert.ErrorIs(t, err, errRead)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
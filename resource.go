@@ -2,7 +2,6 @@ package ghratelimit
 
 import (
 	"net/http"
-	"slices"
 )
 
 // Resource represents the X-Ratelimit-Resource header value.
@@ -47,8 +46,27 @@ const (
 
 	// Code Search API's rate limit.
 	ResourceCodeSearch Resource = "code_search"
+
+	// ResourceUntracked is a pseudo-resource for traffic that does not consume any GitHub rate limit,
+	// ex: content downloads from raw.githubusercontent.com or codeload.github.com.
+	// It is never throttled and is only reported in usage stats, see (*Transport).Usage.
+	ResourceUntracked Resource = "untracked"
+
+	// ResourceOther is a pseudo-resource for requests whose resource type could not be inferred (see InferResource).
+	// Like ResourceUntracked it is never throttled and is only reported in usage stats.
+	ResourceOther Resource = "other"
 )
 
+// UntrackedHosts are the hosts whose requests are inferred as ResourceUntracked.
+// Modifying this slice at runtime may result in undefined behavior.
+var UntrackedHosts = []string{
+	"raw.githubusercontent.com",
+	"codeload.github.com",
+	"objects.githubusercontent.com",
+	"media.githubusercontent.com",
+	"github-cloud.githubusercontent.com",
+}
+
 // ValidResources represents the list of valid/known rate-limit resources.
 // Modifying this slice at runtime may result in undefined behavior.
 var ValidResources = []Resource{
@@ -65,12 +83,15 @@ func (r Resource) String() string {
 	return string(r)
 }
 
-// Valid checks if the resource is valid/known.
+// Valid checks if the resource is valid/known, either one of the ValidResources or added by RegisterResource.
 func (r Resource) Valid() bool {
-	return slices.Contains(ValidResources, r)
+	return registry.valid(r)
 }
 
 // ParseResource extracts the Resource from the X-RateLimit-Resource header of the HTTP response.
 func ParseResource(headers http.Header) Resource {
-	return Resource(headers.Get("X-RateLimit-Resource"))
+	if vals := headers["X-Ratelimit-Resource"]; len(vals) > 0 {
+		return Resource(vals[0])
+	}
+	return ""
 }
@@ -13,3 +13,25 @@ func TestParseResource(t *testing.T) {
 	})
 	assert.Equal(t, ResourceCore, resource, "mismatch")
 }
+
+func TestRegisterResource(t *testing.T) {
+	const resource Resource = "copilot_usage"
+	var r resourceRegistry
+	assert.False(t, r.valid(resource))
+	assert.True(t, r.valid(ResourceCore))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/orgs/o/copilot/usage", nil)
+	assert.Equal(t, ResourceCore, r.infer(req))
+
+	r.register(resource, MatchPathPrefix("/orgs/o/copilot/"))
+	assert.True(t, r.valid(resource), "registered resources should be valid")
+	assert.Equal(t, resource, r.infer(req), "registered matcher should be consulted")
+	assert.False(t, resource.Valid(), "the package registry should be untouched")
+
+	r.register(resource, nil)
+	assert.True(t, r.valid(resource))
+	assert.Equal(t, ResourceCore, r.infer(req), "registering again should replace the matcher")
+
+	r.unregister(resource)
+	assert.False(t, r.valid(resource))
+}
@@ -0,0 +1,52 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ResponseInfo is everything Limits observed in a response, passed to NotifyResponse
+// so observability hooks can correlate rate limits with specific requests.
+type ResponseInfo struct {
+	// Response is the response the information was parsed from, its body must not be read.
+	Response *http.Response `json:"-"`
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"status_code"`
+	// RequestID is the X-GitHub-Request-Id of the response, to quote when contacting GitHub support.
+	RequestID string `json:"request_id,omitempty"`
+	// ContentType is the Content-Type of the response.
+	ContentType string `json:"content_type,omitempty"`
+	// Resource is the rate-limit resource of the response, if known.
+	Resource Resource `json:"resource,omitempty"`
+	// Rate is the rate limit of the response, nil if it had no (or malformed) rate-limit headers.
+	Rate *Rate `json:"rate,omitempty"`
+	// RetryAfter is the Retry-After of the response, zero if it had none.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+	// Deprecation is set if the response signalled its endpoint is deprecated.
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+}
+
+// ParseResponseInfo parses everything Limits observes in a response.
+// A malformed rate-limit header is returned as an error alongside the rest of the information.
+func ParseResponseInfo(resp *http.Response) (*ResponseInfo, error) {
+	resource, rate, err := FromResponse(resp)
+	info := &ResponseInfo{
+		Response:    resp,
+		StatusCode:  resp.StatusCode,
+		RequestID:   resp.Header.Get("X-GitHub-Request-Id"),
+		ContentType: resp.Header.Get("Content-Type"),
+		Resource:    resource,
+		Rate:        rate,
+		Deprecation: ParseDeprecation(resp.Header),
+	}
+	info.RetryAfter, _ = ParseRetryAfter(resp.Header)
+	if info.Deprecation != nil && resp.Request != nil {
+		info.Deprecation.Method, info.Deprecation.Resource = resp.Request.Method, resource
+		if resp.Request.URL != nil {
+			info.Deprecation.Path = strings.TrimPrefix(resp.Request.URL.Path, "/api/v3")
+			info.Deprecation.Route = routeTemplate(info.Deprecation.Path)
+		}
+	}
+	return info, err
+}
@@ -0,0 +1,46 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_NotifyResponse(t *testing.T) {
+	var infos []*ResponseInfo
+	limits := &Limits{NotifyResponse: func(info *ResponseInfo) { infos = append(infos, info) }}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/search/issues?q=foo", nil)
+	resp := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header: http.Header{
+			"X-Github-Request-Id":   []string{"C0DE:1234:5678"},
+			"Content-Type":          []string{"application/json; charset=utf-8"},
+			"Retry-After":           []string{"60"},
+			"Deprecation":           []string{"@1688169599"},
+			"X-Ratelimit-Resource":  []string{"search"},
+			"X-Ratelimit-Limit":     []string{"30"},
+			"X-Ratelimit-Remaining": []string{"0"},
+			"X-Ratelimit-Used":      []string{"30"},
+			"X-Ratelimit-Reset":     []string{"1745121612"},
+		},
+		Request: req,
+	}
+	assert.NoError(t, limits.Parse(resp))
+	resp = &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Request: req}
+	assert.NoError(t, limits.Parse(resp))
+
+	if assert.Len(t, infos, 2, "every response should be notified") {
+		info := infos[0]
+		assert.Equal(t, http.StatusForbidden, info.StatusCode)
+		assert.Equal(t, "C0DE:1234:5678", info.RequestID)
+		assert.Equal(t, "application/json; charset=utf-8", info.ContentType)
+		assert.Equal(t, ResourceSearch, info.Resource)
+		assert.Equal(t, uint64(0), info.Rate.Remaining)
+		assert.Equal(t, time.Minute, info.RetryAfter)
+		assert.Equal(t, "/search/issues", info.Deprecation.Path)
+		assert.Nil(t, infos[1].Rate)
+		assert.Nil(t, infos[1].Deprecation)
+	}
+}
@@ -0,0 +1,110 @@
+package ghratelimit
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures automatic retries of requests that were rejected due to rate limiting.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	// If less than 2, requests are never retried.
+	MaxAttempts int
+	// MinBackoff is the initial backoff between attempts, doubled after each attempt and randomized with full jitter.
+	MinBackoff time.Duration
+	// MaxBackoff, if non-zero, caps the exponential backoff.
+	// Requests that would need to wait longer than MaxBackoff (ex: for the Retry-After or X-RateLimit-Reset) are not retried.
+	MaxBackoff time.Duration
+	// Retryable decides if the attempt should be retried.
+	// If nil, DefaultRetryable is used.
+	Retryable func(resource Resource, resp *http.Response, err error) bool
+}
+
+// DefaultRetryable retries secondary rate limits, primary rate limits detected by ClassifyForbidden,
+// 429 responses and 403 responses with no requests remaining or a Retry-After header.
+func DefaultRetryable(resource Resource, resp *http.Response, err error) bool {
+	var secondary *SecondaryRateLimitError
+	if errors.As(err, &secondary) || errors.Is(err, ErrPrimaryRateLimited) {
+		return true
+	}
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("X-Ratelimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// retryable decides if the attempt should be retried per the policy.
+func (p *RetryPolicy) retryable(resource Resource, resp *http.Response, err error) bool {
+	if p.Retryable == nil {
+		return DefaultRetryable(resource, resp, err)
+	}
+	return p.Retryable(resource, resp, err)
+}
+
+// delay calculates how long to wait before the next attempt, returning false if it exceeds MaxBackoff.
+func (p *RetryPolicy) delay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	var wait time.Duration
+	var secondary *SecondaryRateLimitError
+	var rlErr *RateLimitError
+	if errors.As(err, &secondary) {
+		wait = secondary.RetryAfter
+	} else if errors.Is(err, ErrPrimaryRateLimited) && errors.As(err, &rlErr) {
+		wait = min(rlErr.RetryAfter(), MaxResetDelay)
+	} else if resp != nil {
+		if retryAfter, ok := ParseRetryAfter(resp.Header); ok {
+			wait = retryAfter
+		} else if resp.Header.Get("X-Ratelimit-Remaining") == "0" {
+			if reset, err := strconv.ParseUint(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+				wait = min(max(time.Until((&Rate{Reset: reset}).ResetTime()), 0), MaxResetDelay)
+			}
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		return 0, false
+	}
+	if p.MinBackoff > 0 {
+		backoff := p.MinBackoff << min(attempt, 32)
+		if backoff <= 0 || (p.MaxBackoff > 0 && backoff > p.MaxBackoff) {
+			backoff = p.MaxBackoff
+		}
+		if backoff > 0 {
+			wait = max(wait, time.Duration(rand.Int63n(int64(backoff)+1)))
+		}
+	}
+	return wait, true
+}
+
+// retryPolicy returns the effective RetryPolicy for the Transport, or nil if retries are disabled.
+func (t *Transport) retryPolicy() *RetryPolicy {
+	if t.Retry != nil {
+		return t.Retry
+	}
+	if t.SecondaryRateLimitRetries > 0 {
+		return &RetryPolicy{
+			MaxAttempts: t.SecondaryRateLimitRetries + 1,
+			Retryable: func(resource Resource, resp *http.Response, err error) bool {
+				_, ok := err.(*SecondaryRateLimitError)
+				return ok
+			},
+		}
+	}
+	return nil
+}
+
+// discard drains and closes the response body so the connection can be reused.
+func discard(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+}
@@ -0,0 +1,63 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tooManyThenOK returns a RoundTripper that responds with n 429s (with the given Retry-After) before a 200 OK.
+func tooManyThenOK(n int, retryAfter string, attempts *int) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*attempts++
+		if *attempts <= n {
+			header := http.Header{}
+			if retryAfter != "" {
+				header.Set("Retry-After", retryAfter)
+			}
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		return okResponse().RoundTrip(req)
+	})
+}
+
+func TestTransport_Retry(t *testing.T) {
+	var attempts int
+	transport := &Transport{
+		Base:  tooManyThenOK(2, "", &attempts),
+		Retry: &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("payload"))
+	resp, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "(*Transport).RoundTrip failed")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	transport.Base = tooManyThenOK(1, "3600", &attempts)
+	transport.Retry.MaxBackoff = time.Second
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err = transport.RoundTrip(req)
+	var secondary *SecondaryRateLimitError
+	assert.ErrorAs(t, err, &secondary, "Retry-After beyond MaxBackoff should not be retried")
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	transport.Base = tooManyThenOK(1, "", &attempts)
+	transport.Retry.Retryable = func(resource Resource, resp *http.Response, err error) bool {
+		return resource != ResourceCore
+	}
+	resp, err = transport.RoundTrip(req)
+	assert.NoError(t, err, "(*Transport).RoundTrip failed")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "custom Retryable should be respected")
+	assert.Equal(t, 1, attempts)
+}
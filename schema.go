@@ -0,0 +1,23 @@
+package ghratelimit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the JSON encoding of the state exported by this package, embedded as "schema_version"
+// in state files (see Limits.SaveFile), dead-letters, events and AdminHandler responses so external tooling can detect changes.
+// Fields may be added within a version, removing, renaming or changing the meaning of a field increments it.
+const SchemaVersion = 1
+
+// ErrSchemaVersion is returned when decoding JSON encoded with a newer SchemaVersion than this package supports.
+var ErrSchemaVersion = errors.New("unsupported schema version")
+
+// checkSchemaVersion returns an error wrapping ErrSchemaVersion if the version is newer than SchemaVersion.
+// A zero version is JSON encoded before versioning (or by GitHub, ex: a /rate_limit response) and is accepted.
+func checkSchemaVersion(version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: %d > %d", ErrSchemaVersion, version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SecondaryRateLimitError is returned by (*Transport).RoundTrip when GitHub responds with a secondary rate limit
+// (abuse detection) error, signalled by a 403 or 429 status code with a Retry-After header,
+// if the Transport retries them (see Retry and SecondaryRateLimitRetries) or ClassifyForbidden is set.
+type SecondaryRateLimitError struct {
+	// Resource is the inferred rate-limit resource of the request.
+	Resource Resource
+	// RetryAfter is how long GitHub asked to wait before retrying.
+	RetryAfter time.Duration
+	// Response is the response from GitHub, its Body can be read again.
+	Response *http.Response
+}
+
+// Error implements error
+func (e *SecondaryRateLimitError) Error() string {
+	return fmt.Sprintf("secondary rate limit exceeded for %s, retry after %s", e.Resource, e.RetryAfter)
+}
+
+// ParseRetryAfter extracts the duration from the Retry-After header, which is either a number of seconds or a HTTP date.
+// It returns false if the header is missing or malformed, durations are clamped to MaxResetDelay.
+func ParseRetryAfter(headers http.Header) (time.Duration, bool) {
+	val := headers.Get("Retry-After")
+	if val == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(val, 10, 32); err == nil {
+		return min(time.Duration(seconds)*time.Second, MaxResetDelay), true
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		return min(max(time.Until(date), 0), MaxResetDelay), true
+	}
+	return 0, false
+}
+
+// secondaryRateLimit detects a secondary rate limit response, returning nil if it is not one.
+// If detected, the response body is consumed and closed, the returned error holds a re-readable copy.
+func secondaryRateLimit(resp *http.Response, resource Resource) (*SecondaryRateLimitError, error) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, nil
+	}
+	retryAfter, ok := ParseRetryAfter(resp.Header)
+	if !ok {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return nil, fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return &SecondaryRateLimitError{
+		Resource:   resource,
+		RetryAfter: retryAfter,
+		Response:   resp,
+	}, nil
+}
+
+// rewind returns a copy of the request with a fresh body so it can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("cannot retry request with a body but no GetBody: %q", req.URL)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("(*http.Request).GetBody failed: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
@@ -0,0 +1,74 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	d, ok := ParseRetryAfter(http.Header{"Retry-After": []string{"60"}})
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+
+	_, ok = ParseRetryAfter(http.Header{})
+	assert.False(t, ok, "missing header")
+
+	_, ok = ParseRetryAfter(http.Header{"Retry-After": []string{"invalid"}})
+	assert.False(t, ok, "malformed header")
+}
+
+// secondaryThenOK returns a RoundTripper that responds with n secondary rate limits before a 200 OK.
+func secondaryThenOK(n int, attempts *int) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*attempts++
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != "payload" {
+				return nil, io.ErrUnexpectedEOF
+			}
+		}
+		if *attempts <= n {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Header:     http.Header{"Retry-After": []string{"0"}},
+				Body:       io.NopCloser(strings.NewReader(`{"message":"You have exceeded a secondary rate limit."}`)),
+				Request:    req,
+			}, nil
+		}
+		return okResponse().RoundTrip(req)
+	})
+}
+
+func TestTransport_SecondaryRateLimit(t *testing.T) {
+	var attempts int
+	transport := &Transport{Base: secondaryThenOK(1, &attempts)}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("payload"))
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err, "without retries the response should be returned as-is") {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+
+	attempts = 0
+	transport.ClassifyForbidden = true
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("payload"))
+	_, err = transport.RoundTrip(req)
+	var secondary *SecondaryRateLimitError
+	assert.ErrorAs(t, err, &secondary, "expected *SecondaryRateLimitError")
+	assert.Equal(t, ResourceCore, secondary.Resource)
+	body, _ := io.ReadAll(secondary.Response.Body)
+	assert.Contains(t, string(body), "secondary rate limit", "body should be re-readable")
+
+	attempts = 0
+	transport.ClassifyForbidden = false
+	transport.SecondaryRateLimitRetries = 1
+	req, _ = http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/issues", strings.NewReader("payload"))
+	resp, err = transport.RoundTrip(req)
+	assert.NoError(t, err, "(*Transport).RoundTrip should retry")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
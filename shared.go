@@ -0,0 +1,170 @@
+package ghratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// SharedStore shares rate limits between processes using the same credential, ex: replicas backed by Redis.
+// Implementations must be safe for concurrent use.
+type SharedStore interface {
+	// Get returns the value stored for the key, or false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key, expiring it after the TTL.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// SharedTimeout bounds every call to a SharedStore.
+const SharedTimeout = time.Second
+
+// sharedTTL is how long a rate limit is kept in the SharedStore after its window resets.
+const sharedTTL = time.Minute
+
+// fresher returns whichever rate limit reflects the most recent state of the window, preferring a.
+// A later reset is a newer window, within the same window the lower remaining is the more recent.
+func fresher(a, b *Rate) *Rate {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.Reset != b.Reset:
+		if b.Reset > a.Reset {
+			return b
+		}
+		return a
+	case b.Remaining < a.Remaining:
+		return b
+	}
+	return a
+}
+
+// ErrSharedKeyRequired is returned by (*Limits).SyncShared if Shared is set without a SharedKey,
+// as credentials sharing a store would otherwise merge each other's rate limits.
+var ErrSharedKeyRequired = errors.New("ghratelimit: Limits.SharedKey is required when Shared is set")
+
+// sharedKey returns the SharedStore key for the resource type.
+func (l *Limits) sharedKey(resource Resource) string {
+	return l.SharedKey + ":" + resource.String()
+}
+
+// sharing reports if the SharedStore should be used, emitting an EventSharedError the first time it is set without a SharedKey.
+func (l *Limits) sharing() bool {
+	if l.Shared == nil {
+		return false
+	}
+	if l.SharedKey == "" {
+		if l.sharedKeyMissing.CompareAndSwap(false, true) {
+			l.emit(Event{Kind: EventSharedError, Message: ErrSharedKeyRequired.Error()})
+		}
+		return false
+	}
+	return true
+}
+
+// sharedContext returns the context for a call to the SharedStore made while handling the response, bounded by SharedTimeout.
+func sharedContext(resp *http.Response) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if resp != nil && resp.Request != nil {
+		ctx = resp.Request.Context()
+	}
+	return context.WithTimeout(ctx, SharedTimeout)
+}
+
+// loadShared returns the rate limit for the resource type from the SharedStore, if any.
+func (l *Limits) loadShared(ctx context.Context, resource Resource) (*Rate, error) {
+	b, ok, err := l.Shared.Get(ctx, l.sharedKey(resource))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var rate Rate
+	if err := json.Unmarshal(b, &rate); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal for %q failed: %w", l.sharedKey(resource), err)
+	}
+	return &rate, nil
+}
+
+// storeShared merges the rate limit for the resource type into the SharedStore, keeping whichever is fresher,
+// and returns the merged rate limit if it is known.
+// Unless the store merges atomically (see SharedMerger), concurrent writers may briefly overwrite a fresher value
+// until the next response.
+func (l *Limits) storeShared(ctx context.Context, resource Resource, rate *Rate) *Rate {
+	merger, merge := l.Shared.(SharedMerger)
+	merge = merge && l.negotiate(ctx).Has(SharedCapabilityMerge)
+	if !merge {
+		shared, err := l.loadShared(ctx, resource)
+		if err != nil {
+			l.emit(Event{Kind: EventSharedError, Resource: resource, Message: err.Error()})
+		}
+		rate = fresher(rate, shared)
+	}
+	b, err := json.Marshal(rate)
+	if err != nil {
+		l.emit(Event{Kind: EventSharedError, Resource: resource, Message: err.Error()})
+		return nil
+	}
+	ttl := max(rate.ResetTime().Sub(l.clock().Now()), 0) + sharedTTL
+	if merge {
+		err = merger.Merge(ctx, l.sharedKey(resource), b, ttl)
+		rate = nil // the merged value is not returned
+	} else {
+		err = l.Shared.Set(ctx, l.sharedKey(resource), b, ttl)
+	}
+	if err != nil {
+		l.emit(Event{Kind: EventSharedError, Resource: resource, Message: err.Error()})
+	}
+	return rate
+}
+
+// mergeLocal stores the rate limit read from the SharedStore if it is fresher than the one observed by this process.
+func (l *Limits) mergeLocal(resource Resource, rate *Rate) {
+	for {
+		prev, loaded := l.m.Load(resource)
+		if !loaded {
+			if _, loaded = l.m.LoadOrStore(resource, rate); !loaded {
+				return
+			}
+			continue
+		}
+		if prevRate, ok := prev.(*Rate); ok && fresher(prevRate, rate) == prevRate {
+			return
+		}
+		if l.m.CompareAndSwap(resource, prev, rate) {
+			return
+		}
+	}
+}
+
+// SyncShared refreshes the rate limits of this process from the SharedStore, keeping whichever is fresher.
+// Load never consults the SharedStore, so it should be called periodically, (*Transport).Poll does so on every poll.
+// The rate limits of the resource types already known and of the ValidResources are refreshed.
+func (l *Limits) SyncShared(ctx context.Context) error {
+	if l.Shared == nil {
+		return nil
+	}
+	if !l.sharing() {
+		return ErrSharedKeyRequired
+	}
+	l.negotiate(ctx)
+	resources := slices.Clone(ValidResources)
+	for resource := range l.Iter() {
+		if !slices.Contains(resources, resource) {
+			resources = append(resources, resource)
+		}
+	}
+	var errs []error
+	for _, resource := range resources {
+		rate, err := l.loadShared(ctx, resource)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resource %q: %w", resource, err))
+		} else if rate != nil {
+			l.mergeLocal(resource, rate)
+		}
+	}
+	return errors.Join(errs...)
+}
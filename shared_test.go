@@ -0,0 +1,71 @@
+package ghratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySharedStore is a SharedStore backed by a map, ignoring TTLs.
+type memorySharedStore struct {
+	mu sync.Mutex
+	m  map[string][]byte
+}
+
+func (s *memorySharedStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.m[key]
+	return b, ok, nil
+}
+
+func (s *memorySharedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(map[string][]byte)
+	}
+	s.m[key] = value
+	return nil
+}
+
+func TestLimits_Shared(t *testing.T) {
+	store := &memorySharedStore{}
+	replicaA := &Limits{Shared: store, SharedKey: "app"}
+	replicaB := &Limits{Shared: store, SharedKey: "app"}
+
+	replicaA.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+	assert.Nil(t, replicaB.Load(ResourceCore), "Load should not consult the SharedStore")
+	require.NoError(t, replicaB.SyncShared(t.Context()))
+	assert.Equal(t, uint64(4900), replicaB.Load(ResourceCore).Remaining, "replica should see the shared rate limit")
+	assert.Contains(t, replicaB.String(), "Remaining: 4900", "String should agree with Load")
+
+	replicaB.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 50, Remaining: 4950, Reset: 1745121612})
+	assert.Equal(t, uint64(4900), replicaB.Load(ResourceCore).Remaining, "stale update should not win")
+	assert.Equal(t, uint64(4900), replicaA.Load(ResourceCore).Remaining)
+
+	replicaB.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 1, Remaining: 4999, Reset: 1745125212})
+	require.NoError(t, replicaA.SyncShared(t.Context()))
+	assert.Equal(t, uint64(4999), replicaA.Load(ResourceCore).Remaining, "new window should win")
+
+	other := &Limits{Shared: store, SharedKey: "other"}
+	require.NoError(t, other.SyncShared(t.Context()))
+	assert.Nil(t, other.Load(ResourceCore), "keys should be isolated by SharedKey")
+}
+
+func TestLimits_SharedKeyRequired(t *testing.T) {
+	store := &memorySharedStore{}
+	var events []Event
+	limits := &Limits{Shared: store, OnEvent: func(e Event) { events = append(events, e) }}
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 100, Remaining: 4900, Reset: 1745121612})
+	limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Used: 1, Remaining: 29, Reset: 1745121612})
+	assert.Empty(t, store.m, "nothing should be shared without a SharedKey")
+	if assert.Len(t, events, 1, "the missing SharedKey should be reported once") {
+		assert.Equal(t, EventSharedError, events[0].Kind)
+	}
+	assert.ErrorIs(t, limits.SyncShared(t.Context()), ErrSharedKeyRequired)
+}
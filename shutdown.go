@@ -0,0 +1,51 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Shutdown flushes the transport before the process exits, ex: at the end of a CLI tool or batch job: it stops the
+// transport from being selected, waits for the in-flight requests to finish (so their rate limits are parsed and Notify,
+// OnEvent and the Hooks are called for them) and then writes the limits to the StateFile (see Checkpoint).
+// The wait is bounded by ctx, if it is done first the StateFile is still written and a *WaitError is returned.
+func (t *Transport) Shutdown(ctx context.Context) error {
+	err := t.Drain(ctx)
+	return errors.Join(err, t.Checkpoint())
+}
+
+// Shutdown calls (*Transport).Shutdown for every transport concurrently, returning the joined errors of any that failed.
+func (bt *Balancer) Shutdown(ctx context.Context) error {
+	var wg sync.WaitGroup
+	transports := bt.transports()
+	errs := make([]error, len(transports))
+	for idx, transport := range transports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := transport.Shutdown(ctx); err != nil {
+				errs[idx] = fmt.Errorf("transport %s: %w", bt.identify(transport), err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Shutdown waits for the responses being revalidated in the background (see StaleWhileRevalidate) to be stored,
+// so their rate limits are observed before the process exits. If ctx is done first, a *WaitError is returned.
+func (ct *CachingTransport) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ct.background.Wait()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return &WaitError{Op: "revalidations to finish", Err: context.Cause(ctx)}
+	}
+}
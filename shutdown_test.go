@@ -0,0 +1,53 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	var notified atomic.Int64
+	transport := &Transport{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			<-release
+			resp, _ := okResponse().RoundTrip(req)
+			resp.Header.Set("X-RateLimit-Resource", "core")
+			resp.Header.Set("X-RateLimit-Limit", "5000")
+			resp.Header.Set("X-RateLimit-Remaining", "4321")
+			resp.Header.Set("X-RateLimit-Used", "679")
+			resp.Header.Set("X-RateLimit-Reset", "1745121612")
+			return resp, nil
+		}),
+	}
+	transport.Limits.Notify = func(*http.Response, Resource, *Rate) { notified.Add(1) }
+	bt := &Balancer{Transports: []*Transport{transport}}
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		if resp, err := bt.RoundTrip(req); err == nil {
+			discard(resp)
+		}
+	}()
+	assert.Eventually(t, func() bool { return transport.InFlight() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var waitErr *WaitError
+	assert.ErrorAs(t, bt.Shutdown(ctx), &waitErr, "the timeout should bound the wait")
+
+	close(release)
+	assert.NoError(t, bt.Shutdown(context.Background()))
+	assert.Equal(t, int64(1), notified.Load(), "the in-flight response should be notified before returning")
+	restored := Limits{Clock: beforeReset}
+	assert.NoError(t, restored.LoadFile(transport.StateFile))
+	assert.Equal(t, uint64(4321), restored.Load(ResourceCore).Remaining, "the final rate limit should be checkpointed")
+}
@@ -0,0 +1,30 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// ToggleDebugOnSignal flips decision logging on or off every time one of the signals (ex: syscall.SIGUSR1) is received,
+// until ctx is done.
+func ToggleDebugOnSignal(ctx context.Context, sig ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				enabled := !Debug()
+				SetDebug(enabled)
+				log.Printf("ghratelimit: debug logging enabled: %t\n", enabled)
+			}
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+package ghratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPrimaryRateLimited is returned by ClassifyResponse for a response rejected by the primary rate limit.
+	ErrPrimaryRateLimited = errors.New("primary rate limit exceeded")
+	// ErrSecondaryRateLimited is returned by ClassifyResponse for a response rejected by a secondary rate limit,
+	// every *SecondaryRateLimitError matches it via errors.Is.
+	ErrSecondaryRateLimited = errors.New("secondary rate limit exceeded")
+	// ErrForbidden is returned by ClassifyResponse for a 403 response that was not rate limited, ex: missing permissions.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// DefaultSecondaryRetryAfter is how long to wait after a secondary rate limit without a Retry-After header,
+// GitHub asks for at least a minute.
+const DefaultSecondaryRetryAfter = time.Minute
+
+// Is reports if the target is ErrSecondaryRateLimited.
+func (e *SecondaryRateLimitError) Is(target error) bool {
+	return target == ErrSecondaryRateLimited
+}
+
+// ClassifyResponse distinguishes why GitHub rejected a request with a 403 or 429 status code, as it uses them both
+// for permission errors and for rate limiting, by inspecting the headers and the message and documentation_url of the
+// JSON error body. It returns ErrPrimaryRateLimited, ErrSecondaryRateLimited, ErrForbidden or nil for other status codes.
+// The body is consumed and closed, then replaced with a re-readable copy so later readers are unaffected.
+func ClassifyResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Message          string `json:"message"`
+		DocumentationURL string `json:"documentation_url"`
+	}
+	_ = json.Unmarshal(body, &payload) // a body that is not JSON is classified by the headers alone
+	message, docs := strings.ToLower(payload.Message), strings.ToLower(payload.DocumentationURL)
+	switch {
+	case strings.Contains(message, "secondary rate limit"), strings.Contains(message, "abuse detection"), strings.Contains(docs, "secondary-rate-limit"):
+		return ErrSecondaryRateLimited
+	case strings.Contains(message, "api rate limit exceeded"), resp.Header.Get("X-Ratelimit-Remaining") == "0":
+		return ErrPrimaryRateLimited
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return ErrSecondaryRateLimited
+	default:
+		return ErrForbidden
+	}
+}
+
+// classifyRateLimit converts a rate-limited response into a *SecondaryRateLimitError or a *RateLimitError
+// wrapping ErrPrimaryRateLimited, see ClassifyForbidden. Other responses are returned as-is with a nil error.
+func (t *Transport) classifyRateLimit(req *http.Request, resp *http.Response, resource Resource) error {
+	switch err := ClassifyResponse(resp); {
+	case errors.Is(err, ErrSecondaryRateLimited):
+		retryAfter, ok := ParseRetryAfter(resp.Header)
+		if !ok {
+			retryAfter = DefaultSecondaryRetryAfter
+		}
+		return &SecondaryRateLimitError{Resource: resource, RetryAfter: retryAfter, Response: resp}
+	case errors.Is(err, ErrPrimaryRateLimited):
+		rlErr := &RateLimitError{Resource: resource, Err: ErrPrimaryRateLimited}
+		if reset, err := strconv.ParseUint(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			rlErr.Reset = (&Rate{Reset: reset}).ResetTime()
+		} else if rate := t.Limits.Load(resource); rate != nil {
+			rlErr.Reset = rate.ResetTime()
+		}
+		return t.throttled(req, resource, rlErr)
+	case errors.Is(err, ErrForbidden):
+		return nil
+	default:
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// forbidden returns a response with the status code, headers and JSON error body.
+func forbidden(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestClassifyResponse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		resp     *http.Response
+		expected error
+	}{
+		"secondary message": {forbidden(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`), ErrSecondaryRateLimited},
+		"secondary docs":    {forbidden(http.StatusForbidden, nil, `{"message":"slow down","documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`), ErrSecondaryRateLimited},
+		"primary message":   {forbidden(http.StatusForbidden, nil, `{"message":"API rate limit exceeded for user ID 1."}`), ErrPrimaryRateLimited},
+		"primary header":    {forbidden(http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": []string{"0"}}, `not json`), ErrPrimaryRateLimited},
+		"429":               {forbidden(http.StatusTooManyRequests, nil, ``), ErrSecondaryRateLimited},
+		"forbidden":         {forbidden(http.StatusForbidden, nil, `{"message":"Resource not accessible by integration"}`), ErrForbidden},
+		"ok":                {forbidden(http.StatusOK, nil, `{}`), nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ClassifyResponse(tc.resp))
+		})
+	}
+
+	resp := forbidden(http.StatusForbidden, nil, `{"message":"Must have admin rights to Repository."}`)
+	assert.ErrorIs(t, ClassifyResponse(resp), ErrForbidden)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"message":"Must have admin rights to Repository."}`, string(body), "the body should be re-readable")
+}
+
+func TestTransport_ClassifyForbidden(t *testing.T) {
+	var resp *http.Response
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return resp, nil
+	})}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+
+	resp = forbidden(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit."}`)
+	got, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "classification should be opt-in")
+	discard(got)
+
+	transport.ClassifyForbidden = true
+	resp = forbidden(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit."}`)
+	_, err = transport.RoundTrip(req)
+	var secondary *SecondaryRateLimitError
+	if assert.ErrorAs(t, err, &secondary) {
+		assert.ErrorIs(t, err, ErrSecondaryRateLimited)
+		assert.Equal(t, DefaultSecondaryRetryAfter, secondary.RetryAfter)
+	}
+
+	resp = forbidden(http.StatusForbidden, nil, `{"message":"API rate limit exceeded for installation ID 1."}`)
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrPrimaryRateLimited)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.True(t, DefaultRetryable(ResourceCore, nil, err), "primary rate limits should be retryable")
+
+	resp = forbidden(http.StatusForbidden, nil, `{"message":"Resource not accessible by integration"}`)
+	got, err = transport.RoundTrip(req)
+	if assert.NoError(t, err, "authorization failures should be returned as-is") {
+		assert.Equal(t, http.StatusForbidden, got.StatusCode)
+		body, _ := io.ReadAll(got.Body)
+		assert.Contains(t, string(body), "Resource not accessible")
+	}
+}
@@ -0,0 +1,71 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"time"
+)
+
+// staleTolerance is how long after a rate limit is stored that an update appearing older is ignored.
+// Out-of-order responses arrive within moments of each other, an update after this is trusted even if it appears older
+// (ex: a request that was counted but then refunded by GitHub).
+const staleTolerance = 5 * time.Second
+
+// stale reports if the rate limit from a response is older than the previously stored one. Within the same window
+// GitHub's Used only grows, so a lower Used than the last stored one (tracked by o, if any request was dispatched
+// optimistically) is always stale. Otherwise it is stale if from an earlier window, or from the same window with more
+// remaining, and prev was received within the staleTolerance.
+func (l *Limits) stale(resp *http.Response, resource Resource, o *optimistic, prev, rate *Rate, now time.Time) bool {
+	if l.AcceptStale {
+		return false
+	}
+	if o != nil && rate.Reset == o.reset && rate.Used < o.used {
+		return true
+	}
+	if resp == nil || prev == nil || now.After(prev.ResetTime()) {
+		return false
+	}
+	if _, ok := l.aborted.Load(resource); ok {
+		return false
+	}
+	val, ok := l.received.Load(resource)
+	if !ok || now.Sub(val.(time.Time)) > staleTolerance {
+		return false
+	}
+	switch {
+	case rate.Reset != prev.Reset:
+		return rate.Reset < prev.Reset
+	default:
+		return rate.Remaining > prev.Remaining
+	}
+}
+
+// swap stores the rate limit adjusted for requests still pending (see dispatch) unless it is stale, returning the stored
+// and previous rate limits. The freshness check and the optimistic state are committed together with the store.
+func (l *Limits) swap(resp *http.Response, resource Resource, rate *Rate, now time.Time) (stored, prev *Rate, ok bool) {
+	o := l.optimisticFor(resource, false)
+	if o != nil {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+	}
+	for {
+		val, loaded := l.m.Load(resource)
+		prev, _ = val.(*Rate)
+		if l.stale(resp, resource, o, prev, rate, now) {
+			return nil, prev, false
+		}
+		stored = o.adjust(rate)
+		if !loaded {
+			if _, loaded = l.m.LoadOrStore(resource, stored); !loaded {
+				break
+			}
+			continue
+		}
+		if l.m.CompareAndSwap(resource, val, stored) {
+			break
+		}
+	}
+	if o != nil {
+		o.reset, o.used = rate.Reset, rate.Used
+	}
+	return stored, prev, true
+}
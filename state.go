@@ -0,0 +1,62 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"maps"
+)
+
+// MarshalJSON implements json.Marshaler, encoding the limits in the same format as the /rate_limit endpoint (plus the SchemaVersion).
+func (l *Limits) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		SchemaVersion int                `json:"schema_version"`
+		Resources     map[Resource]*Rate `json:"resources"`
+	}{
+		SchemaVersion: SchemaVersion,
+		Resources:     maps.Collect(l.Iter()),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, storing every resource in the same format as the /rate_limit endpoint.
+// Resources whose window has already reset are skipped, as their counts no longer apply.
+// Existing resources that are not present are left as-is.
+func (l *Limits) UnmarshalJSON(b []byte) error {
+	var limits struct {
+		SchemaVersion int               `json:"schema_version"`
+		Resources     map[Resource]Rate `json:"resources"`
+	}
+	if err := json.Unmarshal(b, &limits); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(limits.SchemaVersion); err != nil {
+		return err
+	}
+	now := l.clock().Now()
+	for resource, rate := range limits.Resources {
+		if !now.Before(rate.ResetTime()) {
+			continue
+		}
+		l.Store(nil, resource, &rate)
+	}
+	return nil
+}
+
+// restore loads the limits from the StateFile (once), before the transport is first used.
+func (t *Transport) restore() {
+	if t.StateFile == "" {
+		return
+	}
+	t.restoreOnce.Do(func() {
+		if err := t.Limits.LoadFile(t.StateFile); err != nil {
+			t.Limits.emit(Event{Kind: EventStateError, Message: err.Error()})
+		}
+	})
+}
+
+// Checkpoint writes the limits to the StateFile, if set.
+// It is called after every (*Transport).Poll interval and should be called before the process exits, see Shutdown.
+func (t *Transport) Checkpoint() error {
+	if t.StateFile == "" {
+		return nil
+	}
+	return t.Limits.SaveFile(t.StateFile)
+}
@@ -0,0 +1,55 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SaveFile atomically writes the limits to the file at path, so they can be restored after a restart with LoadFile.
+func (l *Limits) SaveFile(path string) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("json.Marshal failed: %w", err)
+	}
+	return writeFile(path, b)
+}
+
+// writeFile atomically replaces the file at path with b, via a temporary file in the same directory.
+func writeFile(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp for %q failed: %w", path, err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("(*os.File).Write for %q failed: %w", f.Name(), err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("(*os.File).Close for %q failed: %w", f.Name(), err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("os.Rename to %q failed: %w", path, err)
+	}
+	return nil
+}
+
+// LoadFile restores the limits previously written by SaveFile, a missing file is not an error.
+func (l *Limits) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("os.ReadFile for %q failed: %w", path, err)
+	}
+	if err := json.Unmarshal(b, l); err != nil {
+		return fmt.Errorf("json.Unmarshal for %q failed: %w", path, err)
+	}
+	return nil
+}
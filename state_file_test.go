@@ -0,0 +1,27 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_StateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	transport := &Transport{Base: okResponse(), StateFile: path}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 4990, Remaining: 10, Reset: 1745121612})
+	assert.NoError(t, transport.Checkpoint(), "(*Transport).Checkpoint failed")
+
+	restarted := &Transport{Base: okResponse(), StateFile: path, Limits: Limits{Clock: beforeReset}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := restarted.RoundTrip(req)
+	assert.NoError(t, err, "(*Transport).RoundTrip failed")
+	assert.Equal(t, &Rate{Limit: 5000, Used: 4990, Remaining: 10, Reset: 1745121612}, restarted.Limits.Load(ResourceCore), "state should be restored on first use")
+
+	var missing Limits
+	assert.NoError(t, missing.LoadFile(filepath.Join(t.TempDir(), "missing.json")), "missing file is not an error")
+}
@@ -0,0 +1,18 @@
+//go:build ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SaveFile is unsupported when built with the ghratelimit_minimal tag, it always fails with errors.ErrUnsupported.
+func (l *Limits) SaveFile(path string) error {
+	return fmt.Errorf("(*Limits).SaveFile for %q: %w", path, errors.ErrUnsupported)
+}
+
+// LoadFile is unsupported when built with the ghratelimit_minimal tag, it always fails with errors.ErrUnsupported.
+func (l *Limits) LoadFile(path string) error {
+	return fmt.Errorf("(*Limits).LoadFile for %q: %w", path, errors.ErrUnsupported)
+}
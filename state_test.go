@@ -0,0 +1,51 @@
+package ghratelimit
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedClock is a Clock whose Now is fixed, ex: before the reset of the fixtures.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time                         { return time.Time(c) }
+func (c fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (c fixedClock) NewTicker(d time.Duration) Ticker       { return systemClock{}.NewTicker(d) }
+
+// beforeReset is a fixedClock before the reset (1745121612) of the fixtures.
+var beforeReset = fixedClock(time.Unix(1745121600, 0))
+
+func TestLimits_JSON(t *testing.T) {
+	limits := Limits{Clock: beforeReset}
+	assert.NoError(t, json.Unmarshal([]byte(limitsResponse), &limits), "json.Unmarshal failed")
+	assert.Equal(t, &Rate{Limit: 5000, Used: 0, Remaining: 5000, Reset: 1745121612}, limits.Load(ResourceCore))
+
+	b, err := json.Marshal(&limits)
+	assert.NoError(t, err, "json.Marshal failed")
+	restored := Limits{Clock: beforeReset}
+	assert.NoError(t, json.Unmarshal(b, &restored), "json.Unmarshal failed")
+	assert.Equal(t, maps.Collect(limits.Iter()), maps.Collect(restored.Iter()))
+
+	expired := Limits{Clock: fixedClock(time.Unix(1745121612, 0))}
+	assert.NoError(t, json.Unmarshal(b, &expired), "json.Unmarshal failed")
+	assert.Nil(t, expired.Load(ResourceCore), "windows that already reset should not be restored")
+}
+
+func TestLimits_JSON_SchemaVersion(t *testing.T) {
+	var limits Limits
+	limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 4999, Used: 1, Reset: 1745121612})
+	b, err := json.Marshal(&limits)
+	assert.NoError(t, err, "json.Marshal failed")
+	assert.Contains(t, string(b), `"schema_version":1`)
+
+	err = json.Unmarshal([]byte(`{"schema_version":99,"resources":{}}`), &Limits{})
+	assert.ErrorIs(t, err, ErrSchemaVersion, "newer schema versions should be rejected")
+
+	b, err = json.Marshal(Event{Kind: EventExhausted, Resource: ResourceCore, Message: "exhausted"})
+	assert.NoError(t, err, "json.Marshal failed")
+	assert.JSONEq(t, `{"schema_version":1,"kind":"exhausted","resource":"core","message":"exhausted"}`, string(b))
+}
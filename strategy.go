@@ -0,0 +1,136 @@
+package ghratelimit
+
+import (
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// Strategy selects which transport in a Balancer executes a request.
+type Strategy interface {
+	// Select returns the transport from candidates that should execute the request for the given resource type.
+	// Candidates is never empty. If nil is returned, a random candidate is used.
+	Select(req *http.Request, resource Resource, candidates []*Transport) *Transport
+}
+
+// StrategyFunc adapts a function into a Strategy.
+type StrategyFunc func(req *http.Request, resource Resource, candidates []*Transport) *Transport
+
+// Select implements Strategy
+func (f StrategyFunc) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	return f(req, resource, candidates)
+}
+
+// available reports if the transport is not known to be exhausted for the resource type.
+func available(transport *Transport, resource Resource) bool {
+	rate := transport.Limits.Load(resource)
+	return rate == nil || rate.Remaining > 0
+}
+
+// weighted returns the remaining rate limit of the transport scaled by its Weight.
+func weighted(transport *Transport, remaining uint64) float64 {
+	weight := transport.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return float64(remaining) * weight
+}
+
+// HighestRemaining selects the transport with the highest remaining rate limit (scaled by its Weight).
+// It is the default Strategy of a Balancer.
+type HighestRemaining struct{}
+
+// Select implements Strategy
+func (HighestRemaining) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	var bestTransport *Transport
+	var bestRemaining float64
+	for _, transport := range candidates {
+		if rate := transport.Limits.Load(resource); rate != nil {
+			if remaining := weighted(transport, rate.Remaining); remaining > bestRemaining {
+				bestRemaining = remaining
+				bestTransport = transport
+			}
+		}
+	}
+	return bestTransport
+}
+
+// RoundRobin selects each transport in turn, skipping transports known to be exhausted.
+// It must not be copied after first use.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+// Select implements Strategy
+func (rr *RoundRobin) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	start := rr.next.Add(1) - 1
+	for offset := range uint64(len(candidates)) {
+		transport := candidates[(start+offset)%uint64(len(candidates))]
+		if available(transport, resource) {
+			return transport
+		}
+	}
+	return nil
+}
+
+// WeightedRandom selects a random transport with a probability proportional to its remaining rate limit
+// (scaled by its Weight), which avoids flapping between transports with similar remaining rate limits.
+type WeightedRandom struct{}
+
+// Select implements Strategy
+func (WeightedRandom) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	var total float64
+	remaining := make([]float64, len(candidates))
+	for idx, transport := range candidates {
+		if rate := transport.Limits.Load(resource); rate != nil {
+			remaining[idx] = weighted(transport, rate.Remaining)
+			total += remaining[idx]
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	n := rand.Float64() * total
+	for idx, transport := range candidates {
+		if n < remaining[idx] {
+			return transport
+		}
+		n -= remaining[idx]
+	}
+	return nil
+}
+
+// LeastRecentlyUsed selects the transport that has gone the longest without executing a request,
+// skipping transports known to be exhausted.
+type LeastRecentlyUsed struct{}
+
+// Select implements Strategy
+func (LeastRecentlyUsed) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	var bestTransport *Transport
+	var bestUsed int64
+	for _, transport := range candidates {
+		if !available(transport, resource) {
+			continue
+		}
+		if used := transport.lastUsed.Load(); bestTransport == nil || used < bestUsed {
+			bestTransport, bestUsed = transport, used
+		}
+	}
+	return bestTransport
+}
+
+// FirstAboveThreshold selects the first transport (in order) with at least Threshold requests remaining,
+// draining transports one at a time. If no transport is above the threshold, HighestRemaining is used.
+type FirstAboveThreshold struct {
+	Threshold uint64
+}
+
+// Select implements Strategy
+func (s FirstAboveThreshold) Select(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+	for _, transport := range candidates {
+		if rate := transport.Limits.Load(resource); rate != nil && rate.Remaining >= s.Threshold {
+			return transport
+		}
+	}
+	return HighestRemaining{}.Select(req, resource, candidates)
+}
@@ -0,0 +1,127 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withRemaining returns a Transport with the given core remaining rate limit.
+func withRemaining(remaining uint64) *Transport {
+	transport := &Transport{Base: okResponse()}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: remaining, Used: 5000 - remaining})
+	return transport
+}
+
+func TestStrategies(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	a, b, c := withRemaining(100), withRemaining(0), withRemaining(4000)
+	candidates := []*Transport{a, b, c}
+
+	assert.Same(t, c, HighestRemaining{}.Select(req, ResourceCore, candidates), "HighestRemaining")
+	assert.Same(t, a, FirstAboveThreshold{Threshold: 50}.Select(req, ResourceCore, candidates), "FirstAboveThreshold")
+	assert.Same(t, c, FirstAboveThreshold{Threshold: 5000}.Select(req, ResourceCore, candidates), "FirstAboveThreshold fallback")
+
+	var rr RoundRobin
+	assert.Same(t, a, rr.Select(req, ResourceCore, candidates), "RoundRobin first")
+	assert.Same(t, c, rr.Select(req, ResourceCore, candidates), "RoundRobin should skip exhausted")
+	assert.Same(t, c, rr.Select(req, ResourceCore, candidates), "RoundRobin third")
+	assert.Same(t, a, rr.Select(req, ResourceCore, candidates), "RoundRobin wraps")
+
+	a.lastUsed.Store(3)
+	b.lastUsed.Store(1)
+	c.lastUsed.Store(2)
+	assert.Same(t, c, LeastRecentlyUsed{}.Select(req, ResourceCore, candidates), "LeastRecentlyUsed should skip exhausted")
+
+	counts := make(map[*Transport]int)
+	for range 1000 {
+		counts[WeightedRandom{}.Select(req, ResourceCore, candidates)]++
+	}
+	assert.Zero(t, counts[b], "WeightedRandom should never select exhausted")
+	assert.Greater(t, counts[c], counts[a], "WeightedRandom should prefer higher remaining")
+}
+
+func TestBalancer_Strategy(t *testing.T) {
+	a, b := withRemaining(100), withRemaining(200)
+	bt := &Balancer{
+		Transports: []*Transport{a, b},
+		Strategy: StrategyFunc(func(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+			return candidates[0]
+		}),
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := bt.RoundTrip(req)
+	assert.NoError(t, err, "(*Balancer).RoundTrip failed")
+	assert.NotZero(t, a.lastUsed.Load(), "custom strategy should be used")
+	assert.Zero(t, b.lastUsed.Load(), "custom strategy should be used")
+}
+
+func TestBalancer_SetStrategy(t *testing.T) {
+	a, b := withRemaining(100), withRemaining(200)
+	var events []Event
+	bt := &Balancer{
+		Transports: []*Transport{a, b},
+		OnEvent:    func(event Event) { events = append(events, event) },
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, b, selected, "default strategy should be HighestRemaining")
+
+	bt.SetStrategy(FirstAboveThreshold{Threshold: 50})
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, a, selected, "replaced strategy should be used")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventStrategyChanged, events[0].Kind)
+		assert.Equal(t, "ghratelimit.HighestRemaining -> ghratelimit.FirstAboveThreshold", events[0].Message)
+	}
+}
+
+func TestBalancer_ResourceStrategies(t *testing.T) {
+	a, b := withRemaining(100), withRemaining(200)
+	a.Limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 20})
+	b.Limits.Store(nil, ResourceSearch, &Rate{Limit: 30, Remaining: 10})
+	bt := &Balancer{
+		Transports:         []*Transport{a, b},
+		Strategy:           HighestRemaining{},
+		ResourceStrategies: map[Resource]Strategy{ResourceCore: FirstAboveThreshold{Threshold: 50}},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, a, selected, "core should use its own strategy")
+	selected, _ = bt.selectTransport(req, ResourceSearch, nil)
+	assert.Same(t, a, selected, "search should use the default strategy")
+	bt.SetStrategy(FirstAboveThreshold{Threshold: 15})
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, a, selected, "resource strategies should take precedence over SetStrategy")
+}
+
+func TestBalancer_FloorsAndWeights(t *testing.T) {
+	pat, app := withRemaining(1000), withRemaining(800)
+	bt := &Balancer{Transports: []*Transport{pat, app}}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	selected, _ := bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, pat, selected)
+
+	app.Weight = 2
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, app, selected, "higher weighted transports should be preferred when remaining counts are comparable")
+
+	bt.Floors = map[Resource]uint64{ResourceCore: 900}
+	app.Weight = 0
+	pat.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 950})
+	app.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 850})
+	bt.Strategy = StrategyFunc(func(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+		assert.Equal(t, []*Transport{pat}, candidates, "transports below the floor should be skipped")
+		return nil
+	})
+	selected, _ = bt.selectTransport(req, ResourceCore, nil)
+	assert.Same(t, pat, selected)
+
+	bt.Strategy = StrategyFunc(func(req *http.Request, resource Resource, candidates []*Transport) *Transport {
+		assert.Len(t, candidates, 2, "high priority requests should ignore the floor")
+		return nil
+	})
+	req, _ = http.NewRequestWithContext(ContextWithPriority(req.Context(), PriorityHigh), http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, _ = bt.selectTransport(req, ResourceCore, nil)
+}
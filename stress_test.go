@@ -0,0 +1,88 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitHub returns a RoundTripper that serves /rate_limit and emits random rate-limit headers for everything else.
+func fakeGitHub() http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/rate_limit" {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(limitsResponse)),
+				Request:    req,
+			}, nil
+		}
+		remaining := rand.Intn(5000)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"5000"},
+				"X-Ratelimit-Used":      []string{strconv.Itoa(5000 - remaining)},
+				"X-Ratelimit-Remaining": []string{strconv.Itoa(remaining)},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+				"X-Ratelimit-Resource":  []string{"core"},
+			},
+			Body:    io.NopCloser(strings.NewReader("{}")),
+			Request: req,
+		}, nil
+	})
+}
+
+// TestStress exercises concurrent pollers, round trips and readers, it is most useful with -race.
+func TestStress(t *testing.T) {
+	bt := &Balancer{}
+	for range 4 {
+		bt.Transports = append(bt.Transports, &Transport{Base: fakeGitHub(), RampUp: time.Millisecond})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bt.Poll(ctx, time.Millisecond, nil)
+	}()
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+				resp, err := bt.RoundTrip(req)
+				if err == nil {
+					discard(resp)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				for _, transport := range bt.Transports {
+					_ = transport.Limits.String()
+					_ = transport.Limits.BurnRate(ResourceCore)
+				}
+				_ = bt.Recommendation(ResourceCore)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, transport := range bt.Transports {
+		assert.NotNil(t, transport.Limits.Load(ResourceCore), "limits should be populated")
+	}
+}
@@ -0,0 +1,34 @@
+package ghratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrHostNotAllowed is returned by a Strict Transport for requests to hosts that are not known GitHub endpoints.
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// allowed reports if a Strict transport may send the request: its host must be the host of the BaseURL
+// (api.github.com by default), one of the UntrackedHosts or one of the AllowedHosts.
+func (t *Transport) allowed(req *http.Request) error {
+	if !t.Strict {
+		return nil
+	}
+	if req.URL == nil {
+		return fmt.Errorf("%w: request without a URL", ErrHostNotAllowed)
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	base := DefaultURL
+	if t.BaseURL != nil {
+		base = t.BaseURL
+	}
+	if host != "" && (host == strings.ToLower(base.Hostname()) || slices.Contains(UntrackedHosts, host) || slices.ContainsFunc(t.AllowedHosts, func(allowed string) bool {
+		return strings.EqualFold(allowed, host)
+	})) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q is not a configured GitHub endpoint", ErrHostNotAllowed, req.URL.Host)
+}
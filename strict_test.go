@@ -0,0 +1,39 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Strict(t *testing.T) {
+	transport := &Transport{Base: okResponse(), Strict: true, AllowedHosts: []string{"uploads.github.com"}}
+	for _, u := range []string{
+		"https://api.github.com/users/bored-engineer",
+		"https://API.github.com:443/users/bored-engineer",
+		"https://uploads.github.com/repos/o/r/releases/1/assets",
+		"https://raw.githubusercontent.com/o/r/main/README.md",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		_, err := transport.RoundTrip(req)
+		assert.NoError(t, err, "%s should be allowed", u)
+	}
+	for _, u := range []string{
+		"https://api.github.com.evil.example/users/bored-engineer",
+		"https://api.githhub.com/users/bored-engineer",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		_, err := transport.RoundTrip(req)
+		assert.ErrorIs(t, err, ErrHostNotAllowed, "%s should be rejected", u)
+	}
+
+	transport.BaseURL, _ = url.Parse("https://github.example.com/api/v3/")
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	_, err := transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrHostNotAllowed, "only the configured BaseURL should be allowed")
+	req, _ = http.NewRequest(http.MethodGet, "https://github.example.com/api/v3/users/bored-engineer", nil)
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,48 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// StaleRule allows a CachingTransport to serve cached responses to matching requests while revalidating them in the background.
+type StaleRule struct {
+	// Match reports if the rule applies to the request, ex: MatchPathPrefix("/repos/").
+	Match Matcher
+	// MaxStale is the maximum age of a cached response that is served immediately,
+	// older responses are revalidated before being served.
+	MaxStale time.Duration
+}
+
+// serveStale reports if the cached response may be served immediately, see StaleWhileRevalidate.
+func (ct *CachingTransport) serveStale(req *http.Request, cached *CachedResponse) bool {
+	if minimal || CacheBypassFromContext(req.Context()) {
+		return false
+	}
+	for _, rule := range ct.StaleWhileRevalidate {
+		if rule.Match(req) {
+			return time.Since(cached.Stored) <= rule.MaxStale
+		}
+	}
+	return false
+}
+
+// revalidate refreshes the cached response in the background, unless it is already being revalidated.
+// The request is sent with PriorityLow so it only spends quota not reserved for other work (see Transport.Reserve),
+// and is not cancelled with the original request.
+func (ct *CachingTransport) revalidate(req *http.Request, key string, cached *CachedResponse) {
+	if _, loaded := ct.revalidating.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	ctx := ContextWithPriority(context.WithoutCancel(req.Context()), PriorityLow)
+	req = req.Clone(ctx)
+	ct.background.Add(1)
+	go func() {
+		defer ct.background.Done()
+		defer ct.revalidating.Delete(key)
+		if resp, err := ct.fetch(ct.base(), req, key, cached, true); err == nil {
+			discard(resp)
+		}
+	}()
+}
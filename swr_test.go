@@ -0,0 +1,50 @@
+//go:build !ghratelimit_minimal
+
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingTransport_StaleWhileRevalidate(t *testing.T) {
+	var requests []*http.Request
+	base := etagServer(`"v1"`, `{"name":"r"}`, &requests)
+	revalidated := make(chan *http.Request)
+	ct := &CachingTransport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("If-None-Match") != "" {
+				revalidated <- req
+			}
+			return base.RoundTrip(req)
+		}),
+		Store:                &MemoryCacheStore{},
+		StaleWhileRevalidate: []StaleRule{{Match: MatchPathPrefix("/repos/"), MaxStale: time.Hour}},
+	}
+
+	get := func() string {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+		resp, err := ct.RoundTrip(req)
+		assert.NoError(t, err, "(*CachingTransport).RoundTrip failed")
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	assert.Equal(t, `{"name":"r"}`, get())
+	assert.Equal(t, `{"name":"r"}`, get(), "stale response should be served without waiting for revalidation")
+	assert.Equal(t, `{"name":"r"}`, get(), "concurrent revalidations should be coalesced")
+
+	req := <-revalidated
+	assert.Equal(t, PriorityLow, PriorityFromContext(req.Context()), "revalidation should be low priority")
+	assert.Eventually(t, func() bool { return ct.Stats().Hits == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(2), ct.Stats().Stale)
+
+	cached, _ := ct.Store.Get(CacheKey(req))
+	cached.Stored = time.Now().Add(-2 * time.Hour)
+	go func() { <-revalidated }()
+	assert.Equal(t, `{"name":"r"}`, get(), "responses older than MaxStale should be revalidated before being served")
+	assert.Equal(t, uint64(2), ct.Stats().Stale)
+}
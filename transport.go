@@ -2,49 +2,457 @@ package ghratelimit
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Transport updates the Limits field with the most recent rate-limit information as responses from GitHub are executed.
 // It implements the http.RoundTripper interface, so it can be used as a base transport for http.Client.
 type Transport struct {
+	// Name identifies the transport's credential (ex: the token's owner or the GitHub App's slug), it is reported
+	// instead of the transport's index by errors, Status, debug logs, profiler labels and metrics. As Limits.Notify and
+	// Limits.OnEvent are set per transport, they can capture the name themselves.
+	Name string
 	// Base is the base RoundTripper used to make HTTP requests.
 	// If nil, http.DefaultTransport is used.
 	Base http.RoundTripper
+	// BaseURL is the base URL of the REST API (ex: a GitHub Enterprise Server instance) that Poll fetches the limits from,
+	// see RateLimitURL. If nil, api.github.com is used.
+	BaseURL *url.URL
 	// Limits is the most recent rate-limit information
 	Limits Limits
+	// RampUp, if non-zero, gradually introduces the transport into a Balancer.
+	// Its selection weight ramps linearly from 0% to 100% over the duration, starting when it is first considered for selection.
+	RampUp time.Duration
+	// Weight scales the remaining rate limit of the transport when a Balancer's HighestRemaining or WeightedRandom
+	// strategy compares it with others, ex: 2 to prefer an App token over a PAT with comparable remaining requests.
+	// If zero, a weight of 1 is used.
+	Weight float64
+	// Standby, if true, excludes the transport from selection in a Balancer
+	// unless the remaining capacity of the active transports drops below its StandbyThreshold.
+	Standby bool
+	// WaitOnExhaustion, if true, blocks requests while the inferred resource has no requests remaining,
+	// until its rate-limit window resets or the request's context is done, instead of sending a request that will be rejected.
+	// Requests that would wait longer than their latency budget fail immediately instead, see ContextWithLatencyBudget.
+	WaitOnExhaustion bool
+	// SecondaryRateLimitRetries is the number of times a request that hits a secondary rate limit
+	// is retried after sleeping for the Retry-After duration, after which a *SecondaryRateLimitError is returned.
+	// It is ignored if Retry is set. Unless either is set (or ClassifyForbidden is), secondary rate limit responses
+	// are returned as-is.
+	SecondaryRateLimitRetries int
+	// RetryAborted is the number of times an idempotent request (by method, or with an Idempotency-Key header) is retried
+	// immediately if the connection is lost before its response, ex: by a HTTP/2 GOAWAY or a connection reset.
+	RetryAborted int
+	// ClassifyForbidden, if true, inspects the body of 403 and 429 responses (see ClassifyResponse) so that rate limits
+	// GitHub did not signal via headers are still detected: secondary rate limits are returned as a *SecondaryRateLimitError
+	// and primary rate limits as a *RateLimitError wrapping ErrPrimaryRateLimited. Other 403 responses are returned as-is.
+	ClassifyForbidden bool
+	// Retry, if set, automatically retries requests that were rejected due to rate limiting.
+	Retry *RetryPolicy
+	// DeadLetter, if set, receives a sanitized description of every request rejected by the Transport.
+	DeadLetter DeadLetterSink
+	// InspectGraphQL, if true, inspects the body of successful GraphQL responses for a queried rateLimit object
+	// and updates the graphql rate limit with the actual point cost of the query, see (*Limits).ParseGraphQL.
+	InspectGraphQL bool
+	// StateFile, if set, is the path the limits are restored from before the transport is first used,
+	// and checkpointed to after every Poll interval (see Checkpoint), so a restarted process does not start blind.
+	StateFile string
+	// MaxConcurrency, if non-zero, is the maximum number of in-flight requests, additional requests block until a slot is available.
+	// GitHub enforces a maximum of ~100 concurrent requests per token. A Balancer skips transports at their maximum.
+	MaxConcurrency int
+	// MaxDownloadConcurrency, if non-zero, is the maximum number of in-flight downloads (requests of ResourceUntracked,
+	// ex: Git LFS objects and release or artifact archives). Downloads then use their own pool instead of MaxConcurrency,
+	// so slow transfers of large files do not hold the slots API calls need.
+	MaxDownloadConcurrency int
+	// Pace lists the resource types (ex: ResourceSearch) whose requests are delayed to spread the remaining requests
+	// evenly over the rate-limit window, rather than exhausting them in a burst. Waits are bounded by the request's context.
+	Pace []Resource
+	// Reserve is the fraction (0.0 to 1.0) of every resource's rate limit reserved for requests of each priority (see ContextWithPriority).
+	// Once the remaining requests drop into the quota reserved for higher priorities, lower priority requests are queued until the
+	// rate-limit window resets (or their context is done), ex: {PriorityHigh: 0.1} keeps the last 10% for user-facing requests.
+	// A Balancer prefers transports where the request's priority is not queued.
+	Reserve map[Priority]float64
+	// CostEstimator, if set, estimates how many points a request will consume (ex: GraphQL queries or expensive endpoints),
+	// so WaitOnExhaustion, Pace and HardCap account for it instead of assuming 1 per request.
+	CostEstimator func(*http.Request) uint64
+	// ProfilerLabels, if true, labels the goroutine executing each request with its resource type, the transport's Name in a
+	// Balancer (or its fingerprint, see Balancer.Fingerprint, if unnamed) and whether it is being throttled,
+	// so CPU and goroutine profiles can be sliced by quota behavior.
+	ProfilerLabels bool
+	// HardCap is the maximum number of requests sent per rate-limit window for each resource type,
+	// enforced even if GitHub's headers report more remaining, ex: to leave part of a shared enterprise quota to other systems.
+	// Requests over the cap fail with ErrHardCapReached, or wait for the window to reset if WaitOnExhaustion is set.
+	HardCap map[Resource]uint64
+	// Optimistic, if true, decrements the remaining requests as each request is dispatched rather than waiting for its response,
+	// so a burst of concurrent requests does not over-commit a nearly exhausted token. Responses reconcile it from GitHub's headers.
+	Optimistic bool
+	// PollPolicy, if set, adapts the interval of Poll to the remaining requests and resets rather than polling at a fixed interval.
+	PollPolicy *PollPolicy
+	// OnPollError, if set, is called with every error from Poll instead of logging it, ex: to alert on a revoked token.
+	// Consecutive failures back off exponentially (up to MaxPollBackoff) either way.
+	OnPollError func(error)
+	// Strict, if true, rejects requests to hosts other than the BaseURL's (api.github.com by default), the UntrackedHosts
+	// and the AllowedHosts with ErrHostNotAllowed, so a misconfigured URL cannot leak the credential to a lookalike domain.
+	Strict bool
+	// AllowedHosts are additional hosts a Strict transport may send requests to, ex: uploads.github.com.
+	AllowedHosts []string
+	// APIVersion, if set, pins the X-GitHub-Api-Version of every request (overriding any set on the request),
+	// ex: while operating against GHES instances mid-upgrade. ContextWithAPIVersion overrides it per request.
+	APIVersion string
+	// Breaker, if set, stops sending requests for a cool-down period after consecutive rate-limited or server error responses.
+	Breaker *CircuitBreaker
+	// Hooks, if set, are called as each attempt of a request is dispatched and its response parsed.
+	Hooks *Hooks
+	// DryRun, if true, never sends requests: each is answered with a synthetic response that consumes the transport's Limits
+	// (seed them first, ex: via (*Limits).Fetch with the Base or a StateFile), or a 403 once they are exhausted.
+	// It allows running a job in "what-if" mode to measure how much quota it would consume, see Accounting.
+	DryRun bool
+	// ParseErrorPolicy, if set, decides if a response with malformed rate-limit headers is returned or fails the request.
+	// By default the response is returned, see ParseErrorPolicy.
+	ParseErrorPolicy ParseErrorPolicy
+	// OrderingKey, if set, returns the ordering key of requests without one set by ContextWithOrderingKey,
+	// requests sharing a key are sent one at a time in submission order, ex: SCIMOrderingKey.
+	OrderingKey func(*http.Request) string
+	// Member, if set, sends the requests instead of Base. The rate limits it reports are copied into the Limits (instead of
+	// parsing them from its responses) after each response and as a Balancer selects a transport, and the
+	// transport is only Healthy while it is, see RateAwareTransport. A Balancer can be a Member, see (*Balancer).RateLimit.
+	Member RateAwareTransport
+
+	rampStart       atomic.Int64 // unix nanoseconds
+	lastUsed        atomic.Int64 // unix nanoseconds
+	inflight        atomic.Int64
+	sem             chan struct{}
+	semOnce         sync.Once
+	downloadSem     chan struct{}
+	downloadSemOnce sync.Once
+	downloads       atomic.Int64 // in-flight requests holding a downloadSem slot
+	queued          atomic.Int64 // requests waiting to be sent, see QueueDepth
+	paused          atomic.Bool
+	mu              sync.Mutex
+	idle            chan struct{} // closed when inflight reaches zero, guarded by mu
+	polling         chan struct{} // closed when the running Poll stops, guarded by mu
+
+	restoreOnce sync.Once
+	pace        syncMap // Resource -> *atomic.Int64 of the next paced slot in unix nanoseconds
+	health      health
+	breaker     breaker
+	spends      syncMap // Resource -> *spend
+	usage       syncMap // Resource -> *usage
+	accounts    syncMap // UsageKey -> *account
+	sequencer   sequencer
+}
+
+// rampWeight returns the current selection weight (0.0 to 1.0) of the transport based on RampUp.
+func (t *Transport) rampWeight(now time.Time) float64 {
+	if t.RampUp <= 0 {
+		return 1
+	}
+	t.rampStart.CompareAndSwap(0, now.UnixNano())
+	elapsed := now.Sub(time.Unix(0, t.rampStart.Load()))
+	if elapsed >= t.RampUp {
+		return 1
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(elapsed) / float64(t.RampUp)
+}
+
+// admit randomly decides if the transport should be considered for selection based on its rampWeight.
+func (t *Transport) admit(now time.Time) bool {
+	weight := t.rampWeight(now)
+	return weight >= 1 || rand.Float64() < weight
 }
 
 // RoundTrip implements http.RoundTripper
-func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	if t.Base == nil {
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.allowed(req); err != nil {
+		return nil, err // never dead-lettered, as the request may carry credentials for another host
+	}
+	t.restore()
+	t.lastUsed.Store(t.clock().Now().UnixNano())
+	req = t.apiVersion(req)
+	resource := InferResource(req)
+	ctx := req.Context()
+	t.label(ctx, resource, true)
+	defer t.unlabel(ctx)
+	if resource == "" {
+		resource = ResourceOther
+	}
+	if key := requestOrderingKey(req, t.OrderingKey); key != "" {
+		_, done, err := t.sequencer.enqueue(ctx, key)
+		if err != nil {
+			return nil, reject(t.DeadLetter, req, resource, err)
+		}
+		defer done()
+	}
+	if resource == ResourceUntracked || resource == ResourceOther {
+		t.label(ctx, resource, false)
+		return t.roundTripAborted(req, resource, 0)
+	}
+	cost := estimateCost(t.CostEstimator, req)
+	reason, override := EmergencyOverrideFromContext(ctx)
+	if override {
+		t.audit(req, resource, reason)
+	}
+	if err := t.await(req, resource, cost, override); err != nil {
+		return nil, reject(t.DeadLetter, req, resource, t.throttled(req, resource, err))
+	}
+	policy := t.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		t.label(ctx, resource, false)
+		resp, err := t.roundTripAborted(req, resource, cost)
+		if policy == nil || attempt >= policy.MaxAttempts || !policy.retryable(resource, resp, err) {
+			return resp, err
+		}
+		delay, ok := policy.delay(attempt-1, resp, err)
+		if !ok {
+			return resp, err
+		}
+		retry, rerr := rewind(req)
+		if rerr != nil {
+			if resp != nil {
+				return resp, err
+			}
+			return nil, errors.Join(err, rerr)
+		}
+		discard(resp)
+		debugf("retrying %s %s in %s (attempt %d)", req.Method, req.URL, delay, attempt+1)
+		t.Limits.emit(retried(req, resource, fmt.Sprintf("attempt %d in %s", attempt+1, delay)))
+		t.label(ctx, resource, true)
+		if err := sleepUntil(req.Context(), t.clock(), t.clock().Now().Add(delay), "rate limit retry", resource); err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// await blocks until the request may be sent: for the rate limit to reset (if WaitOnExhaustion is set), and unless the
+// request overrides local policies, for the HardCap, the quota reserved for higher priorities and its paced slot.
+func (t *Transport) await(req *http.Request, resource Resource, cost uint64, override bool) error {
+	t.queued.Add(1)
+	defer t.queued.Add(-1)
+	ctx := req.Context()
+	if t.WaitOnExhaustion {
+		if err := t.budget(req, resource, cost); err != nil {
+			return err
+		}
+		if rate := t.Limits.Load(resource); rate != nil && rate.Remaining == 0 {
+			debugf("waiting until %s for %s rate limit reset before %s %s", rate.ResetTime(), resource, req.Method, req.URL)
+		}
+		if err := t.Limits.waitFor(ctx, resource, cost); err != nil {
+			return err
+		}
+	}
+	if override {
+		return nil
+	}
+	if t.WaitOnExhaustion {
+		if err := t.waitHardCap(ctx, resource, cost); err != nil {
+			return err
+		}
+	}
+	if err := t.waitReserved(ctx, resource); err != nil {
+		return err
+	}
+	return t.wait(ctx, resource, cost)
+}
+
+// audit logs and emits an EventEmergencyOverride for a request that bypasses local policies.
+func (t *Transport) audit(req *http.Request, resource Resource, reason string) {
+	msg := fmt.Sprintf("emergency override of local policies for %s %s: %s", req.Method, req.URL, reason)
+	if caller := CallerFromContext(req.Context()); caller != "" {
+		msg += fmt.Sprintf(" (caller %q)", caller)
+	}
+	log.Printf("ghratelimit: %s\n", msg)
+	t.Limits.emit(Event{Kind: EventEmergencyOverride, Resource: resource, Rate: t.Limits.Load(resource), Message: msg, IdempotencyKey: IdempotencyKeyFromContext(req.Context())})
+}
+
+// roundTrip executes a single attempt of the request, updating the limits from the response.
+func (t *Transport) roundTrip(req *http.Request, resource Resource, cost uint64) (resp *http.Response, err error) {
+	done, err := t.breaker.allow(t.Breaker, t.Name, t.clock().Now())
+	if err != nil {
+		return nil, reject(t.DeadLetter, req, resource, err)
+	}
+	defer func() { done(breakerOutcome(resource, resp, err)) }()
+	if _, ok := EmergencyOverrideFromContext(req.Context()); !ok {
+		if err := t.spend(resource, cost); err != nil {
+			return nil, reject(t.DeadLetter, req, resource, t.throttled(req, resource, err))
+		}
+	}
+	release, err := t.acquire(req, resource)
+	if err != nil {
+		return nil, err
+	}
+	info := HookInfo{Request: req, Transport: t, Resource: resource, Rate: t.Limits.Load(resource), Start: t.clock().Now()}
+	if err := t.Hooks.request(info); err != nil {
+		release()
+		return nil, reject(t.DeadLetter, req, resource, err)
+	}
+	var parseErr error
+	defer func() {
+		info.ParseErr = parseErr
+		t.Hooks.response(info, resp, err, t.clock().Now())
+	}()
+	t.count(req, resource)
+	if t.Optimistic {
+		t.Limits.dispatch(resource, cost)
+	}
+	base := t.Base
+	if t.Member != nil {
+		base = t.Member
+	}
+	if override := BaseFromContext(req.Context()); override != nil {
+		base = override
+	}
+	switch {
+	case t.DryRun:
+		resp = t.simulate(req, resource, cost)
+	case base == nil:
 		resp, err = http.DefaultTransport.RoundTrip(req)
+	default:
+		resp, err = base.RoundTrip(req)
+	}
+	if t.Optimistic {
+		t.Limits.settle(resource, cost)
+	}
+	if aborted(err) {
+		t.Limits.abort(resource)
+	}
+	t.account(req, resource, cost, resp, err)
+	if resp == nil {
+		release()
 	} else {
-		resp, err = t.Base.RoundTrip(req)
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
 	}
 	if resp != nil {
-		if err := t.Limits.Parse(resp); err != nil {
-			return nil, err
+		if t.Member != nil {
+			t.observeMember(resource) // the member's rate limit replaces the (possibly partial) view of a single response
+		} else {
+			parseErr = t.Limits.Parse(resp)
+			if err := t.parseError(resp, parseErr); err != nil {
+				discard(resp)
+				return nil, err
+			}
+		}
+		if t.InspectGraphQL && resource == ResourceGraphQL && req.Method == http.MethodPost && resp.StatusCode == http.StatusOK {
+			if err := t.Limits.ParseGraphQL(resp); err != nil {
+				return nil, err
+			}
+		}
+		if t.ClassifyForbidden {
+			if err := t.classifyRateLimit(req, resp, resource); err != nil {
+				return nil, err
+			}
+		}
+		if t.ClassifyForbidden || t.retryPolicy() != nil {
+			if secondary, err := secondaryRateLimit(resp, resource); err != nil {
+				return nil, err
+			} else if secondary != nil {
+				return nil, secondary
+			}
 		}
 	}
 	return
 }
 
-// Poll calls (*Transport).Limits.Update every interval, starting immediately.
+// throttled annotates an error of a request that was throttled with the request and the current rate limit,
+// wrapping it in a *RateLimitError if it is not one already.
+func (t *Transport) throttled(req *http.Request, resource Resource, err error) error {
+	var rlErr *RateLimitError
+	if !errors.As(err, &rlErr) {
+		rlErr = &RateLimitError{Resource: resource, Err: err}
+		if rate := t.Limits.Load(resource); rate != nil {
+			rlErr.Remaining, rlErr.Reset = rate.Remaining, rate.ResetTime()
+		}
+		err = rlErr
+	}
+	rlErr.Transport = t.Name
+	rlErr.Method = req.Method
+	if req.URL != nil {
+		u := *req.URL
+		u.User = nil
+		rlErr.URL = u.String()
+	}
+	return err
+}
+
+// Poll calls (*Transport).Limits.Update every interval (adapted by PollPolicy, if set), starting immediately.
+// If u is nil, the /rate_limit endpoint of the BaseURL is used. If Limits.Shared is set, Limits.SyncShared is also called.
+// Only one Poll runs per transport, concurrent calls wait (taking over if the running Poll stops) until ctx is done.
 func (t *Transport) Poll(ctx context.Context, interval time.Duration, u *url.URL) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
 	for {
-		if err := t.Limits.Fetch(ctx, t, u); err != nil {
-			log.Printf("(*ghratelimit.Transport).Limits.Fetch failed: %v\n", err)
+		t.mu.Lock()
+		running := t.polling
+		if running == nil {
+			t.polling = make(chan struct{})
+		}
+		t.mu.Unlock()
+		if running == nil {
+			break
+		}
+		debugf("transport %q is already polling, waiting for it to stop", t.Name)
+		select {
+		case <-ctx.Done():
+			return
+		case <-running:
+		}
+	}
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		close(t.polling)
+		t.polling = nil
+	}()
+	t.restore()
+	if u == nil {
+		u = RateLimitURL(t.BaseURL)
+	}
+	var ticker Ticker
+	if t.PollPolicy == nil {
+		ticker = t.clock().NewTicker(interval)
+		defer ticker.Stop()
+	}
+	var polled int64 // lastUsed after the previous poll, unchanged when the transport has been idle
+	var failures int
+	for {
+		idle := t.lastUsed.Load() == polled
+		if err := t.Limits.Fetch(ctx, t, u); err != nil && ctx.Err() == nil {
+			failures++
+			t.pollError(err, failures)
+		} else if err == nil {
+			if failures > 0 && t.OnPollError == nil {
+				log.Printf("(*ghratelimit.Transport).Limits.Fetch recovered after %d failures\n", failures)
+			}
+			failures = 0
+		}
+		if err := t.Limits.SyncShared(ctx); err != nil && ctx.Err() == nil {
+			t.Limits.emit(Event{Kind: EventSharedError, Message: err.Error()})
+		}
+		if err := t.Checkpoint(); err != nil {
+			t.Limits.emit(Event{Kind: EventStateError, Message: err.Error()})
+		}
+		polled = t.lastUsed.Load()
+		var ticks <-chan time.Time
+		switch {
+		case failures > 0:
+			ticks = t.clock().After(pollBackoff(interval, failures))
+		case t.PollPolicy != nil:
+			ticks = t.clock().After(t.PollPolicy.interval(&t.Limits, interval, idle, t.clock().Now()))
+		default:
+			ticks = ticker.C()
 		}
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticks:
 		}
 	}
 }
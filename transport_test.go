@@ -0,0 +1,48 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_rampWeight(t *testing.T) {
+	var transport Transport
+	now := time.Now()
+	assert.Equal(t, 1.0, transport.rampWeight(now), "no RampUp should always be fully weighted")
+
+	transport.RampUp = time.Minute
+	assert.Equal(t, 0.0, transport.rampWeight(now), "weight should start at 0")
+	assert.InDelta(t, 0.5, transport.rampWeight(now.Add(30*time.Second)), 0.001, "weight should ramp linearly")
+	assert.Equal(t, 1.0, transport.rampWeight(now.Add(time.Hour)), "weight should cap at 1")
+	assert.False(t, transport.admit(now), "weight of 0 should never be admitted")
+}
+
+func TestContextWithBase(t *testing.T) {
+	var proxied int
+	proxy := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		proxied++
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{
+			"X-Ratelimit-Limit":     []string{"5000"},
+			"X-Ratelimit-Used":      []string{"10"},
+			"X-Ratelimit-Remaining": []string{"4990"},
+			"X-Ratelimit-Reset":     []string{"1700000000"},
+			"X-Ratelimit-Resource":  []string{"core"},
+		}, Body: http.NoBody, Request: req}, nil
+	})
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatal("Base should not be used")
+		return nil, nil
+	})}
+	req, _ := http.NewRequestWithContext(ContextWithBase(context.Background(), proxy), http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, 1, proxied)
+	assert.Equal(t, uint64(4990), transport.Limits.Load(ResourceCore).Remaining, "the limits should still be updated")
+	assert.Nil(t, BaseFromContext(context.Background()))
+}
@@ -0,0 +1,51 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// usage counts the requests sent for a resource type by method class.
+type usage struct {
+	reads, writes atomic.Uint64
+}
+
+// MethodUsage is the number of requests sent for a resource type by method class.
+type MethodUsage struct {
+	// Reads is the number of GET, HEAD and OPTIONS requests.
+	Reads uint64 `json:"reads"`
+	// Writes is the number of mutating requests (ex: POST, PATCH or DELETE), which also carry a higher secondary rate-limit risk.
+	Writes uint64 `json:"writes"`
+}
+
+// count records a request sent for the resource type in the usage stats.
+func (t *Transport) count(req *http.Request, resource Resource) {
+	val, _ := t.usage.LoadOrStore(resource, new(usage))
+	if isWrite(req) {
+		val.(*usage).writes.Add(1)
+	} else {
+		val.(*usage).reads.Add(1)
+	}
+}
+
+// Usage returns the number of requests sent by the transport for each resource type, including retries
+// and the ResourceUntracked and ResourceOther pseudo-resources which do not consume any GitHub rate limit.
+func (t *Transport) Usage() map[Resource]uint64 {
+	totals := make(map[Resource]uint64)
+	for resource, u := range t.MethodUsage() {
+		totals[resource] = u.Reads + u.Writes
+	}
+	return totals
+}
+
+// MethodUsage returns the requests counted by Usage, broken down into reads and writes.
+// A high share of reads suggests conditional requests (see CachingTransport) would save budget.
+func (t *Transport) MethodUsage() map[Resource]MethodUsage {
+	totals := make(map[Resource]MethodUsage)
+	t.usage.Range(func(key, value any) bool {
+		u := value.(*usage)
+		totals[key.(Resource)] = MethodUsage{Reads: u.reads.Load(), Writes: u.writes.Load()}
+		return true
+	})
+	return totals
+}
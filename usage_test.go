@@ -0,0 +1,83 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_Usage(t *testing.T) {
+	transport := exhausted()
+	transport.HardCap = map[Resource]uint64{ResourceUntracked: 0}
+	req, _ := http.NewRequest(http.MethodGet, "https://raw.githubusercontent.com/o/r/main/README.md", nil)
+	assert.Equal(t, ResourceUntracked, InferResource(req))
+	for range 2 {
+		_, err := transport.RoundTrip(req)
+		assert.NoError(t, err, "untracked requests should never be throttled")
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://codeload.github.com/o/r/tar.gz/main", nil)
+	assert.Equal(t, ResourceUntracked, InferResource(req))
+
+	assert.Equal(t, map[Resource]uint64{ResourceUntracked: 2}, transport.Usage())
+}
+
+func TestTransport_Usage_Other(t *testing.T) {
+	transport := exhausted()
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{}}
+	_, err := transport.RoundTrip(req)
+	assert.NoError(t, err, "requests of an unknown resource should never be throttled")
+
+	bt := &Balancer{Transports: []*Transport{transport}, UnknownResource: ResourceOther}
+	_, err = bt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, map[Resource]uint64{ResourceOther: 2}, transport.Usage())
+}
+
+func TestTransport_MethodUsage(t *testing.T) {
+	transport := &Transport{Base: okResponse()}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodDelete, http.MethodGet} {
+		req, _ := http.NewRequest(method, "https://api.github.com/repos/o/r/issues", nil)
+		resp, err := transport.RoundTrip(req)
+		assert.NoError(t, err)
+		discard(resp)
+	}
+	assert.Equal(t, map[Resource]MethodUsage{ResourceCore: {Reads: 3, Writes: 2}}, transport.MethodUsage())
+	assert.Equal(t, map[Resource]uint64{ResourceCore: 5}, transport.Usage())
+}
+
+func TestTransport_Accounting(t *testing.T) {
+	var requests []*http.Request
+	transport := &Transport{
+		Base: etagServer(`"v1"`, `{}`, &requests),
+		CostEstimator: func(req *http.Request) uint64 {
+			if strings.HasPrefix(req.URL.Path, "/search/") {
+				return 2
+			}
+			return 1
+		},
+	}
+	send := func(caller, path, etag string) {
+		req, _ := http.NewRequestWithContext(ContextWithCaller(context.Background(), caller), http.MethodGet, "https://api.github.com"+path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := transport.RoundTrip(req)
+		assert.NoError(t, err, "(*Transport).RoundTrip failed")
+		discard(resp)
+	}
+	send("indexer", "/repos/o/r", "")
+	send("indexer", "/repos/o/r", `"v1"`)
+	send("indexer", "/search/issues", "")
+	send("", "/repos/o/r", "")
+
+	bt := &Balancer{Transports: []*Transport{transport, {}}}
+	assert.Equal(t, map[UsageKey]Accounting{
+		{Resource: ResourceCore, Caller: "indexer"}:   {Requests: 2, Points: 1, NotModified: 1},
+		{Resource: ResourceSearch, Caller: "indexer"}: {Requests: 1, Points: 2},
+		{Resource: ResourceCore}:                      {Requests: 1, Points: 1},
+	}, bt.Accounting())
+}
@@ -0,0 +1,72 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// verifyChecks are the endpoints exercised by Verify and the resource type they are expected to consume.
+var verifyChecks = []struct {
+	path     string
+	query    string
+	resource Resource
+}{
+	{path: "meta", resource: ResourceCore},
+	{path: "search/repositories", query: "q=ghratelimit&per_page=1", resource: ResourceSearch},
+}
+
+// Verify exercises the transport's credential against real endpoints of the transport's BaseURL (ex: after a GitHub Enterprise
+// Server upgrade), checking that the /rate_limit endpoint can be fetched, that resources are inferred as GitHub reports them
+// and that the rate-limit headers are parsed. It returns every failed check, joined.
+func Verify(ctx context.Context, transport *Transport) error {
+	var errs []error
+	if err := transport.Limits.Fetch(ctx, transport, RateLimitURL(transport.BaseURL)); err != nil {
+		errs = append(errs, err)
+	} else if transport.Limits.Load(ResourceCore) == nil {
+		errs = append(errs, errors.New("(*Limits).Fetch did not return the core rate limit"))
+	}
+	for _, check := range verifyChecks {
+		if err := verify(ctx, transport, check.path, check.query, check.resource); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verify sends a GET request to the endpoint, checking the resource it consumed and the rate limit it returned.
+func verify(ctx context.Context, transport *Transport, path, query string, expected Resource) error {
+	u := apiURL(transport.BaseURL, path)
+	u.RawQuery = query
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext for %q failed: %w", u, err)
+	}
+	req.Header.Set("User-Agent", "github.com/bored-engineer/github-rate-limit-http-transport")
+	if inferred := InferResource(req); inferred != expected {
+		return fmt.Errorf("InferResource for %q returned %q, expected %q", u, inferred, expected)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("(*Transport).RoundTrip for %q failed: %w", u, err)
+	}
+	discard(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("(*http.Response).StatusCode(%d) != 200 for %q", resp.StatusCode, u)
+	}
+	resource, rate, err := FromResponse(resp)
+	switch {
+	case err != nil:
+		return fmt.Errorf("FromResponse for %q failed: %w", u, err)
+	case resource != expected:
+		return fmt.Errorf("GitHub reported resource %q for %q, expected %q", resource, u, expected)
+	case rate == nil:
+		return fmt.Errorf("no rate-limit headers for %q", u)
+	}
+	// The stored rate limit may be fresher than the response (see Limits.AcceptStale), but never staler.
+	if stored := transport.Limits.Load(resource); stored == nil || stored.Reset < rate.Reset || (stored.Reset == rate.Reset && stored.Remaining > rate.Remaining) {
+		return fmt.Errorf("(*Limits).Load(%q) returned %v, expected %v", resource, stored, rate)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package ghratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	var paths []string
+	transport := &Transport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			resp, err := fakeGitHub().RoundTrip(req)
+			if resource := InferResource(req); req.URL.Path != "/api/v3/rate_limit" {
+				resp.Header.Set("X-Ratelimit-Resource", resource.String())
+			} else {
+				resp.Body = io.NopCloser(strings.NewReader(limitsResponse))
+			}
+			return resp, err
+		}),
+	}
+	transport.BaseURL, _ = url.Parse("https://github.example.com")
+	assert.NoError(t, Verify(context.Background(), transport), "Verify failed")
+	assert.Equal(t, []string{"/api/v3/rate_limit", "/api/v3/meta", "/api/v3/search/repositories"}, paths)
+
+	transport.Base = fakeGitHub() // always reports the core resource
+	assert.ErrorContains(t, Verify(context.Background(), transport), `GitHub reported resource "core" for "https://github.example.com/api/v3/search/repositories?q=ghratelimit&per_page=1", expected "search"`)
+}
+
+// TestVerify_Integration runs Verify against the real GitHub API, it is skipped unless GHRATELIMIT_INTEGRATION is set.
+// GITHUB_TOKEN is used if set, and GITHUB_API_URL selects a GitHub Enterprise Server instance.
+func TestVerify_Integration(t *testing.T) {
+	if os.Getenv("GHRATELIMIT_INTEGRATION") == "" {
+		t.Skip("set GHRATELIMIT_INTEGRATION=1 to run against the GitHub API")
+	}
+	transport := &Transport{}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+token)
+			return http.DefaultTransport.RoundTrip(req)
+		})
+	}
+	if api := os.Getenv("GITHUB_API_URL"); api != "" {
+		base, err := url.Parse(api)
+		if err != nil {
+			t.Fatalf("url.Parse for %q failed: %v", api, err)
+		}
+		transport.BaseURL = base
+	}
+	assert.NoError(t, Verify(context.Background(), transport), "Verify failed")
+}
@@ -0,0 +1,20 @@
+package ghratelimit
+
+import "net/http"
+
+// DefaultAPIVersion is the X-GitHub-Api-Version sent by Fetch and AppPool, unless overridden by a Transport.
+const DefaultAPIVersion = "2022-11-28"
+
+// apiVersion returns the request with the X-GitHub-Api-Version set by ContextWithAPIVersion or APIVersion, if any.
+func (t *Transport) apiVersion(req *http.Request) *http.Request {
+	version := APIVersionFromContext(req.Context())
+	if version == "" {
+		version = t.APIVersion
+	}
+	if version == "" || req.Header.Get("X-GitHub-Api-Version") == version {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("X-GitHub-Api-Version", version)
+	return req
+}
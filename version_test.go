@@ -0,0 +1,36 @@
+package ghratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_APIVersion(t *testing.T) {
+	var versions []string
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		versions = append(versions, req.Header.Get("X-GitHub-Api-Version"))
+		return okResponse().RoundTrip(req)
+	})}
+	get := func(ctx context.Context, version string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+		if version != "" {
+			req.Header.Set("X-GitHub-Api-Version", version)
+		}
+		resp, err := transport.RoundTrip(req)
+		assert.NoError(t, err, "(*Transport).RoundTrip failed")
+		discard(resp)
+	}
+
+	get(context.Background(), "2022-11-28")
+	transport.APIVersion = "2026-03-10"
+	get(context.Background(), "")
+	get(context.Background(), "2022-11-28")
+	get(ContextWithAPIVersion(context.Background(), "2022-11-28"), "")
+	assert.Equal(t, []string{"2022-11-28", "2026-03-10", "2026-03-10", "2022-11-28"}, versions)
+
+	_ = transport.Limits.Fetch(context.Background(), transport, nil)
+	assert.Equal(t, "2026-03-10", versions[4], "Fetch should use the pinned version")
+}
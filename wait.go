@@ -0,0 +1,109 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WaitError is returned when a blocking operation is interrupted because its context is done.
+type WaitError struct {
+	// Op describes what was being waited for, ex: "rate limit reset".
+	Op string
+	// Resource is the rate-limit resource being waited on, if any.
+	Resource Resource
+	// Until is when the wait would have completed, if known.
+	Until time.Time
+	// Err is the cause of the context being done.
+	Err error
+}
+
+// Error implements error
+func (e *WaitError) Error() string {
+	msg := "waiting for " + e.Op
+	if e.Resource != "" {
+		msg += fmt.Sprintf(" (%s)", e.Resource)
+	}
+	if !e.Until.IsZero() {
+		msg += " until " + e.Until.Format(time.RFC3339)
+	}
+	return msg + " interrupted: " + e.Err.Error()
+}
+
+// Unwrap allows errors.Is and errors.As to match the context's cause.
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// ErrRateLimited matches every *RateLimitError via errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is returned when a request cannot be served because the rate limit of its resource is exhausted
+// (ex: because waiting for the reset would exceed the context's deadline), or a local policy such as HardCap,
+// Reserve or Pace throttled it. Its Error is a single line with everything needed to diagnose it.
+type RateLimitError struct {
+	// Method is the HTTP method of the request, if known.
+	Method string
+	// URL is the URL of the request excluding any user info, if known.
+	URL string
+	// Resource is the rate-limit resource of the request.
+	Resource Resource
+	// Remaining is the number of requests remaining.
+	Remaining uint64
+	// Reset is when the rate-limit window resets, if known.
+	Reset time.Time
+	// Transport is the Name of the transport, if any.
+	Transport string
+	// Err is the underlying error, typically a *WaitError.
+	Err error
+}
+
+// Error implements error
+func (e *RateLimitError) Error() string {
+	msg := "rate limited"
+	if e.Method != "" {
+		msg += " " + e.Method + " " + e.URL
+	}
+	msg += fmt.Sprintf(": resource=%s", e.Resource)
+	if e.Transport != "" {
+		msg += fmt.Sprintf(" transport=%q", e.Transport)
+	}
+	msg += fmt.Sprintf(" remaining=%d", e.Remaining)
+	if !e.Reset.IsZero() {
+		msg += " reset=" + e.Reset.Format(time.RFC3339) + " retry_after=" + e.RetryAfter().Round(time.Second).String()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap allows errors.Is and errors.As to match the underlying error.
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports if the target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter returns how long until the rate-limit window resets, or zero if it already has.
+func (e *RateLimitError) RetryAfter() time.Duration {
+	return max(time.Until(e.Reset), 0)
+}
+
+// sleepUntil blocks until the given time, or until ctx is done which returns a *WaitError.
+func sleepUntil(ctx context.Context, clock Clock, until time.Time, op string, resource Resource) error {
+	delay := until.Sub(clock.Now())
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return &WaitError{Op: op, Resource: resource, Until: until, Err: context.Cause(ctx)}
+	case <-clock.After(delay):
+		return nil
+	}
+}
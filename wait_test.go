@@ -0,0 +1,93 @@
+package ghratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exhausted returns a Transport with the core resource exhausted for the next hour.
+func exhausted() *Transport {
+	transport := &Transport{Base: okResponse(), WaitOnExhaustion: true}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Used: 5000, Remaining: 0, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+	return transport
+}
+
+// assertUnblocks asserts that fn returns a *WaitError promptly after its context is cancelled.
+func assertUnblocks(t *testing.T, fn func(ctx context.Context) error) {
+	t.Helper()
+	cause := errors.New("shutting down")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- fn(ctx) }()
+	time.Sleep(5 * time.Millisecond)
+	start := time.Now()
+	cancel(cause)
+	select {
+	case err := <-errCh:
+		assert.Less(t, time.Since(start), 10*time.Millisecond, "should unblock promptly on cancel")
+		var waitErr *WaitError
+		assert.ErrorAs(t, err, &waitErr, "should return a *WaitError")
+		assert.ErrorIs(t, err, cause, "should wrap the context's cause")
+	case <-time.After(time.Second):
+		t.Fatal("did not unblock on cancel")
+	}
+}
+
+func TestWait_Cancel(t *testing.T) {
+	t.Run("Limits.Wait", func(t *testing.T) {
+		transport := exhausted()
+		assertUnblocks(t, func(ctx context.Context) error {
+			return transport.Limits.Wait(ctx, ResourceCore)
+		})
+	})
+	t.Run("Balancer.Wait", func(t *testing.T) {
+		bt := &Balancer{Transports: []*Transport{exhausted(), exhausted()}}
+		assertUnblocks(t, func(ctx context.Context) error {
+			return bt.Wait(ctx, ResourceCore)
+		})
+	})
+	t.Run("Transport.RoundTrip", func(t *testing.T) {
+		transport := exhausted()
+		assertUnblocks(t, func(ctx context.Context) error {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+			_, err := transport.RoundTrip(req)
+			return err
+		})
+	})
+}
+
+func TestRateLimitError(t *testing.T) {
+	transport := exhausted()
+	transport.Name = "app"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/users/bored-engineer", nil)
+	start := time.Now()
+	_, err := transport.RoundTrip(req)
+	assert.Less(t, time.Since(start), time.Second, "should fail fast when the reset is after the deadline")
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var rlErr *RateLimitError
+	if assert.ErrorAs(t, err, &rlErr) {
+		assert.Equal(t, ResourceCore, rlErr.Resource)
+		assert.Equal(t, "app", rlErr.Transport)
+		assert.InDelta(t, time.Hour, rlErr.RetryAfter(), float64(time.Minute))
+		assert.Equal(t, "https://api.github.com/users/bored-engineer", rlErr.URL)
+	}
+	assert.Regexp(t, `^rate limited GET https://api.github.com/users/bored-engineer: resource=core transport="app" remaining=0 reset=\S+ retry_after=\S+: waiting for rate limit reset`, err.Error())
+
+	transport = &Transport{Name: "capped", Base: okResponse(), HardCap: map[Resource]uint64{ResourceCore: 0}}
+	transport.Limits.Store(nil, ResourceCore, &Rate{Limit: 5000, Remaining: 10, Reset: uint64(time.Now().Add(time.Hour).Unix())})
+	_, err = transport.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrHardCapReached)
+	if assert.ErrorAs(t, err, &rlErr, "local policies should be annotated too") {
+		assert.Equal(t, "capped", rlErr.Transport)
+		assert.Equal(t, uint64(10), rlErr.Remaining)
+	}
+}
@@ -0,0 +1,256 @@
+package ghratelimit
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile bundles the settings of a Transport tuned for a workload, applied with (*Transport).ApplyProfile.
+type Profile struct {
+	// Name describes the workload, ex: "dependency_submission".
+	Name string
+	// Pace lists the resource types whose requests are paced, added to the Transport.Pace.
+	Pace []Resource
+	// WaitOnExhaustion, if true, enables the Transport.WaitOnExhaustion so requests queue rather than fail while exhausted.
+	WaitOnExhaustion bool
+	// MaxConcurrency, if non-zero, replaces the Transport.MaxConcurrency.
+	MaxConcurrency int
+	// Retry, if set, replaces the Transport.Retry.
+	Retry *RetryPolicy
+	// OnResponse, if set, is called after any Hooks.OnResponse of the Transport, ex: SubmissionStats.Observe.
+	OnResponse func(HookInfo)
+	// OrderingKey, if set, replaces the Transport.OrderingKey.
+	OrderingKey func(*http.Request) string
+}
+
+// ProfileDependencySubmission is tuned for CI systems submitting dependency snapshots (SBOMs) at scale, whose
+// ResourceDependencySnapshots bucket only allows 100 requests per hour: submissions are paced evenly over the window,
+// queued (bounded by their context) once it is exhausted rather than rejected, limited to a few at a time as snapshots
+// can be large, and retried when rate limited, waiting up to an hour for the window to reset.
+// It is intended for a Transport dedicated to submissions, as the retries and waits apply to every resource type.
+func ProfileDependencySubmission() Profile {
+	return Profile{
+		Name:             "dependency_submission",
+		Pace:             []Resource{ResourceDependencySnapshots},
+		WaitOnExhaustion: true,
+		MaxConcurrency:   4,
+		Retry:            &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Minute, MaxBackoff: time.Hour},
+	}
+}
+
+// ApplyProfile configures the transport with the settings of the Profile, it must be called before the transport is used.
+func (t *Transport) ApplyProfile(p Profile) {
+	for _, resource := range p.Pace {
+		if !slices.Contains(t.Pace, resource) {
+			t.Pace = append(t.Pace, resource)
+		}
+	}
+	t.WaitOnExhaustion = t.WaitOnExhaustion || p.WaitOnExhaustion
+	if p.MaxConcurrency > 0 {
+		t.MaxConcurrency = p.MaxConcurrency
+	}
+	if p.Retry != nil {
+		retry := *p.Retry
+		t.Retry = &retry
+	}
+	if p.OrderingKey != nil {
+		t.OrderingKey = p.OrderingKey
+	}
+	if p.OnResponse != nil {
+		var hooks Hooks
+		if t.Hooks != nil {
+			hooks = *t.Hooks
+		}
+		prev := hooks.OnResponse
+		hooks.OnResponse = func(info HookInfo) {
+			if prev != nil {
+				prev(info)
+			}
+			p.OnResponse(info)
+		}
+		t.Hooks = &hooks
+	}
+}
+
+// ProfileRunnerRegistration is tuned for autoscalers registering ephemeral Actions runners (ResourceActionsRunnerRegistration),
+// as autoscaling storms are a frequent cause of rate-limit incidents: registrations are limited to a few at a time so a
+// storm drains gradually, queued once the window is exhausted, and retried when rate limited or failed with a server error
+// after a jittered backoff so the retries of a storm do not arrive in lockstep. Requesting a registration token is safe to repeat.
+func ProfileRunnerRegistration() Profile {
+	return Profile{
+		Name:             "runner_registration",
+		WaitOnExhaustion: true,
+		MaxConcurrency:   10,
+		Retry: &RetryPolicy{MaxAttempts: 5, MinBackoff: time.Second, MaxBackoff: 5 * time.Minute, Retryable: func(resource Resource, resp *http.Response, err error) bool {
+			if resource == ResourceActionsRunnerRegistration && resp != nil && resp.StatusCode >= http.StatusInternalServerError {
+				return true
+			}
+			return DefaultRetryable(resource, resp, err)
+		}},
+	}
+}
+
+// ProfileSARIFUpload is tuned for CI systems uploading SARIF results (ResourceCodeScanningUpload), a common burst workload:
+// uploads are paced evenly over the window, queued once it is exhausted and limited to a few at a time. Rate-limited and
+// transiently failed (502, 503 or 504) uploads are retried, but an accepted upload (202 Accepted, processed asynchronously)
+// is never sent again, poll its status instead. If stats is not nil, every upload attempt is counted per repository.
+func ProfileSARIFUpload(stats *SubmissionStats) Profile {
+	p := Profile{
+		Name:             "sarif_upload",
+		Pace:             []Resource{ResourceCodeScanningUpload},
+		WaitOnExhaustion: true,
+		MaxConcurrency:   4,
+		Retry: &RetryPolicy{MaxAttempts: 3, MinBackoff: 30 * time.Second, MaxBackoff: time.Hour, Retryable: func(resource Resource, resp *http.Response, err error) bool {
+			if resp != nil && resp.StatusCode < http.StatusMultipleChoices {
+				return false
+			}
+			if resource == ResourceCodeScanningUpload && resp != nil {
+				switch resp.StatusCode {
+				case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+					return true
+				}
+			}
+			return DefaultRetryable(resource, resp, err)
+		}},
+	}
+	if stats != nil {
+		p.OnResponse = stats.Observe
+	}
+	return p
+}
+
+// ProfileSCIM is tuned for SCIM sync jobs (ResourceSCIM), whose generous limit is easily exhausted by bursts: updates are
+// paced evenly over the window, queued once it is exhausted, retried when rate limited, and the operations on each user
+// (or group) are serialized in submission order, see SCIMOrderingKey. With a Balancer, also set its OrderingKey
+// to SCIMOrderingKey. If stats is not nil, every attempt is counted per SCIM resource type (SCIMResourceType, unless its Key
+// is set), ex: to report the progress of the Users and Groups of a sync.
+func ProfileSCIM(stats *SubmissionStats) Profile {
+	p := Profile{
+		Name:             "scim",
+		Pace:             []Resource{ResourceSCIM},
+		WaitOnExhaustion: true,
+		Retry:            &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Second, MaxBackoff: time.Hour},
+		OrderingKey:      SCIMOrderingKey,
+	}
+	if stats != nil {
+		p.OnResponse = func(info HookInfo) { stats.observe(info, SCIMResourceType) }
+	}
+	return p
+}
+
+// scimPath splits the path of a SCIM request into its scope (ex: "organizations/acme"), resource type (ex: "Users") and id.
+func scimPath(req *http.Request) (scope, kind, id string, ok bool) {
+	if req.URL == nil {
+		return "", "", "", false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(req.URL.Path, "/api/v3"), "/scim/v2/")
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(rest, "/", 4)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", false
+	}
+	if len(parts) == 4 {
+		id = parts[3]
+	}
+	return parts[0] + "/" + parts[1], parts[2], id, true
+}
+
+// SCIMResourceType returns the SCIM resource type of a request including its scope, ex: "organizations/acme/Users"
+// for /scim/v2/organizations/acme/Users/{id}, or an empty string for other requests.
+func SCIMResourceType(req *http.Request) string {
+	scope, kind, _, ok := scimPath(req)
+	if !ok {
+		return ""
+	}
+	return scope + "/" + kind
+}
+
+// SCIMOrderingKey is an OrderingKey serializing the operations on each SCIM user or group (/scim/v2/.../Users/{id}),
+// other requests (ex: listing or provisioning users) are not serialized.
+func SCIMOrderingKey(req *http.Request) string {
+	scope, kind, id, ok := scimPath(req)
+	if !ok || id == "" {
+		return ""
+	}
+	return "scim:" + scope + "/" + kind + "/" + id
+}
+
+// Submissions are the submission attempts counted by SubmissionStats for a key (ex: a repository).
+type Submissions struct {
+	// Attempts is the number of attempts sent, including retries.
+	Attempts uint64 `json:"attempts"`
+	// Accepted is the number of attempts with a successful (2xx) response.
+	Accepted uint64 `json:"accepted"`
+	// RateLimited is the number of attempts that were rate limited.
+	RateLimited uint64 `json:"rate_limited"`
+	// Failed is the number of other attempts, that failed or had an unsuccessful response.
+	Failed uint64 `json:"failed"`
+}
+
+// SubmissionStats counts the submission attempts of a resource type per key, ex: the SARIF uploads of each repository.
+// Use Observe as a Hooks.OnResponse (or Profile.OnResponse). It is safe for concurrent use.
+type SubmissionStats struct {
+	// Resource is the resource type of the counted requests, if empty the requests of every resource type are counted.
+	Resource Resource
+	// Key returns the key requests are counted under, requests with an empty key are not counted.
+	// If nil, RepositoryAffinity is used.
+	Key func(*http.Request) string
+
+	mu          sync.Mutex
+	submissions map[string]*Submissions
+}
+
+// Observe counts the outcome of the attempt described by the HookInfo of a Hooks.OnResponse.
+func (s *SubmissionStats) Observe(info HookInfo) {
+	s.observe(info, RepositoryAffinity)
+}
+
+// observe implements Observe, using defaultKey if the Key is nil.
+func (s *SubmissionStats) observe(info HookInfo, defaultKey func(*http.Request) string) {
+	if s.Resource != "" && info.Resource != s.Resource {
+		return
+	}
+	keyFn := s.Key
+	if keyFn == nil {
+		keyFn = defaultKey
+	}
+	key := keyFn(info.Request)
+	if key == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.submissions == nil {
+		s.submissions = make(map[string]*Submissions)
+	}
+	sub, ok := s.submissions[key]
+	if !ok {
+		sub = &Submissions{}
+		s.submissions[key] = sub
+	}
+	sub.Attempts++
+	switch {
+	case info.Err == nil && info.Response != nil && info.Response.StatusCode < http.StatusMultipleChoices:
+		sub.Accepted++
+	case DefaultRetryable(info.Resource, info.Response, info.Err):
+		sub.RateLimited++
+	default:
+		sub.Failed++
+	}
+}
+
+// Stats returns a snapshot of the submissions counted for each key.
+func (s *SubmissionStats) Stats() map[string]Submissions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]Submissions, len(s.submissions))
+	for key, sub := range s.submissions {
+		stats[key] = *sub
+	}
+	return stats
+}
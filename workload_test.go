@@ -0,0 +1,157 @@
+package ghratelimit
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport_ApplyProfile(t *testing.T) {
+	transport := &Transport{Base: okResponse(), Pace: []Resource{ResourceSearch, ResourceDependencySnapshots}}
+	profile := ProfileDependencySubmission()
+	transport.ApplyProfile(profile)
+	assert.Equal(t, []Resource{ResourceSearch, ResourceDependencySnapshots}, transport.Pace, "paced resources should not be duplicated")
+	assert.True(t, transport.WaitOnExhaustion)
+	assert.Equal(t, 4, transport.MaxConcurrency)
+	if assert.NotNil(t, transport.Retry) {
+		assert.Equal(t, 3, transport.Retry.MaxAttempts)
+		assert.NotSame(t, profile.Retry, transport.Retry, "the profile should not be shared")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/o/r/dependency-graph/snapshots", nil)
+	assert.Equal(t, ResourceDependencySnapshots, InferResource(req))
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+}
+
+func TestProfileSARIFUpload(t *testing.T) {
+	var calls int
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		status := http.StatusAccepted
+		if calls == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header: http.Header{
+				"X-Ratelimit-Resource":  []string{"code_scanning_upload"},
+				"X-Ratelimit-Limit":     []string{"1000"},
+				"X-Ratelimit-Remaining": []string{"999"},
+				"X-Ratelimit-Used":      []string{"1"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+			},
+			Body:    io.NopCloser(strings.NewReader(`{"id":"47177e22"}`)),
+			Request: req,
+		}, nil
+	})}
+	var responses int
+	transport.Hooks = &Hooks{OnResponse: func(HookInfo) { responses++ }}
+	stats := &SubmissionStats{Resource: ResourceCodeScanningUpload}
+	profile := ProfileSARIFUpload(stats)
+	profile.Retry.MinBackoff = time.Millisecond
+	transport.ApplyProfile(profile)
+	assert.Equal(t, []Resource{ResourceCodeScanningUpload}, transport.Pace)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/repos/O/R/code-scanning/sarifs", strings.NewReader("{}"))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("{}")), nil }
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		discard(resp)
+	}
+	assert.Equal(t, 2, calls, "transient failures should be retried, accepted uploads should not")
+	assert.Equal(t, 2, responses, "existing hooks should still be called")
+	assert.Equal(t, map[string]Submissions{"o/r": {Attempts: 2, Accepted: 1, Failed: 1}}, stats.Stats())
+
+	req, _ = http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r/code-scanning/sarifs/47177e22", nil)
+	resp, err = transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	assert.Equal(t, uint64(2), stats.Stats()["o/r"].Attempts, "other resource types should not be counted")
+}
+
+func TestProfileRunnerRegistration(t *testing.T) {
+	var calls int
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls < 3 {
+			return &http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+		}
+		return okResponse().RoundTrip(req)
+	})}
+	profile := ProfileRunnerRegistration()
+	profile.Retry.MinBackoff = time.Millisecond
+	transport.ApplyProfile(profile)
+	assert.Equal(t, 10, transport.MaxConcurrency)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.github.com/orgs/o/actions/runners/registration-token", nil)
+	resp, err := transport.RoundTrip(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		discard(resp)
+	}
+	assert.Equal(t, 3, calls, "server errors should be retried")
+	assert.Equal(t, time.Second, ProfileRunnerRegistration().Retry.MinBackoff, "each call should return a fresh profile")
+}
+
+func TestProfileSCIM(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	transport := &Transport{Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		order = append(order, req.Method)
+		mu.Unlock()
+		if req.Method == http.MethodPatch {
+			<-release
+		}
+		return okResponse().RoundTrip(req)
+	})}
+	stats := &SubmissionStats{Resource: ResourceSCIM}
+	transport.ApplyProfile(ProfileSCIM(stats))
+	assert.Equal(t, []Resource{ResourceSCIM}, transport.Pace)
+
+	const user = "https://api.github.com/scim/v2/organizations/acme/Users/5fc0c238"
+	patch, _ := http.NewRequest(http.MethodPatch, user, nil)
+	del, _ := http.NewRequest(http.MethodDelete, user, nil)
+	assert.Equal(t, "organizations/acme/Users", SCIMResourceType(patch))
+	assert.Equal(t, "scim:organizations/acme/Users/5fc0c238", SCIMOrderingKey(patch))
+
+	var wg sync.WaitGroup
+	for i, req := range []*http.Request{patch, del} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := transport.RoundTrip(req)
+			if assert.NoError(t, err) {
+				discard(resp)
+			}
+		}()
+		assert.Eventually(t, func() bool { return transport.sequencer.queued(SCIMOrderingKey(req)) == i+1 }, time.Second, time.Millisecond)
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 1
+	}, time.Second, time.Millisecond, "the first operation should be sent")
+	list, _ := http.NewRequest(http.MethodGet, "https://api.github.com/scim/v2/organizations/acme/Users", nil)
+	resp, err := transport.RoundTrip(list)
+	if assert.NoError(t, err) {
+		discard(resp)
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{http.MethodPatch, http.MethodGet, http.MethodDelete}, order, "operations on the same user should be serialized")
+	assert.Equal(t, map[string]Submissions{"organizations/acme/Users": {Attempts: 3, Accepted: 3}}, stats.Stats())
+	assert.Nil(t, stats.Key, "the caller's SubmissionStats should not be modified")
+}